@@ -2,168 +2,236 @@ package handlers
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
+	"meerank/apperror"
+	"meerank/audit"
 	"meerank/models"
+	"meerank/phones"
+	"meerank/store"
 	"net/http"
+	"strconv"
+	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// --- 1. Get All Users (UID and Name only) ---
+// --- 1. Search Users ---
 
-// UserSummary เป็น struct สำหรับส่งข้อมูลแบบย่อ
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// UserSummary เป็น struct สำหรับส่งข้อมูลผู้ใช้แบบย่อในรายการ
 type UserSummary struct {
-	UID  string `json:"uid"`
-	Name string `json:"name"`
+	UID         string     `json:"uid"`
+	Name        string     `json:"name"`
+	Phone       *string    `json:"phone,omitempty"`
+	Role        string     `json:"role"`
+	Status      string     `json:"status"`
+	Gender      *string    `json:"gender,omitempty"`
+	Age         *int       `json:"age,omitempty"`
+	Minute      int        `json:"minute"`
+	Score       int        `json:"score"`
+	NumberTree  int        `json:"number_tree"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
 }
 
-// GetAllUsersSummaryHandler ดึงรายชื่อผู้ใช้ทั้งหมด (เฉพาะ UID และ Name)
-// GetAllUsersSummaryHandler ดึงรายชื่อผู้ใช้ทั้งหมด (ยกเว้น admin ที่เรียก)
-func GetAllUsersSummaryHandler(c *gin.Context, client *firestore.Client) {
+// GetAllUsersSummaryHandler ค้นหาผู้ใช้ทีละหน้า (ยกเว้น admin ที่เรียก หน้านั้นจึงอาจสั้นกว่า limit หนึ่งคน)
+// query:
+//   - role, gender: ต้องตรงกัน
+//   - min_age, max_age: ช่วงอายุ (รวมทั้งสองฝั่ง)
+//   - last_login_from, last_login_to: RFC 3339 หรือ YYYY-MM-DD (last_login_to แบบวันที่นับรวมทั้งวัน)
+//   - name, phone: ค้นหาแบบขึ้นต้นด้วย (phone พิมพ์แบบในประเทศ เช่น 081 ได้)
+//   - sort: score (ค่าเริ่มต้น), minute, number_tree หรือ last_login_at
+//   - order: desc (ค่าเริ่มต้น) หรือ asc
+//   - limit (ค่าเริ่มต้น 20), cursor (ค่า next_cursor จากหน้าก่อนหน้า)
+func GetAllUsersSummaryHandler(c *gin.Context, st store.Store) {
 	// ✨ 1. ดึง UID ของ Admin ที่ล็อกอินอยู่ออกจาก Context ✨
 	adminUIDValue, exists := c.Get("uid")
 	if !exists {
 		// กรณีนี้ไม่น่าเกิดขึ้นถ้าผ่าน Middleware มาได้ แต่ใส่ไว้เพื่อความปลอดภัย
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin UID not found in token"})
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Admin UID not found in token"))
 		return
 	}
 	adminUID, _ := adminUIDValue.(string)
 
-	var users []UserSummary
-	ctx := context.Background()
-
-	// 2. ดึง document ทั้งหมดจาก collection users
-	iter := client.Collection(models.CollectionUsers).Documents(ctx)
-	defer iter.Stop()
+	// 2. อ่านเงื่อนไขการค้นหา
+	query, ok := userQueryParams(c)
+	if !ok {
+		return
+	}
 
-	// 3. วนลูปเพื่ออ่านข้อมูล
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Failed to iterate users: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve users"})
+	// 3. ค้นหาผู้ใช้หนึ่งหน้า
+	page, err := st.SearchUsers(context.Background(), query)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			apperror.Abort(c, apperror.InvalidParameter("cursor", "Invalid cursor"))
 			return
 		}
+		log.Printf("Failed to search users: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not retrieve users"))
+		return
+	}
 
-		// ✨ 4. เพิ่มเงื่อนไข: ถ้า UID ไม่ใช่ของ Admin คนปัจจุบัน ให้เพิ่มเข้าไปใน list ✨
-		if doc.Ref.ID != adminUID {
-			var user models.User
-			if err := doc.DataTo(&user); err == nil {
-				users = append(users, UserSummary{
-					UID:  doc.Ref.ID,
-					Name: user.Name,
-				})
-			}
+	// 4. แปลงเป็นข้อมูลแบบย่อ โดยข้าม Admin คนปัจจุบัน
+	users := []UserSummary{}
+	for _, user := range page.Users {
+		if user.ID == adminUID {
+			continue
 		}
+		users = append(users, UserSummary{
+			UID:         user.ID,
+			Name:        user.Name,
+			Phone:       user.Phone,
+			Role:        user.Role,
+			Status:      user.Status(),
+			Gender:      user.Gender,
+			Age:         user.Age,
+			Minute:      user.Minute,
+			Score:       user.Score,
+			NumberTree:  user.NumberTree,
+			LastLoginAt: user.LastLoginAt,
+		})
 	}
 
-	// 5. ส่งข้อมูลกลับไป
-	c.JSON(http.StatusOK, users)
+	// 5. บันทึกการค้นหา (เงื่อนไขที่ใช้) ลง audit log แล้วส่งข้อมูลกลับไป
+	audit.Record(c, st, models.AuditEntry{
+		Action:   models.AuditActionUserSearch,
+		Metadata: map[string]string{"query": c.Request.URL.RawQuery},
+	})
+	response := gin.H{"users": users}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// --- 2. Get Full User Profile by UID ---
+// userQueryParams อ่านเงื่อนไขการค้นหาผู้ใช้จาก query string
+// ถ้าค่าไม่ถูกต้องจะตอบ 400 ให้แล้วและคืน ok เป็น false
+func userQueryParams(c *gin.Context) (store.UserQuery, bool) {
+	query := store.UserQuery{
+		Role:        c.Query("role"),
+		Gender:      c.Query("gender"),
+		NamePrefix:  c.Query("name"),
+		PhonePrefix: c.Query("phone"),
+		Cursor:      c.Query("cursor"),
+		Limit:       defaultUserPageSize,
+	}
+	if query.PhonePrefix != "" {
+		// เบอร์เก็บเป็น E.164 จึงแปลงเบอร์แบบในประเทศ (เช่น 081) ก่อนค้นหา
+		query.PhonePrefix = phones.NormalizePrefix(query.PhonePrefix)
+	}
 
-// GetFullUserProfileHandler ดึงข้อมูลทั้งหมดของผู้ใช้จาก UID ที่ระบุ
-func GetFullUserProfileHandler(c *gin.Context, client *firestore.Client) {
-	// 1. ดึง uid จาก URL parameter
-	uid := c.Param("uid")
-	if uid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
-		return
+	switch sort := c.DefaultQuery("sort", store.UserSortScore); sort {
+	case store.UserSortScore, store.UserSortMinute, store.UserSortNumberTree, store.UserSortLastLogin:
+		query.Sort = sort
+	default:
+		apperror.Abort(c, apperror.InvalidParameter("sort", "Invalid 'sort', must be score, minute, number_tree or last_login_at"))
+		return query, false
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		apperror.Abort(c, apperror.InvalidParameter("order", "Invalid 'order', must be asc or desc"))
+		return query, false
 	}
 
-	ctx := context.Background()
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			apperror.Abort(c, apperror.InvalidParameter("limit", "'limit' must be a positive integer"))
+			return query, false
+		}
+		query.Limit = min(limit, maxUserPageSize)
+	}
 
-	// 2. ค้นหา document ด้วย uid
-	doc, err := client.Collection(models.CollectionUsers).Doc(uid).Get(ctx)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
+	for _, param := range []struct {
+		name   string
+		target **int
+	}{{"min_age", &query.MinAge}, {"max_age", &query.MaxAge}} {
+		name, target := param.name, param.target
+		raw := c.Query(name)
+		if raw == "" {
+			continue
 		}
-		log.Printf("Failed to get user by ID: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
+		age, err := strconv.Atoi(raw)
+		if err != nil || age < 0 {
+			apperror.Abort(c, apperror.InvalidParameter(name, "'"+name+"' must be a non-negative integer"))
+			return query, false
+		}
+		*target = &age
+	}
+	if query.MinAge != nil && query.MaxAge != nil && *query.MinAge > *query.MaxAge {
+		apperror.Abort(c, apperror.InvalidParameter("min_age", "'min_age' must not be greater than 'max_age'"))
+		return query, false
 	}
 
-	// 3. แปลงข้อมูลและใส่ ID กลับเข้าไปใน struct
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		log.Printf("Failed to process user data: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process user data"})
-		return
+	var err error
+	if query.LastLoginFrom, err = parseTimeParam(c.Query("last_login_from"), false); err != nil {
+		apperror.Abort(c, apperror.InvalidParameter("last_login_from", "Invalid 'last_login_from', use RFC 3339 or YYYY-MM-DD"))
+		return query, false
 	}
-	user.ID = doc.Ref.ID // อย่าลืมใส่ Document ID กลับเข้าไป
+	if query.LastLoginTo, err = parseTimeParam(c.Query("last_login_to"), true); err != nil {
+		apperror.Abort(c, apperror.InvalidParameter("last_login_to", "Invalid 'last_login_to', use RFC 3339 or YYYY-MM-DD"))
+		return query, false
+	}
+	return query, true
+}
 
-	// 4. ส่งข้อมูลทั้งหมดกลับไป
-	c.JSON(http.StatusOK, user)
+// parseTimeParam แปลงค่า RFC 3339 หรือ YYYY-MM-DD (UTC) เป็นเวลา ค่าว่างคืน nil
+// ถ้า endOfDay เป็น true วันที่แบบ YYYY-MM-DD จะหมายถึงต้นวันถัดไป (ใช้เป็นขอบเขตแบบไม่รวม)
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
 }
 
-// ResetAllUsersStatsHandler รีเซ็ตค่า minute, score, number_tree, tree_progress ของผู้ใช้ทุกคนให้เป็น 0
-func ResetAllUsersStatsHandler(c *gin.Context, client *firestore.Client) {
-	ctx := context.Background()
-	userCount := 0
+// --- 2. Get Full User Profile by UID ---
 
-	// ✨ ใช้ Batched Writes เพื่อประสิทธิภาพสูงสุดในการอัปเดตข้อมูลจำนวนมาก
-	batch := client.Batch()
+// GetFullUserProfileHandler ดึงข้อมูลทั้งหมดของผู้ใช้จาก UID ที่ระบุ
+func GetFullUserProfileHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง uid จาก URL parameter
+	uid := c.Param("uid")
+	if uid == "" {
+		apperror.Abort(c, apperror.InvalidParameter("uid", "User ID is required"))
+		return
+	}
 
-	// 1. ดึงข้อมูลผู้ใช้ทั้งหมดใน Collection "users"
-	iter := client.Collection(models.CollectionUsers).Documents(ctx)
-	defer iter.Stop()
+	ctx := context.Background()
 
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Failed to iterate users for reset: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user data"})
+	// 2. ค้นหาผู้ใช้ด้วย uid (store ใส่ ID กลับเข้าไปใน struct ให้แล้ว)
+	user, err := st.GetUser(ctx, uid)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
 			return
 		}
-
-		// 2. เตรียมข้อมูลที่จะอัปเดต
-		updates := []firestore.Update{
-			{Path: "minute", Value: 0},
-			{Path: "score", Value: 0},
-			{Path: "number_tree", Value: 0},
-			{Path: "tree_progress", Value: 0},
-		}
-
-		// 3. เพิ่ม Operation การอัปเดตเข้าไปใน Batch
-		batch.Update(doc.Ref, updates)
-		userCount++
-
-		// Firestore Batched Writes มีขีดจำกัดที่ 500 operations ต่อครั้ง
-		// หากมีผู้ใช้จำนวนมาก เราจะ commit ทุกๆ 500 คน แล้วเริ่ม batch ใหม่
-		if userCount%500 == 0 {
-			if _, err := batch.Commit(ctx); err != nil {
-				log.Printf("Batch commit failed during reset: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user stats"})
-				return
-			}
-			// เริ่ม Batch ใหม่สำหรับรอบต่อไป
-			batch = client.Batch()
-		}
-	}
-
-	// 4. Commit Batch สุดท้าย (สำหรับผู้ใช้ที่เหลือที่ยังไม่ถึง 500)
-	if _, err := batch.Commit(ctx); err != nil {
-		log.Printf("Final batch commit failed during reset: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finalize updating user stats"})
+		log.Printf("Failed to get user by ID: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
 		return
 	}
 
-	// 5. ส่งคำตอบกลับเมื่อสำเร็จ
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("Successfully reset stats for %d users.", userCount),
+	// 3. บันทึกการเปิดดูข้อมูลส่วนบุคคลลง audit log แล้วส่งข้อมูลทั้งหมดกลับไป
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionUserView,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID,
 	})
+	c.JSON(http.StatusOK, user)
 }
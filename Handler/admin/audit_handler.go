@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/models"
+	"meerank/store"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// --- Audit log ---
+
+// ListAuditHandler ดึง audit log เรียงจากใหม่ไปเก่าทีละหน้า
+// query:
+//   - actor: UID ของผู้กระทำ
+//   - target: ID ของสิ่งที่ถูกกระทำ (เช่น UID ของผู้ใช้)
+//   - action: เช่น user.update หรือ auth.login
+//   - from, to: RFC 3339 หรือ YYYY-MM-DD (to แบบวันที่นับรวมทั้งวัน)
+//   - limit (ค่าเริ่มต้น 50), cursor (ค่า next_cursor จากหน้าก่อนหน้า)
+func ListAuditHandler(c *gin.Context, st store.Store) {
+	// 1. อ่านเงื่อนไขการค้นหา
+	query := store.AuditQuery{
+		ActorID:  c.Query("actor"),
+		TargetID: c.Query("target"),
+		Action:   c.Query("action"),
+		Cursor:   c.Query("cursor"),
+		Limit:    defaultAuditPageSize,
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			apperror.Abort(c, apperror.InvalidParameter("limit", "'limit' must be a positive integer"))
+			return
+		}
+		query.Limit = min(limit, maxAuditPageSize)
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(c.Query("from"), false); err != nil {
+		apperror.Abort(c, apperror.InvalidParameter("from", "Invalid 'from', use RFC 3339 or YYYY-MM-DD"))
+		return
+	}
+	if query.To, err = parseTimeParam(c.Query("to"), true); err != nil {
+		apperror.Abort(c, apperror.InvalidParameter("to", "Invalid 'to', use RFC 3339 or YYYY-MM-DD"))
+		return
+	}
+
+	// 2. ดึงข้อมูลหนึ่งหน้า
+	page, err := st.ListAudit(context.Background(), query)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			apperror.Abort(c, apperror.InvalidParameter("cursor", "Invalid cursor"))
+			return
+		}
+		log.Printf("Failed to list audit log: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not retrieve audit log"))
+		return
+	}
+
+	// 3. การเปิดดู audit log ก็ถูกบันทึกเช่นกัน
+	audit.Record(c, st, models.AuditEntry{
+		Action:   models.AuditActionAuditView,
+		Metadata: map[string]string{"query": c.Request.URL.RawQuery},
+	})
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/jobs"
+	"meerank/models"
+	"meerank/store"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- Background jobs ---
+
+// ResetAllUsersStatsHandler สั่งรีเซ็ตค่า minute, score, number_tree, tree_progress ของผู้ใช้ทุกคนให้เป็น 0
+// งานทำเบื้องหลัง คำตอบคืน job ทันที (ดูความคืบหน้าที่ GET /admin/jobs/:id)
+// query: dry_run=true เพื่อดูว่าจะมีผู้ใช้กี่คนและยอดเท่าไรถูกล้าง โดยไม่แก้ข้อมูล
+func ResetAllUsersStatsHandler(c *gin.Context, st store.Store, runner *jobs.Runner) {
+	// 1. ดึง UID ของ Admin ที่ล็อกอินอยู่
+	adminUID, _ := c.Get("uid")
+	creator, _ := adminUID.(string)
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidParameter("dry_run", "'dry_run' must be true or false"))
+		return
+	}
+
+	// 2. สร้างงานและเริ่มทำเบื้องหลัง
+	job, err := runner.StartReset(context.Background(), creator, dryRun)
+	if err != nil {
+		respondJobError(c, err, "Failed to start reset job")
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionStatsReset,
+		TargetType: models.AuditTargetJob,
+		TargetID:   job.ID,
+		Metadata:   map[string]string{"dry_run": strconv.FormatBool(dryRun)},
+	})
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJobHandler คืนสถานะและความคืบหน้าของงาน
+func GetJobHandler(c *gin.Context, st store.Store) {
+	job, err := st.GetJob(context.Background(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeJobNotFound, "Job not found"))
+			return
+		}
+		log.Printf("Failed to get job: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionJobView,
+		TargetType: models.AuditTargetJob,
+		TargetID:   job.ID,
+	})
+	c.JSON(http.StatusOK, job)
+}
+
+// UndoJobHandler คืนสถิติที่งานรีเซ็ตล้างไปให้ผู้ใช้ (ยอดที่ได้หลังรีเซ็ตยังอยู่ครบ)
+func UndoJobHandler(c *gin.Context, st store.Store, runner *jobs.Runner) {
+	job, err := runner.Undo(context.Background(), c.Param("id"))
+	if err != nil {
+		respondJobError(c, err, "Failed to undo job")
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionJobUndo,
+		TargetType: models.AuditTargetJob,
+		TargetID:   job.ID,
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ResumeJobHandler ทำงานที่ล้มเหลวต่อจาก batch สุดท้ายที่บันทึกสำเร็จ
+func ResumeJobHandler(c *gin.Context, st store.Store, runner *jobs.Runner) {
+	job, err := runner.Resume(context.Background(), c.Param("id"))
+	if err != nil {
+		respondJobError(c, err, "Failed to resume job")
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionJobResume,
+		TargetType: models.AuditTargetJob,
+		TargetID:   job.ID,
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func respondJobError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		apperror.Abort(c, apperror.NotFound(apperror.CodeJobNotFound, "Job not found"))
+	case errors.Is(err, jobs.ErrNotUndoable):
+		apperror.Abort(c, apperror.Conflict(apperror.CodeJobNotUndoable, "Only a completed reset that is not a dry run can be undone"))
+	case errors.Is(err, jobs.ErrNotResumable):
+		apperror.Abort(c, apperror.Conflict(apperror.CodeJobNotResumable, "Only a failed job can be resumed"))
+	case errors.Is(err, jobs.ErrInProgress):
+		apperror.Abort(c, apperror.Conflict(apperror.CodeJobInProgress, "Another reset job is still running"))
+	default:
+		log.Printf("%s: %v", message, err)
+		apperror.Abort(c, apperror.Internal(message))
+	}
+}
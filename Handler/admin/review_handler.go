@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/models"
+	"meerank/store"
+	"meerank/validation"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultReviewPageSize = 50
+	maxReviewPageSize     = 200
+)
+
+var errReviewAlreadyResolved = apperror.Conflict(apperror.CodeReviewAlreadyResolved, "Review already resolved")
+
+// --- Activity review queue ---
+
+// ListActivityReviewsHandler ดึงคิวกิจกรรมที่ถูก flag (ค่าเริ่มต้นเฉพาะที่รอตรวจ)
+// query: status (pending|approved|rejected|all), limit, cursor
+func ListActivityReviewsHandler(c *gin.Context, st store.Store) {
+	// 1. อ่านเงื่อนไขการค้นหา
+	query := store.ReviewQuery{
+		Status: c.DefaultQuery("status", models.ReviewStatusPending),
+		Cursor: c.Query("cursor"),
+		Limit:  defaultReviewPageSize,
+	}
+	switch query.Status {
+	case models.ReviewStatusPending, models.ReviewStatusApproved, models.ReviewStatusRejected:
+	case "all":
+		query.Status = ""
+	default:
+		apperror.Abort(c, apperror.InvalidParameter("status", "Invalid 'status'"))
+		return
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			apperror.Abort(c, apperror.InvalidParameter("limit", "'limit' must be a positive integer"))
+			return
+		}
+		query.Limit = min(limit, maxReviewPageSize)
+	}
+
+	ctx := context.Background()
+
+	// 2. ดึงข้อมูลหนึ่งหน้า
+	page, err := st.ListActivityReviews(ctx, query)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			apperror.Abort(c, apperror.InvalidParameter("cursor", "Invalid cursor"))
+			return
+		}
+		log.Printf("Failed to list activity reviews: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not retrieve reviews"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:   models.AuditActionReviewList,
+		Metadata: map[string]string{"query": c.Request.URL.RawQuery},
+	})
+	c.JSON(http.StatusOK, page)
+}
+
+// ResolveActivityReviewHandler อนุมัติหรือปฏิเสธกิจกรรมในคิวตรวจสอบ
+// ถ้าปฏิเสธ นาทีและคะแนนของกิจกรรมนั้นจะถูกหักออกจากผู้ใช้
+func ResolveActivityReviewHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง UID ของ Admin ที่ล็อกอินอยู่
+	adminUID, _ := c.Get("uid")
+	reviewer, _ := adminUID.(string)
+
+	var payload struct {
+		Decision string `json:"decision" binding:"required,oneof=approve reject"`
+		Note     string `json:"note" binding:"max=500"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+
+	status := models.ReviewStatusApproved
+	if payload.Decision == "reject" {
+		status = models.ReviewStatusRejected
+	}
+
+	ctx := context.Background()
+
+	// 2. เปลี่ยนสถานะใน Transaction (ตรวจได้ครั้งเดียว)
+	review, err := st.ResolveActivityReview(ctx, c.Param("id"), func(review *models.ActivityReview) error {
+		if review.Status != models.ReviewStatusPending {
+			return errReviewAlreadyResolved
+		}
+		now := time.Now()
+		review.Status = status
+		review.ReviewedBy = reviewer
+		review.ReviewedAt = &now
+		review.Note = payload.Note
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeReviewNotFound, "Review not found"))
+			return
+		}
+		if errors.Is(err, errReviewAlreadyResolved) {
+			apperror.Abort(c, err)
+			return
+		}
+		log.Printf("Failed to resolve activity review: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to resolve review"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionReviewResolve,
+		TargetType: models.AuditTargetReview,
+		TargetID:   review.ID,
+		Reason:     payload.Note,
+		Metadata:   map[string]string{"decision": payload.Decision, "user_id": review.UserID},
+	})
+	c.JSON(http.StatusOK, review)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/models"
+	"meerank/store"
+	"meerank/validation"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errSeasonOverlap       = apperror.Conflict(apperror.CodeSeasonOverlap, "Season overlaps an existing season")
+	errSeasonAlreadyClosed = apperror.Conflict(apperror.CodeSeasonAlreadyClosed, "Season already closed")
+	errSeasonNotEnded      = apperror.Conflict(apperror.CodeSeasonNotEnded, "Season has not ended yet")
+)
+
+// --- Seasons ---
+
+// CreateSeasonHandler สร้างฤดูกาลใหม่ ยอดของสมาชิกในช่วง starts_at ถึง ends_at จะสะสมแยกให้ฤดูกาลนี้
+// ช่วงเวลาของฤดูกาลห้ามทับกับฤดูกาลอื่น
+func CreateSeasonHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง UID ของ Admin ที่ล็อกอินอยู่
+	adminUID, _ := c.Get("uid")
+	creator, _ := adminUID.(string)
+
+	var payload struct {
+		Name     string    `json:"name" binding:"required,notblank,max=255"`
+		StartsAt time.Time `json:"starts_at" binding:"required"`
+		EndsAt   time.Time `json:"ends_at" binding:"required"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+	if !payload.EndsAt.After(payload.StartsAt) {
+		validation.Respond(c, validation.Errors{validation.Field("ends_at", validation.CodeMustBeAfter, "starts_at")})
+		return
+	}
+
+	season := &models.Season{
+		Name:      payload.Name,
+		StartsAt:  payload.StartsAt.UTC(),
+		EndsAt:    payload.EndsAt.UTC(),
+		Status:    models.SeasonStatusOpen,
+		CreatedBy: creator,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	ctx := context.Background()
+
+	// 2. บันทึกใน Transaction พร้อมตรวจว่าไม่ทับกับฤดูกาลเดิม
+	err := st.CreateSeason(ctx, season, func(existing []models.Season) error {
+		for _, other := range existing {
+			if other.StartsAt.Before(season.EndsAt) && season.StartsAt.Before(other.EndsAt) {
+				return errSeasonOverlap
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errSeasonOverlap) {
+			apperror.Abort(c, err)
+			return
+		}
+		log.Printf("Failed to create season: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to create season"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionSeasonCreate,
+		TargetType: models.AuditTargetSeason,
+		TargetID:   season.ID,
+		Metadata:   map[string]string{"name": season.Name},
+	})
+	c.JSON(http.StatusCreated, season)
+}
+
+// CloseSeasonHandler ปิดฤดูกาลที่สิ้นสุดแล้ว และบันทึกอันดับสุดท้ายไว้ดูย้อนหลัง
+// หลังปิดแล้วกิจกรรมที่ส่งย้อนหลังเข้ามาจะไม่ถูกนับเข้าฤดูกาลนี้อีก
+func CloseSeasonHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง UID ของ Admin ที่ล็อกอินอยู่
+	adminUID, _ := c.Get("uid")
+	closer, _ := adminUID.(string)
+
+	ctx := context.Background()
+
+	// 2. ปิดฤดูกาลและบันทึก standings (สั่งซ้ำได้ถ้าครั้งก่อนค้างอยู่ที่ closing)
+	season, err := st.CloseSeason(ctx, c.Param("id"), func(season *models.Season) error {
+		if season.Status == models.SeasonStatusClosed {
+			return errSeasonAlreadyClosed
+		}
+		now := time.Now().UTC()
+		if now.Before(season.EndsAt) {
+			return errSeasonNotEnded
+		}
+		if season.ClosedAt == nil {
+			season.ClosedBy = closer
+			season.ClosedAt = &now
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeSeasonNotFound, "Season not found"))
+			return
+		}
+		if errors.Is(err, errSeasonAlreadyClosed) || errors.Is(err, errSeasonNotEnded) {
+			apperror.Abort(c, err)
+			return
+		}
+		log.Printf("Failed to close season: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to close season"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionSeasonClose,
+		TargetType: models.AuditTargetSeason,
+		TargetID:   season.ID,
+	})
+	c.JSON(http.StatusOK, season)
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/phones"
+	"meerank/store"
+	"meerank/validation"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errUserDeleted        = apperror.Conflict(apperror.CodeUserDeleted, "User has been deleted")
+	errUserNotDeleted     = apperror.Conflict(apperror.CodeUserNotDeleted, "User is not deleted")
+	errUserSuspended      = apperror.Conflict(apperror.CodeUserAlreadySuspended, "User already suspended")
+	errUserNotSuspended   = apperror.Conflict(apperror.CodeUserNotSuspended, "User is not suspended")
+	errPurgeWindowExpired = apperror.Conflict(apperror.CodeRestoreWindowPassed, "Purge window has passed, user can no longer be restored")
+	errLastAdmin          = apperror.Conflict(apperror.CodeLastAdmin, "Cannot remove the last admin")
+)
+
+// --- User Management ---
+
+// UpdateUserHandler แก้ไขข้อมูลโปรไฟล์และสถิติของผู้ใช้ (ส่งเฉพาะ field ที่ต้องการแก้)
+// ต้องระบุ reason เสมอ การแก้สถิติไม่ย้อนไปแก้ยอดสะสมรายช่วงเวลาหรือฤดูกาล
+func UpdateUserHandler(c *gin.Context, st store.Store) {
+	uid := c.Param("uid")
+
+	// 1. รับ field ที่ต้องการแก้และเหตุผล
+	var payload struct {
+		Name         *string `json:"name" binding:"omitempty,notblank,max=100"`
+		Phone        *string `json:"phone" binding:"omitempty,min=1,max=32"`
+		Age          *int    `json:"age" binding:"omitempty,min=1,max=120"`
+		Gender       *string `json:"gender" binding:"omitempty,gender"`
+		Minute       *int    `json:"minute" binding:"omitempty,min=0"`
+		Score        *int    `json:"score" binding:"omitempty,min=0"`
+		NumberTree   *int    `json:"number_tree" binding:"omitempty,min=0"`
+		TreeProgress *int    `json:"tree_progress" binding:"omitempty,min=0"`
+		Reason       string  `json:"reason" binding:"required,notblank,max=500"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+	if payload.Name == nil && payload.Phone == nil && payload.Age == nil && payload.Gender == nil &&
+		payload.Minute == nil && payload.Score == nil && payload.NumberTree == nil && payload.TreeProgress == nil {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeNoFieldsToUpdate, "No fields to update"))
+		return
+	}
+
+	// 2. ตัดช่องว่างหัวท้ายชื่อเหมือนที่สมาชิกแก้เอง และแปลงเบอร์โทรศัพท์เป็น E.164
+	// (store ตรวจว่าเบอร์ไม่ซ้ำกับผู้ใช้คนอื่นใน Transaction เดียวกับการแก้ไข)
+	// admin เปลี่ยนเบอร์ได้โดยไม่ต้องยืนยันด้วย OTP
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		payload.Name = &name
+	}
+	if payload.Phone != nil {
+		phone, err := phones.Normalize(*payload.Phone)
+		if err != nil {
+			apperror.Abort(c, apperror.BadRequest(apperror.CodeInvalidPhone, "Invalid phone number"))
+			return
+		}
+		payload.Phone = &phone
+	}
+
+	ctx := context.Background()
+
+	// 3. แก้ไขใน Transaction โดยเก็บค่าก่อนแก้ไว้สำหรับ audit log
+	var before models.User
+	user, err := st.ModifyUser(ctx, uid, func(user *models.User) error {
+		if user.DeletedAt != nil {
+			return errUserDeleted
+		}
+		before = *user
+		if payload.Name != nil {
+			user.Name = *payload.Name
+		}
+		if payload.Phone != nil {
+			user.Phone = payload.Phone
+		}
+		if payload.Age != nil {
+			user.Age = payload.Age
+		}
+		if payload.Gender != nil {
+			user.Gender = payload.Gender
+		}
+		if payload.Minute != nil {
+			user.Minute = *payload.Minute
+		}
+		if payload.Score != nil {
+			user.Score = *payload.Score
+		}
+		if payload.NumberTree != nil {
+			user.NumberTree = *payload.NumberTree
+		}
+		if payload.TreeProgress != nil {
+			user.TreeProgress = *payload.TreeProgress
+		}
+		return nil
+	})
+	if err != nil {
+		respondUserManagementError(c, err, "Failed to update user")
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionUserUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Reason:     payload.Reason,
+		Changes:    audit.Changes(before, user),
+	})
+	c.JSON(http.StatusOK, user)
+}
+
+// SuspendUserHandler ระงับบัญชีผู้ใช้ Token ที่ออกไปแล้วใช้ไม่ได้ทันทีและล็อกอินใหม่ไม่ได้
+func SuspendUserHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง UID ของ Admin ที่ล็อกอินอยู่
+	adminUID, _ := c.Get("uid")
+	suspender, _ := adminUID.(string)
+	uid := c.Param("uid")
+	if uid == suspender {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeCannotSuspendSelf, "You cannot suspend your own account"))
+		return
+	}
+
+	var payload struct {
+		Reason string `json:"reason" binding:"required,notblank,max=500"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+
+	// 2. บันทึกการระงับ ระงับ admin คนสุดท้ายที่ยังใช้งานได้ไม่ได้
+	user, err := st.ModifyUserWithAdmins(context.Background(), uid, func(user *models.User, admins []models.User) error {
+		if user.DeletedAt != nil {
+			return errUserDeleted
+		}
+		if user.SuspendedAt != nil {
+			return errUserSuspended
+		}
+		if err := requireOtherActiveAdmin(user, admins); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		user.SuspendedAt = &now
+		user.SuspendedBy = suspender
+		user.SuspendReason = payload.Reason
+		return nil
+	})
+	if err != nil {
+		respondUserManagementError(c, err, "Failed to suspend user")
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionUserSuspend,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Reason:     payload.Reason,
+	})
+	c.JSON(http.StatusOK, user)
+}
+
+// UnsuspendUserHandler ยกเลิกการระงับบัญชี (ผู้ใช้ต้องล็อกอินใหม่ถ้า session เดิมหมดอายุแล้ว)
+func UnsuspendUserHandler(c *gin.Context, st store.Store) {
+	uid := c.Param("uid")
+
+	user, err := st.ModifyUser(context.Background(), uid, func(user *models.User) error {
+		if user.DeletedAt != nil {
+			return errUserDeleted
+		}
+		if user.SuspendedAt == nil {
+			return errUserNotSuspended
+		}
+		user.SuspendedAt = nil
+		user.SuspendedBy = ""
+		user.SuspendReason = ""
+		return nil
+	})
+	if err != nil {
+		respondUserManagementError(c, err, "Failed to unsuspend user")
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionUserUnsuspend,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+	})
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUserHandler ลบบัญชีผู้ใช้แบบ soft-delete ผู้ใช้ใช้งานไม่ได้ทันที
+// และข้อมูลจะถูกลบถาวรเมื่อครบ models.UserPurgeWindow (กู้คืนได้ก่อนหน้านั้น)
+// ลบ admin คนสุดท้ายที่ยังใช้งานได้ไม่ได้
+func DeleteUserHandler(c *gin.Context, st store.Store) {
+	adminUID, _ := c.Get("uid")
+	deleter, _ := adminUID.(string)
+	uid := c.Param("uid")
+	if uid == deleter {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeCannotDeleteSelf, "You cannot delete your own account"))
+		return
+	}
+
+	user, err := st.ModifyUserWithAdmins(context.Background(), uid, func(user *models.User, admins []models.User) error {
+		if user.DeletedAt != nil {
+			return errUserDeleted
+		}
+		if err := requireOtherActiveAdmin(user, admins); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		purgeAt := now.Add(models.UserPurgeWindow)
+		user.DeletedAt = &now
+		user.DeletedBy = deleter
+		user.PurgeAt = &purgeAt
+		return nil
+	})
+	if err != nil {
+		respondUserManagementError(c, err, "Failed to delete user")
+		return
+	}
+	if err := st.RenameLeaderboardEntries(context.Background(), uid, models.DeletedUserName); err != nil {
+		log.Printf("Failed to anonymize leaderboard entries of user %s: %v", uid, err)
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionUserDelete,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Metadata:   map[string]string{"purge_at": user.PurgeAt.Format(time.RFC3339)},
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"code":     i18n.MsgUserDeleted,
+		"message":  i18n.Message(c, i18n.MsgUserDeleted),
+		"purge_at": user.PurgeAt,
+	})
+}
+
+// RestoreUserHandler กู้คืนบัญชีที่ถูก soft-delete (ต้องยังไม่ครบกำหนด purge)
+func RestoreUserHandler(c *gin.Context, st store.Store) {
+	uid := c.Param("uid")
+
+	user, err := st.ModifyUser(context.Background(), uid, func(user *models.User) error {
+		if user.DeletedAt == nil {
+			return errUserNotDeleted
+		}
+		// ผู้ใช้ที่ครบกำหนดแล้วอาจกำลังถูกลบถาวรอยู่ จึงกู้คืนไม่ได้
+		if user.PurgeAt != nil && !time.Now().Before(*user.PurgeAt) {
+			return errPurgeWindowExpired
+		}
+		user.DeletedAt = nil
+		user.DeletedBy = ""
+		user.PurgeAt = nil
+		return nil
+	})
+	if err != nil {
+		respondUserManagementError(c, err, "Failed to restore user")
+		return
+	}
+	if err := st.RenameLeaderboardEntries(context.Background(), uid, user.Name); err != nil {
+		log.Printf("Failed to restore leaderboard entries of user %s: %v", uid, err)
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionUserRestore,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+	})
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangeUserRoleHandler เปลี่ยน role ของผู้ใช้ (member, moderator หรือ admin) ต้องระบุ reason
+// ลด role ของ admin คนสุดท้ายที่ยังใช้งานได้ไม่ได้ เพื่อไม่ให้ระบบไม่มี admin เหลืออยู่
+func ChangeUserRoleHandler(c *gin.Context, st store.Store) {
+	uid := c.Param("uid")
+
+	// 1. รับ role ใหม่และเหตุผล
+	var payload struct {
+		Role   string `json:"role" binding:"required,oneof=member moderator admin"`
+		Reason string `json:"reason" binding:"required,notblank,max=500"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+
+	// 2. เปลี่ยน role ใน Transaction พร้อมตรวจว่ายังเหลือ admin คนอื่นที่ใช้งานได้
+	var previous string
+	user, err := st.ChangeUserRole(context.Background(), uid, payload.Role, func(user *models.User, admins []models.User) error {
+		if user.DeletedAt != nil {
+			return errUserDeleted
+		}
+		previous = user.Role
+		if payload.Role == models.RoleAdmin {
+			return nil
+		}
+		return requireOtherActiveAdmin(user, admins)
+	})
+	if err != nil {
+		respondUserManagementError(c, err, "Failed to change role")
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionUserRoleChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Reason:     payload.Reason,
+		Changes:    []models.AuditChange{{Field: "role", Before: previous, After: user.Role}},
+	})
+	c.JSON(http.StatusOK, user)
+}
+
+// requireOtherActiveAdmin คืน errLastAdmin ถ้า user เป็น admin และไม่มี admin คนอื่นที่ยังใช้งานได้เหลืออยู่
+// admins คือ admin ทุกคนที่อ่านใน transaction เดียวกับการแก้ไข
+func requireOtherActiveAdmin(user *models.User, admins []models.User) error {
+	if user.Role != models.RoleAdmin {
+		return nil
+	}
+	for _, admin := range admins {
+		if admin.ID != user.ID && admin.Status() == models.UserStatusActive {
+			return nil
+		}
+	}
+	return errLastAdmin
+}
+
+// respondUserManagementError แปลง error ของการแก้ไขผู้ใช้เป็น error ที่ตอบ client
+func respondUserManagementError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+	case errors.Is(err, store.ErrPhoneTaken):
+		apperror.Abort(c, apperror.Conflict(apperror.CodePhoneTaken, "Phone number already registered"))
+	case errors.Is(err, errUserDeleted), errors.Is(err, errUserNotDeleted),
+		errors.Is(err, errUserSuspended), errors.Is(err, errUserNotSuspended),
+		errors.Is(err, errLastAdmin), errors.Is(err, errPurgeWindowExpired):
+		apperror.Abort(c, err)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		apperror.Abort(c, apperror.Internal(fallback))
+	}
+}
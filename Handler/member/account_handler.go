@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errAccountDeleted        = apperror.Conflict(apperror.CodeAccountDeleted, "Account has been deleted")
+	errAdminSelfDelete       = apperror.Conflict(apperror.CodeAdminMustBeDemoted, "Admins must be demoted before deleting their account")
+	errDeletionNotCancelable = errors.New("account deletion can no longer be cancelled")
+)
+
+// exportActivityPageSize คือจำนวนกิจกรรมที่อ่านต่อครั้งตอนส่งออกข้อมูล
+const exportActivityPageSize = 500
+
+// --- Personal data export ---
+
+// ExportBundle คือข้อมูลส่วนบุคคลทั้งหมดของผู้ใช้ที่ส่งออกได้ (สิทธิ์ขอรับข้อมูลตาม PDPA)
+type ExportBundle struct {
+	ExportedAt time.Time         `json:"exported_at"`
+	Profile    *models.User      `json:"profile"`
+	Activities []models.Activity `json:"activities"`
+	Trees      ExportTrees       `json:"trees"`
+	Sessions   []models.Session  `json:"sessions"`
+}
+
+// ExportTrees คือข้อมูลต้นไม้ของผู้ใช้
+type ExportTrees struct {
+	NumberTree   int `json:"number_tree"`
+	TreeProgress int `json:"tree_progress"`
+}
+
+// ExportMyDataHandler ส่งออกโปรไฟล์ ประวัติกิจกรรม ต้นไม้ และ session ของผู้ใช้ที่ล็อกอินอยู่
+// query: format=json (ค่าเริ่มต้น) หรือ zip (ไฟล์ CSV แยกตามหมวด)
+func ExportMyDataHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		apperror.Abort(c, apperror.InvalidParameter("format", "Invalid 'format', must be json or zip"))
+		return
+	}
+
+	// 1. รวบรวมข้อมูลทั้งหมดของผู้ใช้
+	bundle, err := collectExport(context.Background(), st, uid)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		log.Printf("Failed to export data for user %s: %v", uid, err)
+		apperror.Abort(c, apperror.Internal("Could not export data"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionProfileExport,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Metadata:   map[string]string{"format": format},
+	})
+
+	// 2. ส่งเป็นไฟล์แนบตามรูปแบบที่ขอ
+	filename := "meerank-export-" + uid
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, bundle)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := writeExportZip(c.Writer, bundle); err != nil {
+		// ส่ง header ไปแล้วจึงเปลี่ยน status ไม่ได้ ไฟล์ที่ได้จะไม่สมบูรณ์
+		log.Printf("Failed to write export zip for user %s: %v", uid, err)
+	}
+}
+
+// collectExport อ่านข้อมูลทุกส่วนของผู้ใช้ (กิจกรรมอ่านทีละหน้าจนครบ)
+func collectExport(ctx context.Context, st store.Store, uid string) (*ExportBundle, error) {
+	user, err := st.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	activities := []models.Activity{}
+	query := store.ActivityQuery{Limit: exportActivityPageSize}
+	for {
+		page, err := st.ListActivities(ctx, uid, query)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, page.Activities...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	sessions, err := st.ListUserSessions(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportBundle{
+		ExportedAt: time.Now().UTC(),
+		Profile:    user,
+		Activities: activities,
+		Trees:      ExportTrees{NumberTree: user.NumberTree, TreeProgress: user.TreeProgress},
+		Sessions:   sessions,
+	}, nil
+}
+
+// writeExportZip เขียน bundle เป็น ZIP ที่มี profile.csv, activities.csv, trees.csv และ sessions.csv
+func writeExportZip(w http.ResponseWriter, bundle *ExportBundle) error {
+	archive := zip.NewWriter(w)
+	user := bundle.Profile
+
+	profile := [][]string{
+		{"field", "value"},
+		{"id", user.ID},
+		{"name", user.Name},
+		{"phone", stringValue(user.Phone)},
+		{"age", intValue(user.Age)},
+		{"gender", stringValue(user.Gender)},
+		{"locale", user.Locale},
+		{"role", user.Role},
+		{"minute", strconv.Itoa(user.Minute)},
+		{"score", strconv.Itoa(user.Score)},
+		{"last_login_at", timeValue(user.LastLoginAt)},
+		{"exported_at", bundle.ExportedAt.Format(time.RFC3339)},
+	}
+
+	activities := [][]string{{"id", "type", "started_at", "ended_at", "duration_minutes", "score_awarded", "source_device", "created_at", "flags", "review_status"}}
+	for _, a := range bundle.Activities {
+		activities = append(activities, []string{
+			a.ID, a.Type, a.StartedAt.Format(time.RFC3339), a.EndedAt.Format(time.RFC3339),
+			strconv.Itoa(a.DurationMinutes), strconv.Itoa(a.ScoreAwarded), a.SourceDevice,
+			a.CreatedAt.Format(time.RFC3339), strings.Join(a.Flags, ";"), a.ReviewStatus,
+		})
+	}
+
+	trees := [][]string{
+		{"number_tree", "tree_progress"},
+		{strconv.Itoa(bundle.Trees.NumberTree), strconv.Itoa(bundle.Trees.TreeProgress)},
+	}
+
+	sessions := [][]string{{"id", "user_agent", "ip", "created_at", "last_used_at", "expires_at", "revoked_at"}}
+	for _, s := range bundle.Sessions {
+		sessions = append(sessions, []string{
+			s.ID, s.UserAgent, s.IP, s.CreatedAt.Format(time.RFC3339), s.LastUsedAt.Format(time.RFC3339),
+			s.ExpiresAt.Format(time.RFC3339), timeValue(s.RevokedAt),
+		})
+	}
+
+	for _, file := range []struct {
+		name string
+		rows [][]string
+	}{
+		{"profile.csv", profile},
+		{"activities.csv", activities},
+		{"trees.csv", trees},
+		{"sessions.csv", sessions},
+	} {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if err := csv.NewWriter(f).WriteAll(file.rows); err != nil {
+			return fmt.Errorf("write %s: %w", file.name, err)
+		}
+	}
+	return archive.Close()
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func intValue(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func timeValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// --- Account deletion ---
+
+// DeleteMyAccountHandler ตั้งเวลาลบบัญชีของผู้ใช้ที่ล็อกอินอยู่
+// บัญชีใช้งานไม่ได้ทันที (ทุก session ถูกเพิกถอน) ชื่อบน leaderboard และอันดับของฤดูกาลถูกซ่อน
+// และข้อมูลถูกลบถาวรเมื่อครบ models.UserPurgeWindow
+// ยกเลิกได้ก่อนครบกำหนดด้วยการล็อกอินพร้อม "cancel_deletion": true
+func DeleteMyAccountHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+	ctx := context.Background()
+
+	// 1. บันทึกการลบแบบ soft-delete (admin ต้องถูกลด role ก่อน เพื่อไม่ให้ระบบไม่มี admin เหลือ)
+	user, err := st.ModifyUser(ctx, uid, func(user *models.User) error {
+		if user.DeletedAt != nil {
+			return errAccountDeleted
+		}
+		if user.Role == models.RoleAdmin {
+			return errAdminSelfDelete
+		}
+		now := time.Now().UTC()
+		purgeAt := now.Add(models.UserPurgeWindow)
+		user.DeletedAt = &now
+		user.DeletedBy = uid
+		user.PurgeAt = &purgeAt
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+		return
+	case errors.Is(err, errAccountDeleted), errors.Is(err, errAdminSelfDelete):
+		apperror.Abort(c, err)
+		return
+	default:
+		log.Printf("Failed to delete account %s: %v", uid, err)
+		apperror.Abort(c, apperror.Internal("Failed to delete account"))
+		return
+	}
+
+	// 2. ซ่อนชื่อบน leaderboard และเพิกถอนทุก session
+	// ถ้าขั้นนี้ล้มเหลว บัญชีก็ใช้งานไม่ได้แล้ว (AuthMiddleware ปฏิเสธบัญชีที่ถูกลบ) จึงแค่บันทึก log
+	if err := st.RenameLeaderboardEntries(ctx, uid, models.DeletedUserName); err != nil {
+		log.Printf("Failed to anonymize leaderboard entries of user %s: %v", uid, err)
+	}
+	revokeAllSessions(ctx, st, uid)
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionAccountDelete,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Metadata:   map[string]string{"purge_at": user.PurgeAt.Format(time.RFC3339)},
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"code":     i18n.MsgAccountDeletionScheduled,
+		"message":  i18n.Message(c, i18n.MsgAccountDeletionScheduled),
+		"purge_at": user.PurgeAt,
+	})
+}
+
+// cancelAccountDeletion กู้คืนบัญชีที่ผู้ใช้ลบเองและยังไม่ครบกำหนด purge แล้วคืนชื่อบน leaderboard
+func cancelAccountDeletion(ctx context.Context, st store.Store, uid string) (*models.User, error) {
+	user, err := st.ModifyUser(ctx, uid, func(user *models.User) error {
+		if !user.DeletedBySelf() || user.PurgeAt == nil || !time.Now().Before(*user.PurgeAt) {
+			return errDeletionNotCancelable
+		}
+		user.DeletedAt = nil
+		user.DeletedBy = ""
+		user.PurgeAt = nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := st.RenameLeaderboardEntries(ctx, uid, user.Name); err != nil {
+		log.Printf("Failed to restore leaderboard entries of user %s: %v", uid, err)
+	}
+	return user, nil
+}
+
+// revokeAllSessions เพิกถอนทุก session ที่ยังใช้งานได้ของผู้ใช้ (error ถูกบันทึกใน log)
+func revokeAllSessions(ctx context.Context, st store.Store, uid string) {
+	sessions, err := st.ListUserSessions(ctx, uid)
+	if err != nil {
+		log.Printf("Failed to list sessions of user %s: %v", uid, err)
+		return
+	}
+	now := time.Now()
+	for _, session := range sessions {
+		if !session.Active(now) {
+			continue
+		}
+		if err := st.RevokeSession(ctx, uid, session.ID); err != nil {
+			log.Printf("Failed to revoke session %s: %v", session.ID, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meerank/apperror"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/scoring"
+	"meerank/store"
+	"meerank/validation"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultActivityPageSize = 20
+	maxActivityPageSize     = 100
+)
+
+// UpdateUserActivityHandler บันทึกกิจกรรมการออกกำลังกาย และบวกคะแนนกับนาทีให้ผู้ใช้
+// คะแนนคิดโดย scoring.Engine จากประเภทและระยะเวลา ส่วน score ที่ client ส่งมาใช้ตรวจความผิดปกติเท่านั้น
+func UpdateUserActivityHandler(c *gin.Context, st store.Store, scorer *scoring.Engine) {
+	// 1. ดึง uid (string) จาก Middleware
+	uidValue, exists := c.Get("uid")
+	if !exists {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
+		return
+	}
+	uid, ok := uidValue.(string)
+	if !ok {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid User ID format in token"))
+		return
+	}
+
+	// ส่ง minute อย่างเดียว หรือส่งทั้ง started_at และ ended_at (ส่ง minute มาด้วยได้แต่ต้องตรงกับช่วงเวลา)
+	// ข้อมูลที่ไม่ผ่านการตรวจตอบ 422
+	var payload struct {
+		Type         string     `json:"type" binding:"max=32"`
+		Minute       *int       `json:"minute" binding:"omitempty,min=1"`
+		Score        *int       `json:"score" binding:"omitempty,min=0"`
+		StartedAt    *time.Time `json:"started_at"`
+		EndedAt      *time.Time `json:"ended_at"`
+		SourceDevice string     `json:"source_device" binding:"max=255"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+	switch {
+	case payload.StartedAt != nil && payload.EndedAt == nil:
+		validation.Respond(c, validation.Errors{validation.Field("ended_at", validation.CodeRequired, "")})
+		return
+	case payload.StartedAt == nil && payload.EndedAt != nil:
+		validation.Respond(c, validation.Errors{validation.Field("started_at", validation.CodeRequired, "")})
+		return
+	case payload.StartedAt == nil && payload.Minute == nil:
+		validation.Respond(c, validation.Errors{validation.Field("minute", validation.CodeRequired, "")})
+		return
+	}
+
+	// 2. หาช่วงเวลาของกิจกรรม ถ้าไม่ส่งเวลามา ถือว่าเพิ่งจบไปตอนนี้และยาว minute นาที
+	// ถ้าส่งเวลามา ระยะเวลาคิดจากช่วงเวลาเสมอ ไม่เชื่อ minute
+	now := time.Now()
+	var startedAt, endedAt time.Time
+	var duration int
+	if payload.StartedAt != nil {
+		startedAt, endedAt = *payload.StartedAt, *payload.EndedAt
+		duration = int(endedAt.Sub(startedAt) / time.Minute)
+		if payload.Minute != nil && *payload.Minute != duration {
+			validation.Respond(c, validation.Errors{validation.Field("minute", validation.CodeInvalid, "")})
+			return
+		}
+	} else {
+		duration = *payload.Minute
+		endedAt = now
+		startedAt = endedAt.Add(-time.Duration(duration) * time.Minute)
+	}
+
+	activityType := payload.Type
+	if activityType == "" {
+		activityType = models.ActivityTypeExercise
+	}
+	sourceDevice := payload.SourceDevice
+	if sourceDevice == "" {
+		sourceDevice = c.Request.UserAgent()
+	}
+
+	activity := &models.Activity{
+		UserID:          uid,
+		Type:            activityType,
+		StartedAt:       startedAt.UTC(),
+		EndedAt:         endedAt.UTC(),
+		DurationMinutes: duration,
+		SourceDevice:    sourceDevice,
+		CreatedAt:       now,
+	}
+
+	// 3. ตรวจสิ่งที่ไม่ต้องดูประวัติก่อน (ประเภท ระยะเวลา เวลาในอนาคต)
+	if err := scorer.Check(activity, now); err != nil {
+		respondActivityRejected(c, err)
+		return
+	}
+
+	ctx := context.Background()
+
+	// 4. คิดคะแนน บันทึกกิจกรรม และบวกค่าสะสมใน Transaction เดียวกัน
+	from, to := scorer.Window(activity)
+	err := st.RecordActivity(ctx, activity, store.ActivityWindow{From: from, To: to}, func(existing []models.Activity) error {
+		return scorer.Score(activity, payload.Score, existing, now)
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		if errors.Is(err, scoring.ErrOverlap) {
+			respondActivityRejected(c, err)
+			return
+		}
+		log.Printf("Failed to update user activity: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to update user activity"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":     i18n.MsgActivityRecorded,
+		"message":  i18n.Message(c, i18n.MsgActivityRecorded),
+		"activity": activity,
+	})
+}
+
+// respondActivityRejected ตอบกลับเมื่อกิจกรรมถูกปฏิเสธโดยกติกาการคิดคะแนน
+func respondActivityRejected(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, scoring.ErrUnknownType):
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeActivityUnknownType, "Unknown activity type"))
+	case errors.Is(err, scoring.ErrInvalidDuration):
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeActivityTooShort, "Activity duration must be at least one minute"))
+	case errors.Is(err, scoring.ErrSessionTooLong):
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeActivityTooLong, "Activity is longer than the allowed maximum"))
+	case errors.Is(err, scoring.ErrFutureActivity):
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeActivityInFuture, "Activity must not end in the future"))
+	case errors.Is(err, scoring.ErrOverlap):
+		apperror.Abort(c, apperror.Conflict(apperror.CodeActivityOverlap, "Activity overlaps an existing activity"))
+	default:
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeActivityRejected, "Activity rejected"))
+	}
+}
+
+// GetMyActivitiesHandler ดึงประวัติกิจกรรมของผู้ใช้ที่ล็อกอินอยู่ (ใหม่ไปเก่า แบ่งหน้าด้วย cursor)
+// query: limit, cursor, from, to (RFC3339 หรือ YYYY-MM-DD)
+func GetMyActivitiesHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง uid (string) จาก Middleware
+	uidValue, exists := c.Get("uid")
+	if !exists {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
+		return
+	}
+	uid, ok := uidValue.(string)
+	if !ok {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid User ID format in token"))
+		return
+	}
+
+	// 2. อ่านเงื่อนไขการค้นหา
+	limit, ok := intQueryParam(c, "limit", defaultActivityPageSize, maxActivityPageSize)
+	if !ok {
+		return
+	}
+	query := store.ActivityQuery{Cursor: c.Query("cursor"), Limit: limit}
+
+	var err error
+	if query.From, err = parseDateParam(c.Query("from"), false); err != nil {
+		apperror.Abort(c, apperror.InvalidParameter("from", "Invalid 'from' date"))
+		return
+	}
+	if query.To, err = parseDateParam(c.Query("to"), true); err != nil {
+		apperror.Abort(c, apperror.InvalidParameter("to", "Invalid 'to' date"))
+		return
+	}
+
+	ctx := context.Background()
+
+	// 3. ดึงข้อมูลหนึ่งหน้า
+	page, err := st.ListActivities(ctx, uid, query)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			apperror.Abort(c, apperror.InvalidParameter("cursor", "Invalid cursor"))
+			return
+		}
+		log.Printf("Failed to list activities: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to fetch activities"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseDateParam แปลงค่าวันที่จาก query string เป็นเวลา UTC (ว่าง = nil)
+// รับทั้ง RFC3339 และ YYYY-MM-DD ถ้าเป็นวันที่อย่างเดียวและ endOfDay เป็น true
+// จะคืนเวลาเริ่มต้นของวันถัดไป เพื่อให้ช่วง to รวมทั้งวันนั้นด้วย
+func parseDateParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
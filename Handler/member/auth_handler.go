@@ -2,128 +2,148 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/auth"
+	"meerank/config"
+	"meerank/i18n"
 	"meerank/models"
+	"meerank/phones"
+	"meerank/store"
+	"meerank/validation"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"google.golang.org/api/iterator"
 )
 
-// --- JWT Configuration ---
-// !!คำเตือน: ในแอปจริง ห้ามเก็บ Secret Key ไว้ในโค้ดเด็ดขาด!
-// ควรเก็บไว้ใน Environment Variable
-var JwtKey = []byte("your_very_secret_key_that_is_long_and_secure")
+// --- JWT Claims ---
+// กุญแจสำหรับเซ็น Token โหลดจากค่าตั้งผ่าน auth.KeyRing (ดู auth.LoadKeyRing) ส่วนอายุ Token อยู่ใน config.Tokens
 
+// Claims มีเพียง role ตอนออก Token ส่วนสิทธิ์ที่ใช้ตรวจจริง AuthMiddleware คำนวณจาก role ล่าสุดในฐานข้อมูล
 type Claims struct {
-	UserID string `json:"user_id"` // <-- ID ของ Firestore เป็น string
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"` // <-- ID ของ Firestore เป็น string
+	Role      string `json:"role"`
+	SessionID string `json:"sid"` // <-- session ที่ออก Token นี้ ใช้ตรวจสอบการเพิกถอน
 	jwt.RegisteredClaims
 }
 
-// --- Register Handler (สำหรับ Firebase) ---
+// --- Register Handler ---
 
-// RegisterHandler จัดการการสมัครสมาชิกใหม่ด้วย Firestore
-func RegisterHandler(c *gin.Context, client *firestore.Client) {
-	// 1. รับข้อมูลจาก JSON payload (ไม่มี password)
+// RegisterHandler จัดการการสมัครสมาชิกใหม่
+func RegisterHandler(c *gin.Context, st store.Store) {
+	// 1. รับข้อมูลจาก JSON payload (ไม่มี password) ข้อมูลที่ไม่ผ่านกฎใน tag binding ตอบ 422
 	var payload struct {
-		Name   string  `json:"name" binding:"required"`
-		Phone  string  `json:"phone" binding:"required"`
-		Age    *int    `json:"age"`
-		Gender *string `json:"gender"`
+		Name   string  `json:"name" binding:"required,notblank,max=100"`
+		Phone  string  `json:"phone" binding:"required,phone"`
+		Age    *int    `json:"age" binding:"omitempty,min=1,max=120"`
+		Gender *string `json:"gender" binding:"omitempty,gender"`
 	}
-
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	if !validation.BindJSON(c, &payload) {
 		return
 	}
 
-	ctx := context.Background()
-
-	// 2. ตรวจสอบว่าเบอร์โทรศัพท์นี้เคยลงทะเบียนแล้วหรือยัง
-	// สร้าง query เพื่อค้นหา document ที่มี field 'phone' ตรงกับ payload
-	iter := client.Collection(models.CollectionUsers).Where("phone", "==", payload.Phone).Limit(1).Documents(ctx)
-
-	defer iter.Stop()
-
-	// ตรวจสอบว่า query เจอผลลัพธ์หรือไม่
-	_, err := iter.Next()
-	if err != iterator.Done { // ถ้าไม่เจอ error 'Done' แปลว่ามีข้อมูลอยู่แล้ว
-		c.JSON(http.StatusConflict, gin.H{"error": "Phone number already registered"})
+	// 2. แปลงเบอร์โทรศัพท์เป็น E.164 (เบอร์เดียวกันที่พิมพ์ต่างรูปแบบจึงถือเป็นเบอร์เดียวกัน)
+	phone, err := phones.Normalize(payload.Phone)
+	if err != nil {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeInvalidPhone, "Invalid phone number"))
 		return
 	}
 
-	// 3. สร้างข้อมูลผู้ใช้ใหม่เพื่อบันทึกลง Firestore
+	ctx := context.Background()
+
+	// 3. สร้างข้อมูลผู้ใช้ใหม่
 	newUser := models.User{
-		Name:   payload.Name,
-		Phone:  &payload.Phone,
+		Name:   strings.TrimSpace(payload.Name),
+		Phone:  &phone,
 		Role:   models.RoleMember, // กำหนด role เริ่มต้น
 		Age:    payload.Age,
 		Gender: payload.Gender,
 		// ไม่มี Password อีกต่อไป
 	}
 
-	// 4. บันทึกผู้ใช้ใหม่ลงใน collection "users"
-	// Firestore จะสร้าง ID ให้โดยอัตโนมัติ
-	_, _, err = client.Collection(models.CollectionUsers).Add(ctx, newUser)
-	if err != nil {
-		log.Printf("Failed to create user in Firestore: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	// 4. บันทึกผู้ใช้ใหม่ (store จะสร้าง ID ให้โดยอัตโนมัติ)
+	// store จองเบอร์ใน Transaction เดียวกัน การสมัครพร้อมกันด้วยเบอร์เดียวกันจึงสำเร็จได้คนเดียว
+	if _, err := st.CreateUser(ctx, &newUser); err != nil {
+		if errors.Is(err, store.ErrPhoneTaken) {
+			apperror.Abort(c, apperror.Conflict(apperror.CodePhoneTaken, "Phone number already registered"))
+			return
+		}
+		log.Printf("Failed to create user: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to create user"))
 		return
 	}
 
 	// 5. ส่งคำตอบกลับไป
-	c.JSON(http.StatusCreated, gin.H{"message": "Registration successful"})
+	c.JSON(http.StatusCreated, gin.H{"code": i18n.MsgRegistered, "message": i18n.Message(c, i18n.MsgRegistered)})
 }
 
-// --- Login Handler (สำหรับ Firebase) ---
-
-// LoginHandler จัดการการล็อกอิน, คำนวณวัน, และอัปเดตเวลาล่าสุด
-func LoginHandler(c *gin.Context, client *firestore.Client) {
-	// 1. รับข้อมูลจาก JSON payload (มีแค่เบอร์โทร)
-	var payload struct {
-		Phone string `json:"phone" binding:"required"`
+// --- Login (หลังจากยืนยัน OTP แล้ว) ---
+
+// InactiveAccountError คืน error ถ้าบัญชีถูกระงับหรือถูกลบ (nil = ใช้งานได้)
+// ใช้ตอนล็อกอิน ตอนต่ออายุ Token และใน AuthMiddleware
+func InactiveAccountError(user *models.User) *apperror.Error {
+	switch user.Status() {
+	case models.UserStatusSuspended:
+		return apperror.Forbidden(apperror.CodeAccountSuspended, "Account is suspended")
+	case models.UserStatusDeleted:
+		return apperror.Forbidden(apperror.CodeAccountDeleted, "Account has been deleted")
+	default:
+		return nil
 	}
+}
 
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
-		return
-	}
-
-	ctx := context.Background()
-
-	// 2. ค้นหาผู้ใช้จากเบอร์โทรศัพท์ใน Firestore
-	var user models.User
-	var docID string
-
-	iter := client.Collection("users").Where("phone", "==", payload.Phone).Limit(1).Documents(ctx)
-	defer iter.Stop()
-
-	doc, err := iter.Next()
-	if err == iterator.Done {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Phone number not found"})
-		return
+// completeLogin คำนวณวันที่ไม่ได้ล็อกอิน, อัปเดตเวลาล่าสุด และออก JWT ให้ผู้ใช้
+// ถูกเรียกหลังจากผู้ใช้ยืนยันตัวตนสำเร็จแล้วเท่านั้น
+// cancelDeletion ยกเลิกการลบบัญชีที่ผู้ใช้สั่งไว้เอง (ถ้ายังไม่ครบกำหนด purge)
+func completeLogin(c *gin.Context, ctx context.Context, st store.Store, user *models.User, keys *auth.KeyRing, tokens config.Tokens, cancelDeletion bool) {
+	docID := user.ID
+
+	// 0. บัญชีที่ผู้ใช้ลบเองกลับมาใช้ได้เมื่อขอยกเลิกการลบ ส่วนบัญชีที่ถูกระงับหรือถูกลบล็อกอินไม่ได้
+	if cancelDeletion && user.DeletedBySelf() {
+		restored, err := cancelAccountDeletion(ctx, st, docID)
+		switch {
+		case err == nil:
+			user = restored
+			audit.Record(c, st, models.AuditEntry{
+				ActorID:    docID,
+				ActorRole:  user.Role,
+				Action:     models.AuditActionAccountDeletionCancel,
+				TargetType: models.AuditTargetUser,
+				TargetID:   docID,
+			})
+		case errors.Is(err, errDeletionNotCancelable):
+		default:
+			log.Printf("Failed to cancel deletion of user %s: %v", docID, err)
+			apperror.Abort(c, apperror.Internal("Failed to cancel account deletion"))
+			return
+		}
 	}
-	if err != nil {
-		log.Printf("Error querying user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+	if inactive := InactiveAccountError(user); inactive != nil {
+		audit.Record(c, st, models.AuditEntry{
+			ActorID:    docID,
+			ActorRole:  user.Role,
+			Action:     models.AuditActionLoginBlocked,
+			TargetType: models.AuditTargetUser,
+			TargetID:   docID,
+			Reason:     inactive.Message,
+		})
+		if user.DeletedBySelf() && user.PurgeAt != nil && time.Now().Before(*user.PurgeAt) {
+			inactive = inactive.WithDetails(gin.H{
+				"purge_at": user.PurgeAt,
+				"hint":     i18n.Message(c, i18n.MsgCancelDeletionHint),
+			})
+		}
+		apperror.Abort(c, inactive)
 		return
 	}
 
-	if err := doc.DataTo(&user); err != nil {
-		log.Printf("Error converting user data: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return
-	}
-	docID = doc.Ref.ID
-
-	// --- V V V ส่วนที่เพิ่มเข้ามา V V V ---
-
-	// 3. คำนวณจำนวนวันที่ไม่ได้ล็อกอิน
+	// 1. คำนวณจำนวนวันที่ไม่ได้ล็อกอิน
 	var daysSinceLastLogin int
 	now := time.Now()
 
@@ -139,41 +159,44 @@ func LoginHandler(c *gin.Context, client *firestore.Client) {
 		daysSinceLastLogin = int(duration.Hours() / 24)
 	}
 
-	// 4. อัปเดตเวลาล็อกอินล่าสุดใน Firestore ให้เป็นเวลาปัจจุบัน
+	// 2. อัปเดตเวลาล็อกอินล่าสุดให้เป็นเวลาปัจจุบัน
 	// ทำขั้นตอนนี้หลังจากคำนวณเสร็จแล้ว
-	updateData := []firestore.Update{
-		{Path: "last_login_at", Value: now},
-	}
-	if _, err := doc.Ref.Update(ctx, updateData); err != nil {
+	if err := st.UpdateUser(ctx, docID, store.UserUpdate{LastLoginAt: &now}); err != nil {
 		// บันทึก error แต่ไม่ต้องหยุดการทำงาน เพื่อให้ผู้ใช้ยังล็อกอินได้
 		log.Printf("Failed to update last login time for user %s: %v", docID, err)
 	}
 
-	// --- ^ ^ ^ สิ้นสุดส่วนที่เพิ่มเข้ามา ^ ^ ^ ---
-
-	// 5. สร้าง JWT Token
-	expirationTime := time.Now().Add(12 * time.Hour)
-	claims := &Claims{
-		UserID: docID,
-		Role:   user.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+	// 3. สร้าง session ใหม่พร้อม Refresh Token สำหรับอุปกรณ์นี้
+	sessionID, refreshToken, err := createSession(c, ctx, st, docID, tokens.RefreshTTL)
+	if err != nil {
+		log.Printf("Failed to create session for user %s: %v", docID, err)
+		apperror.Abort(c, apperror.Internal("Could not create session"))
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(JwtKey)
+	// 4. สร้าง Access Token อายุสั้นที่ผูกกับ session
+	tokenString, err := issueAccessToken(keys, tokens.AccessTTL, docID, user.Role, sessionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		apperror.Abort(c, apperror.Internal("Could not generate token"))
 		return
 	}
 
-	// 6. ส่งคำตอบกลับพร้อม Token และจำนวนวันที่ไม่ได้ล็อกอิน
+	// 5. บันทึกการล็อกอินลง audit log แล้วส่งคำตอบกลับพร้อม Token และจำนวนวันที่ไม่ได้ล็อกอิน
+	audit.Record(c, st, models.AuditEntry{
+		ActorID:    docID,
+		ActorRole:  user.Role,
+		Action:     models.AuditActionLogin,
+		TargetType: models.AuditTargetSession,
+		TargetID:   sessionID,
+	})
 	c.JSON(http.StatusOK, gin.H{
-		"message":               "Login successful",
+		"code":                  i18n.MsgLoginSuccessful,
+		"message":               i18n.Message(c, i18n.MsgLoginSuccessful),
 		"name":                  user.Name,
 		"token":                 tokenString,
+		"refresh_token":         refreshToken,
+		"expires_in":            int(tokens.AccessTTL.Seconds()),
 		"role":                  user.Role,
-		"days_since_last_login": daysSinceLastLogin, // <-- เพิ่มค่านี้เข้าไปใน response
+		"days_since_last_login": daysSinceLastLogin,
 	})
 }
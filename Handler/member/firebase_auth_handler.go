@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"meerank/apperror"
+	"meerank/auth"
+	"meerank/config"
+	"meerank/models"
+	"meerank/phones"
+	"meerank/store"
+	"meerank/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errFirebaseAccountLinked คือบัญชีที่ใช้เบอร์เดียวกันผูกกับ Firebase UID อื่นอยู่แล้ว
+var errFirebaseAccountLinked = apperror.Conflict(apperror.CodeFirebaseAccountLinked, "Phone number is already linked to another sign-in account")
+
+// FirebaseLoginHandler แลก ID Token ของ Firebase Authentication เป็น Token ของระบบเรา
+// ผู้ใช้ที่ล็อกอินผ่าน Firebase ครั้งแรกจะถูกผูกกับบัญชีเดิม (ถ้าเบอร์ตรงกัน) หรือสร้างบัญชีใหม่
+// บัญชีเดิมที่ผูกกับ Firebase UID อื่นไว้แล้วจะไม่ถูกผูกใหม่ (ตอบ 409)
+func FirebaseLoginHandler(c *gin.Context, st store.Store, verifier auth.IDTokenVerifier, keys *auth.KeyRing, tokens config.Tokens) {
+	// 1. รับ ID Token จาก JSON payload
+	var payload struct {
+		IDToken string `json:"id_token" binding:"required"`
+		// CancelDeletion ยกเลิกการลบบัญชีที่ผู้ใช้สั่งไว้ (ดู DeleteMyAccountHandler)
+		CancelDeletion bool `json:"cancel_deletion"`
+	}
+
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. ตรวจสอบ ID Token ผ่าน verifier
+	identity, err := verifier.VerifyIDToken(ctx, payload.IDToken)
+	if err != nil {
+		log.Printf("Invalid Firebase ID token: %v", err)
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidIDToken, "Invalid or expired ID token"))
+		return
+	}
+
+	// 3. หาผู้ใช้ที่ผูกกับ Firebase UID นี้
+	user, err := st.FindUserByFirebaseUID(ctx, identity.UID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Error querying user: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+
+	// 4. ถ้ายังไม่เคยผูก ให้ผูกกับบัญชีที่ใช้เบอร์เดียวกัน หรือสร้างบัญชีใหม่
+	if errors.Is(err, store.ErrNotFound) {
+		user, err = linkOrCreateFirebaseUser(ctx, st, identity)
+		if errors.Is(err, errFirebaseAccountLinked) {
+			apperror.Abort(c, err)
+			return
+		}
+		if errors.Is(err, store.ErrPhoneTaken) {
+			// มีคนสมัครด้วยเบอร์เดียวกันพร้อมกัน ล็อกอินใหม่อีกครั้งจะผูกกับบัญชีนั้นแทน
+			apperror.Abort(c, apperror.Conflict(apperror.CodePhoneTaken, "Phone number already registered, please try again"))
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to create user for Firebase UID %s: %v", identity.UID, err)
+			apperror.Abort(c, apperror.Internal("Failed to create user"))
+			return
+		}
+	}
+
+	completeLogin(c, ctx, st, user, keys, tokens, payload.CancelDeletion)
+}
+
+func linkOrCreateFirebaseUser(ctx context.Context, st store.Store, identity *auth.Identity) (*models.User, error) {
+	// เบอร์โทรใน Firebase ผ่านการยืนยันมาแล้ว จึงผูกกับบัญชีเดิมที่ใช้เบอร์เดียวกันได้
+	phone := ""
+	if identity.Phone != "" {
+		normalized, err := phones.Normalize(identity.Phone)
+		if err != nil {
+			log.Printf("Ignoring invalid phone number from Firebase UID %s: %v", identity.UID, err)
+		}
+		phone = normalized
+	}
+	if phone != "" {
+		user, err := st.FindUserByPhone(ctx, phone)
+		if err == nil {
+			// ตรวจและผูกใน Transaction เดียวกัน เพื่อไม่ให้ทับ Firebase UID ที่ผูกไว้แล้ว
+			return st.ModifyUser(ctx, user.ID, func(user *models.User) error {
+				if user.FirebaseUID != nil && *user.FirebaseUID != identity.UID {
+					return errFirebaseAccountLinked
+				}
+				user.FirebaseUID = &identity.UID
+				return nil
+			})
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = "Member"
+	}
+	newUser := models.User{
+		Name:        name,
+		Role:        models.RoleMember,
+		FirebaseUID: &identity.UID,
+	}
+	if phone != "" {
+		newUser.Phone = &phone
+	}
+
+	if _, err := st.CreateUser(ctx, &newUser); err != nil {
+		return nil, err
+	}
+	return &newUser, nil
+}
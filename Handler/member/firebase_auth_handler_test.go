@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handlers "meerank/Handler/member"
+	"meerank/apperror"
+	"meerank/auth"
+	"meerank/config"
+	"meerank/middleware"
+	"meerank/models"
+	"meerank/periods"
+	"meerank/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testPhone = "+66812345678"
+
+type firebaseLoginTest struct {
+	st       *store.MemoryStore
+	verifier *auth.LocalVerifier
+	router   *gin.Engine
+}
+
+func newFirebaseLoginTest(t *testing.T) *firebaseLoginTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cal, err := periods.NewCalendar("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := auth.NewLocalVerifier("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeyRing(config.JWT{Secret: "test-secret-test-secret-test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := config.Default().Auth.Tokens
+
+	tt := &firebaseLoginTest{st: store.NewMemoryStore(cal), verifier: verifier, router: gin.New()}
+	tt.router.Use(middleware.ErrorMiddleware())
+	tt.router.POST("/auth/firebase", func(c *gin.Context) { handlers.FirebaseLoginHandler(c, tt.st, verifier, keys, tokens) })
+	return tt
+}
+
+// login ส่ง ID Token ของ uid (พร้อมเบอร์ถ้าไม่ว่าง) แล้วคืน status และ code ของคำตอบ
+func (tt *firebaseLoginTest) login(t *testing.T, uid, phone string) (int, string) {
+	t.Helper()
+	claims := map[string]interface{}{}
+	if phone != "" {
+		claims["phone_number"] = phone
+	}
+	idToken, err := tt.verifier.Mint(uid, claims, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tt.post(t, idToken)
+}
+
+func (tt *firebaseLoginTest) post(t *testing.T, idToken string) (int, string) {
+	t.Helper()
+	body, _ := json.Marshal(gin.H{"id_token": idToken})
+	req := httptest.NewRequest(http.MethodPost, "/auth/firebase", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	tt.router.ServeHTTP(rec, req)
+
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp.Code
+}
+
+func (tt *firebaseLoginTest) createUser(t *testing.T, firebaseUID string) *models.User {
+	t.Helper()
+	phone := testPhone
+	user := &models.User{Name: "Somchai", Role: models.RoleMember, Phone: &phone}
+	if firebaseUID != "" {
+		user.FirebaseUID = &firebaseUID
+	}
+	if _, err := tt.st.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestFirebaseLoginCreatesUser(t *testing.T) {
+	tt := newFirebaseLoginTest(t)
+
+	if status, code := tt.login(t, "fb-1", testPhone); status != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", status, code)
+	}
+	user, err := tt.st.FindUserByFirebaseUID(context.Background(), "fb-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Phone == nil || *user.Phone != testPhone {
+		t.Errorf("phone = %v, want %s", user.Phone, testPhone)
+	}
+
+	// ล็อกอินครั้งถัดไปใช้บัญชีเดิม
+	if status, code := tt.login(t, "fb-1", testPhone); status != http.StatusOK {
+		t.Fatalf("second login status = %d (%s), want 200", status, code)
+	}
+	if count, _ := tt.st.CountUsers(context.Background()); count != 1 {
+		t.Errorf("users = %d, want 1", count)
+	}
+}
+
+func TestFirebaseLoginLinksExistingPhone(t *testing.T) {
+	tt := newFirebaseLoginTest(t)
+	existing := tt.createUser(t, "")
+
+	if status, code := tt.login(t, "fb-1", "0812345678"); status != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", status, code)
+	}
+	user, err := tt.st.FindUserByFirebaseUID(context.Background(), "fb-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != existing.ID {
+		t.Errorf("linked user = %s, want existing user %s", user.ID, existing.ID)
+	}
+}
+
+func TestFirebaseLoginRejectsPhoneLinkedToAnotherUID(t *testing.T) {
+	tt := newFirebaseLoginTest(t)
+	existing := tt.createUser(t, "fb-old")
+
+	status, code := tt.login(t, "fb-new", testPhone)
+	if status != http.StatusConflict || code != apperror.CodeFirebaseAccountLinked {
+		t.Fatalf("got %d %s, want 409 %s", status, code, apperror.CodeFirebaseAccountLinked)
+	}
+	user, err := tt.st.GetUser(context.Background(), existing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.FirebaseUID == nil || *user.FirebaseUID != "fb-old" {
+		t.Errorf("firebase_uid = %v, want fb-old", user.FirebaseUID)
+	}
+}
+
+func TestFirebaseLoginRejectsInvalidToken(t *testing.T) {
+	tt := newFirebaseLoginTest(t)
+
+	other, err := auth.NewLocalVerifier("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToken, err := other.Mint("fb-1", nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, code := tt.post(t, idToken)
+	if status != http.StatusUnauthorized || code != apperror.CodeInvalidIDToken {
+		t.Fatalf("got %d %s, want 401 %s", status, code, apperror.CodeInvalidIDToken)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"meerank/apperror"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errFriendRequestSent = apperror.Conflict(apperror.CodeFriendRequestPending, "Friend request already sent")
+	errAlreadyFriends    = apperror.Conflict(apperror.CodeAlreadyFriends, "You are already friends")
+)
+
+// FriendEntry คือความสัมพันธ์หนึ่งรายการในรายชื่อเพื่อนของผู้ใช้
+type FriendEntry struct {
+	UID          string     `json:"uid"`
+	Name         string     `json:"name"`
+	Relationship string     `json:"relationship"` // friend, request_sent หรือ request_received
+	CreatedAt    time.Time  `json:"created_at"`
+	AcceptedAt   *time.Time `json:"accepted_at,omitempty"`
+}
+
+// GetMyFriendsHandler คืนเพื่อนและคำขอเป็นเพื่อนที่ยังรอตอบของผู้ใช้ที่ล็อกอินอยู่
+// ผู้ใช้ที่ถูกลบแล้วไม่แสดงในรายการ
+func GetMyFriendsHandler(c *gin.Context, st store.Store) {
+	ctx := context.Background()
+
+	// 1. อ่านความสัมพันธ์ทั้งหมดของผู้เรียก
+	v, ok := loadViewer(ctx, c, st)
+	if !ok {
+		return
+	}
+
+	ids := make([]string, 0, len(v.friends))
+	for id := range v.friends {
+		ids = append(ids, id)
+	}
+	users, err := st.GetUsers(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to load friends of user %s: %v", v.uid, err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+
+	// 2. แปลงเป็นรายการ เพื่อนและผู้ที่ส่งคำขอมาเห็นชื่อจริง ส่วนคำขอที่ส่งไปแสดงชื่อตามการตั้งค่าของอีกฝ่าย
+	friends := []FriendEntry{}
+	for id, friendship := range v.friends {
+		user, ok := users[id]
+		if !ok || user.DeletedAt != nil {
+			continue
+		}
+		relationship := v.relationship(id)
+		name := user.Name
+		if relationship == relationshipRequestSent {
+			name = v.leaderboardName(&user)
+		}
+		friends = append(friends, FriendEntry{
+			UID:          id,
+			Name:         name,
+			Relationship: relationship,
+			CreatedAt:    friendship.CreatedAt,
+			AcceptedAt:   friendship.AcceptedAt,
+		})
+	}
+	// 3. เรียงรายการใหม่ล่าสุดก่อน
+	sort.Slice(friends, func(i, j int) bool { return friends[i].CreatedAt.After(friends[j].CreatedAt) })
+
+	c.JSON(http.StatusOK, gin.H{"friends": friends})
+}
+
+// AddFriendHandler ส่งคำขอเป็นเพื่อนถึงผู้ใช้ :uid หรือตอบรับคำขอที่ :uid ส่งมา
+func AddFriendHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+	otherID := c.Param("uid")
+	if otherID == uid {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeCannotFriendSelf, "You cannot add yourself as a friend"))
+		return
+	}
+
+	ctx := context.Background()
+
+	// 1. ผู้ใช้ปลายทางต้องมีอยู่และยังไม่ถูกลบ
+	other, err := st.GetUser(ctx, otherID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		log.Printf("Failed to get user: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+	if other.DeletedAt != nil {
+		apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+		return
+	}
+
+	// 2. สร้างคำขอใหม่ หรือตอบรับคำขอที่อีกฝ่ายส่งมาแล้ว ภายใน Transaction
+	friendship, err := st.UpdateFriendship(ctx, uid, otherID, func(friendship *models.Friendship) (*models.Friendship, error) {
+		now := time.Now().UTC()
+		switch {
+		case friendship == nil:
+			return models.NewFriendship(uid, otherID, now), nil
+		case friendship.Status == models.FriendshipAccepted:
+			return nil, errAlreadyFriends
+		case friendship.RequestedBy == uid:
+			return nil, errFriendRequestSent
+		}
+		friendship.Status = models.FriendshipAccepted
+		friendship.AcceptedAt = &now
+		return friendship, nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errAlreadyFriends), errors.Is(err, errFriendRequestSent):
+		apperror.Abort(c, err)
+		return
+	default:
+		log.Printf("Failed to update friendship %s: %v", models.FriendshipID(uid, otherID), err)
+		apperror.Abort(c, apperror.Internal("Could not update friendship"))
+		return
+	}
+
+	if friendship.Status == models.FriendshipAccepted {
+		c.JSON(http.StatusOK, gin.H{"code": i18n.MsgFriendRequestAccepted, "message": i18n.Message(c, i18n.MsgFriendRequestAccepted), "relationship": relationshipFriend})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"code": i18n.MsgFriendRequestSent, "message": i18n.Message(c, i18n.MsgFriendRequestSent), "relationship": relationshipRequestSent})
+}
+
+// RemoveFriendHandler เลิกเป็นเพื่อนกับ :uid หรือยกเลิก/ปฏิเสธคำขอที่ยังรอตอบ
+func RemoveFriendHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+	otherID := c.Param("uid")
+
+	_, err := st.UpdateFriendship(context.Background(), uid, otherID, func(friendship *models.Friendship) (*models.Friendship, error) {
+		if friendship == nil {
+			return nil, store.ErrNotFound
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeFriendshipNotFound, "Friendship not found"))
+			return
+		}
+		log.Printf("Failed to delete friendship %s: %v", models.FriendshipID(uid, otherID), err)
+		apperror.Abort(c, apperror.Internal("Could not update friendship"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": i18n.MsgFriendshipRemoved, "message": i18n.Message(c, i18n.MsgFriendshipRemoved)})
+}
@@ -2,63 +2,185 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
+	"meerank/apperror"
+	"meerank/config"
 	"meerank/models"
+	"meerank/store"
 	"net/http"
+	"strconv"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
-	"google.golang.org/api/iterator"
+)
+
+const (
+	defaultLeaderboardAround = 5
+	maxLeaderboardAround     = 25
 )
 
 // LeaderboardEntry struct สำหรับข้อมูลที่จะส่งกลับไป
-// ✨ แก้ไข: เอา Rank ออก เพราะ Frontend จะเป็นคนจัดการลำดับเอง
+// Rank คำนวณที่เซิร์ฟเวอร์ คนที่ number_tree และ score เท่ากันได้อันดับเดียวกัน (1, 2, 2, 4)
 type LeaderboardEntry struct {
+	Rank       int    `json:"rank"`
+	UID        string `json:"uid"`
 	Name       string `json:"name"`
 	NumberTree int    `json:"number_tree"`
 	Score      int    `json:"score"`
 }
 
-// GetLeaderboardHandler ดึงข้อมูลผู้ใช้มาจัดอันดับจาก Firestore
-func GetLeaderboardHandler(c *gin.Context, client *firestore.Client) {
+func toLeaderboardEntries(rows []store.LeaderboardRow, names map[string]string) []LeaderboardEntry {
+	entries := make([]LeaderboardEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, toLeaderboardEntry(row, names))
+	}
+	return entries
+}
+
+// toLeaderboardEntry แปลงแถวเป็น entry โดยใช้ชื่อจาก names (ผลของ leaderboardNames) ถ้ามี
+func toLeaderboardEntry(row store.LeaderboardRow, names map[string]string) LeaderboardEntry {
+	name, ok := names[row.UserID]
+	if !ok {
+		name = row.Name
+	}
+	return LeaderboardEntry{
+		Rank:       row.Rank,
+		UID:        row.UserID,
+		Name:       name,
+		NumberTree: row.NumberTree,
+		Score:      row.Score,
+	}
+}
+
+// GetLeaderboardHandler ดึงข้อมูลผู้ใช้มาจัดอันดับทีละหน้า
+// query: period (day|week|month|all ค่าเริ่มต้น all), limit (ค่าเริ่มต้นและค่าสูงสุดตาม config.Leaderboard),
+// cursor (ค่า next_cursor จากหน้าก่อนหน้า)
+func GetLeaderboardHandler(c *gin.Context, st store.Store, board config.Leaderboard) {
+	// 1. อ่านช่วงเวลาและเงื่อนไขการแบ่งหน้า
+	period, ok := periodQueryParam(c)
+	if !ok {
+		return
+	}
+	limit, ok := intQueryParam(c, "limit", board.DefaultPageSize, board.MaxPageSize)
+	if !ok {
+		return
+	}
+
 	ctx := context.Background()
-	var leaderboard []LeaderboardEntry
-
-	// 1. สร้าง Query เพื่อดึงข้อมูลผู้ใช้ 10 อันดับแรก
-	// ✨ เพิ่ม .Where(...) เพื่อกรองเอาเฉพาะ member เท่านั้น
-	iter := client.Collection(models.CollectionUsers).
-		Where("role", "==", models.RoleMember). // <-- เพิ่มบรรทัดนี้
-		OrderBy("number_tree", firestore.Desc).
-		OrderBy("score", firestore.Desc).
-		Limit(10).
-		Documents(ctx)
-	defer iter.Stop()
-
-	// 2. วนลูปเพื่ออ่านข้อมูลและสร้างผลลัพธ์ (ไม่ต้องนับ Rank แล้ว)
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Failed to iterate leaderboard data: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard data"})
+
+	// 2. ดึงข้อมูลสมาชิก (เฉพาะ role member) หนึ่งหน้าพร้อมอันดับ
+	page, err := st.Leaderboard(ctx, store.LeaderboardQuery{Period: period, Cursor: c.Query("cursor"), Limit: limit})
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			apperror.Abort(c, apperror.InvalidParameter("cursor", "Invalid cursor"))
 			return
 		}
+		log.Printf("Failed to fetch leaderboard data: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to fetch leaderboard data"))
+		return
+	}
+
+	// 3. แสดงชื่อตามการตั้งค่าความเป็นส่วนตัวของแต่ละคน
+	names, ok := leaderboardNames(ctx, c, st, page.Rows)
+	if !ok {
+		return
+	}
+
+	// 4. ส่งข้อมูลที่ได้กลับไปให้ Frontend
 
-		var user models.User
-		if err := doc.DataTo(&user); err != nil {
-			log.Printf("Failed to convert user data for leaderboard: %v", err)
-			continue
+	response := gin.H{"period": period, "entries": toLeaderboardEntries(page.Rows, names)}
+	if page.PeriodKey != "" {
+		response["period_key"] = page.PeriodKey
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetMyLeaderboardHandler คืนอันดับของผู้ใช้ที่ล็อกอินอยู่ พร้อม n คนที่อยู่ด้านบนและด้านล่าง
+// query: period (day|week|month|all ค่าเริ่มต้น all), n (ค่าเริ่มต้น 5)
+func GetMyLeaderboardHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง uid (string) จาก Middleware
+	uidValue, exists := c.Get("uid")
+	if !exists {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
+		return
+	}
+	uid, ok := uidValue.(string)
+	if !ok {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid User ID format in token"))
+		return
+	}
+
+	period, ok := periodQueryParam(c)
+	if !ok {
+		return
+	}
+	n, ok := intQueryParam(c, "n", defaultLeaderboardAround, maxLeaderboardAround)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. หาอันดับของตัวเองและคนรอบข้าง
+	around, err := st.LeaderboardAround(ctx, period, uid, n)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			if period != models.PeriodAll {
+				apperror.Abort(c, apperror.NotFound(apperror.CodeNoActivityInPeriod, "No activity in this period yet"))
+				return
+			}
+			apperror.Abort(c, apperror.NotFound(apperror.CodeNotOnLeaderboard, "You are not on the leaderboard"))
+			return
 		}
+		log.Printf("Failed to fetch leaderboard rank: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to fetch leaderboard data"))
+		return
+	}
+
+	// 3. แสดงชื่อตามการตั้งค่าความเป็นส่วนตัวของแต่ละคน
+	names, ok := leaderboardNames(ctx, c, st, []store.LeaderboardRow{around.Me}, around.Above, around.Below)
+	if !ok {
+		return
+	}
+
+	response := gin.H{
+		"period": period,
+		"me":     toLeaderboardEntry(around.Me, names),
+		"above":  toLeaderboardEntries(around.Above, names),
+		"below":  toLeaderboardEntries(around.Below, names),
+	}
+	if around.PeriodKey != "" {
+		response["period_key"] = around.PeriodKey
+	}
+	c.JSON(http.StatusOK, response)
+}
 
-		leaderboard = append(leaderboard, LeaderboardEntry{
-			Name:       user.Name,
-			NumberTree: user.NumberTree,
-			Score:      user.Score,
-		})
+// periodQueryParam อ่านช่วงเวลาของ leaderboard (ไม่ส่งมา = all)
+// ถ้าค่าไม่ถูกต้องจะตอบ 400 ให้แล้วและคืน ok เป็น false
+func periodQueryParam(c *gin.Context) (string, bool) {
+	period := c.DefaultQuery("period", models.PeriodAll)
+	switch period {
+	case models.PeriodDay, models.PeriodWeek, models.PeriodMonth, models.PeriodAll:
+		return period, true
 	}
+	apperror.Abort(c, apperror.InvalidParameter("period", "Invalid 'period', must be day, week, month or all"))
+	return "", false
+}
 
-	// 3. ส่งข้อมูลที่ได้กลับไปให้ Frontend
-	c.JSON(http.StatusOK, leaderboard)
+// intQueryParam อ่านจำนวนเต็มบวกจาก query string (ไม่ส่งมา = fallback, เกิน upper = upper)
+// ถ้าค่าไม่ถูกต้องจะตอบ 400 ให้แล้วและคืน ok เป็น false
+func intQueryParam(c *gin.Context, name string, fallback, upper int) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return fallback, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		apperror.Abort(c, apperror.InvalidParameter(name, "'"+name+"' must be a positive integer"))
+		return 0, false
+	}
+	return min(value, upper), true
 }
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"time"
+
+	"meerank/apperror"
+	"meerank/auth"
+	"meerank/config"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/phones"
+	"meerank/sms"
+	"meerank/store"
+	"meerank/validation"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errOTPInvalid         = errors.New("invalid otp")
+	errOTPExpired         = errors.New("otp expired")
+	errOTPTooManyAttempts = errors.New("too many otp attempts")
+)
+
+// --- Request OTP Handler ---
+
+// RequestLoginOTPHandler สร้างรหัส OTP และส่ง SMS ไปยังเบอร์ที่ลงทะเบียนไว้
+func RequestLoginOTPHandler(c *gin.Context, st store.Store, sender sms.Sender) {
+	// 1. รับเบอร์โทรศัพท์จาก JSON payload แล้วแปลงเป็น E.164
+	var payload struct {
+		Phone string `json:"phone" binding:"required"`
+	}
+
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+	phone, err := phones.Normalize(payload.Phone)
+	if err != nil {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeInvalidPhone, "Invalid phone number"))
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. ตรวจสอบว่ามีผู้ใช้เบอร์นี้หรือไม่
+	// ถ้าไม่มีจะตอบกลับเหมือนกรณีปกติ เพื่อไม่ให้ใช้ endpoint นี้เดาเบอร์ที่ลงทะเบียนได้
+	if _, err := st.FindUserByPhone(ctx, phone); err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Error querying user: %v", err)
+			apperror.Abort(c, apperror.Internal("Database error"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": i18n.MsgOTPSent, "message": i18n.Message(c, i18n.MsgOTPSent)})
+		return
+	}
+
+	// 3. สร้างและส่งรหัส
+	if !sendOTP(c, ctx, st, sender, models.LoginOTP{ID: models.LoginOTPID(phone), Phone: phone}, i18n.MsgOTPLoginSMS) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": i18n.MsgOTPSent, "message": i18n.Message(c, i18n.MsgOTPSent)})
+}
+
+// --- Verify OTP Handler ---
+
+// VerifyLoginOTPHandler ตรวจสอบรหัส OTP แล้วออก JWT ให้ผู้ใช้
+func VerifyLoginOTPHandler(c *gin.Context, st store.Store, keys *auth.KeyRing, tokens config.Tokens) {
+	// 1. รับเบอร์โทรศัพท์และรหัส OTP
+	var payload struct {
+		Phone string `json:"phone" binding:"required"`
+		Code  string `json:"code" binding:"required"`
+		// CancelDeletion ยกเลิกการลบบัญชีที่ผู้ใช้สั่งไว้ (ดู DeleteMyAccountHandler)
+		CancelDeletion bool `json:"cancel_deletion"`
+	}
+
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+	phone, err := phones.Normalize(payload.Phone)
+	if err != nil {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeInvalidPhone, "Invalid phone number"))
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. ตรวจสอบรหัส (รหัสยืนยันการเปลี่ยนเบอร์มี ID ต่างกันจึงใช้ล็อกอินไม่ได้)
+	if !checkOTP(c, ctx, st, models.LoginOTPID(phone), payload.Code) {
+		return
+	}
+
+	// 3. ดึงข้อมูลผู้ใช้แล้วออก Token
+	user, err := st.FindUserByPhone(ctx, phone)
+	if errors.Is(err, store.ErrNotFound) {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodePhoneNotRegistered, "Phone number not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying user: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+
+	completeLogin(c, ctx, st, user, keys, tokens, payload.CancelDeletion)
+}
+
+// --- OTP ---
+
+// sendOTP สร้างรหัสใหม่ (เมื่อพ้นช่วง cooldown) เก็บเฉพาะค่า hash แล้วส่ง SMS ไปยัง otp.Phone
+// otp ระบุ ID, Phone และ UserID ของรหัส ส่วน smsKey คือ key ของข้อความ SMS (ดู i18n.MsgOTPLoginSMS)
+// ถ้าไม่สำเร็จจะตอบ error ให้แล้วและคืน false
+func sendOTP(c *gin.Context, ctx context.Context, st store.Store, sender sms.Sender, otp models.LoginOTP, smsKey string) bool {
+	now := time.Now()
+
+	// 1. ป้องกันการขอรหัสถี่เกินไป (นับแยกตาม ID ของรหัส)
+	existing, err := st.GetLoginOTP(ctx, otp.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Error reading OTP: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return false
+	}
+	if err == nil && now.Sub(existing.CreatedAt) < models.OTPResendCooldown {
+		apperror.Abort(c, apperror.TooManyRequests(apperror.CodeOTPCooldown, "Please wait before requesting a new OTP"))
+		return false
+	}
+
+	// 2. สร้างรหัสใหม่และเก็บเฉพาะค่า hash
+	code, err := generateOTPCode()
+	if err != nil {
+		log.Printf("Failed to generate OTP: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not generate OTP"))
+		return false
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Failed to hash OTP: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not generate OTP"))
+		return false
+	}
+
+	otp.CodeHash = string(hash)
+	otp.Attempts = 0
+	otp.ExpiresAt = now.Add(models.OTPTTL)
+	otp.CreatedAt = now
+	if err := st.SaveLoginOTP(ctx, &otp); err != nil {
+		log.Printf("Failed to save OTP: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not generate OTP"))
+		return false
+	}
+
+	// 3. ส่ง SMS
+	message := i18n.Message(c, smsKey, "code", code, "minutes", int(models.OTPTTL.Minutes()))
+	if err := sender.Send(ctx, otp.Phone, message); err != nil {
+		log.Printf("Failed to send OTP SMS: %v", err)
+		// ลบรหัสที่ส่งไม่สำเร็จ เพื่อให้ผู้ใช้ขอใหม่ได้ทันที
+		if err := st.DeleteLoginOTP(ctx, otp.ID); err != nil {
+			log.Printf("Failed to delete unsent OTP: %v", err)
+		}
+		apperror.Abort(c, apperror.Upstream(apperror.CodeSMSFailed, "Could not send OTP"))
+		return false
+	}
+	return true
+}
+
+// checkOTP ตรวจรหัสที่มี ID ตรงกับ id ภายใน Transaction เพื่อให้นับจำนวนครั้งได้ถูกต้อง
+// ถ้าไม่ผ่านจะตอบ error ให้แล้วและคืน false
+func checkOTP(c *gin.Context, ctx context.Context, st store.Store, id, code string) bool {
+	// ผลการตรวจเก็บไว้ใน verifyErr ส่วน store จะลบรหัสหรือเพิ่ม attempts ตามผลที่คืนไป
+	var verifyErr error
+	err := st.ConsumeLoginOTP(ctx, id, func(otp *models.LoginOTP) store.OTPOutcome {
+		verifyErr = nil
+
+		if time.Now().After(otp.ExpiresAt) {
+			verifyErr = errOTPExpired
+			return store.OTPDiscarded
+		}
+
+		if otp.Attempts >= models.OTPMaxAttempts {
+			verifyErr = errOTPTooManyAttempts
+			return store.OTPDiscarded
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(otp.CodeHash), []byte(code)) != nil {
+			verifyErr = errOTPInvalid
+			return store.OTPRejected
+		}
+
+		// รหัสถูกต้อง store จะลบทิ้งเพื่อไม่ให้ใช้ซ้ำได้
+		return store.OTPAccepted
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		err, verifyErr = nil, errOTPInvalid
+	}
+
+	if err != nil {
+		log.Printf("VerifyOTP transaction failed: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return false
+	}
+	switch verifyErr {
+	case nil:
+		return true
+	case errOTPExpired:
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeOTPExpired, "OTP has expired"))
+	case errOTPTooManyAttempts:
+		apperror.Abort(c, apperror.TooManyRequests(apperror.CodeOTPTooManyAttempts, "Too many attempts, please request a new OTP"))
+	default:
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidOTP, "Invalid OTP"))
+	}
+	return false
+}
+
+// --- Helpers ---
+
+// generateOTPCode สุ่มรหัสตัวเลขความยาว models.OTPLength หลัก
+func generateOTPCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < models.OTPLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", models.OTPLength, n), nil
+}
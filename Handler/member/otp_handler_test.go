@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"meerank/apperror"
+	"meerank/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shiftLoginOTP เลื่อนเวลาของรหัสล็อกอินของ phone ย้อนหลังไป d เหมือนเวลาผ่านไปแล้ว
+func (tt *otpTest) shiftLoginOTP(t *testing.T, phone string, d time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+	otp, err := tt.st.GetLoginOTP(ctx, models.LoginOTPID(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp.CreatedAt = otp.CreatedAt.Add(-d)
+	otp.ExpiresAt = otp.ExpiresAt.Add(-d)
+	if err := tt.st.SaveLoginOTP(ctx, otp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// requestLoginCode ขอรหัสล็อกอินของ phone แล้วคืนรหัสที่ส่งไปทาง SMS
+func (tt *otpTest) requestLoginCode(t *testing.T, phone string) string {
+	t.Helper()
+	if status, code := tt.post(t, "/login/otp/request", "", gin.H{"phone": phone}); status != http.StatusOK {
+		t.Fatalf("login OTP request = %d %s, want 200", status, code)
+	}
+	return tt.lastCode(t, phone)
+}
+
+// wrongCode คืนรหัสที่ไม่ตรงกับ code
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+// otpStep คือการส่งรหัส code หนึ่งครั้งกับคำตอบที่คาดไว้ (errCode ว่าง = ไม่ตรวจ code ของคำตอบ)
+type otpStep struct {
+	code    string
+	status  int
+	errCode string
+}
+
+// verifySteps ส่งรหัสล็อกอินของ phone ตามลำดับใน steps
+func (tt *otpTest) verifySteps(t *testing.T, phone string, steps []otpStep) {
+	t.Helper()
+	for i, step := range steps {
+		status, code := tt.post(t, "/login/otp/verify", "", gin.H{"phone": phone, "code": step.code})
+		if status != step.status || (step.errCode != "" && code != step.errCode) {
+			t.Errorf("verify #%d = %d %s, want %d %s", i+1, status, code, step.status, step.errCode)
+		}
+	}
+}
+
+func TestLoginOTPIsSingleUse(t *testing.T) {
+	tt := newOTPTest(t)
+	phone := *tt.createUser(t, "+66811111111").Phone
+	code := tt.requestLoginCode(t, phone)
+
+	// รูปแบบเบอร์ต่างกันแต่เป็นเบอร์เดียวกัน
+	tt.verifySteps(t, "081-111-1111", []otpStep{
+		{code, http.StatusOK, ""},
+		{code, http.StatusUnauthorized, apperror.CodeInvalidOTP},
+	})
+}
+
+func TestLoginOTPUnknownPhone(t *testing.T) {
+	tt := newOTPTest(t)
+
+	// ตอบเหมือนเบอร์ที่ลงทะเบียนไว้ แต่ไม่ส่ง SMS
+	if status, code := tt.post(t, "/login/otp/request", "", gin.H{"phone": "+66899999999"}); status != http.StatusOK {
+		t.Fatalf("login OTP request = %d %s, want 200", status, code)
+	}
+	if got := len(tt.sender.Messages()); got != 0 {
+		t.Errorf("sent %d SMS to an unknown phone", got)
+	}
+	tt.verifySteps(t, "+66899999999", []otpStep{
+		{"123456", http.StatusUnauthorized, apperror.CodeInvalidOTP},
+	})
+}
+
+func TestLoginOTPExpires(t *testing.T) {
+	tt := newOTPTest(t)
+	phone := *tt.createUser(t, "+66811111111").Phone
+	code := tt.requestLoginCode(t, phone)
+	tt.shiftLoginOTP(t, phone, models.OTPTTL+time.Second)
+
+	// รหัสที่หมดอายุถูกทิ้ง ครั้งถัดไปจึงไม่พบรหัสแล้ว
+	tt.verifySteps(t, phone, []otpStep{
+		{code, http.StatusUnauthorized, apperror.CodeOTPExpired},
+		{code, http.StatusUnauthorized, apperror.CodeInvalidOTP},
+	})
+}
+
+func TestLoginOTPAttemptLimit(t *testing.T) {
+	tt := newOTPTest(t)
+	phone := *tt.createUser(t, "+66811111111").Phone
+	code := tt.requestLoginCode(t, phone)
+
+	var steps []otpStep
+	for range models.OTPMaxAttempts {
+		steps = append(steps, otpStep{wrongCode(code), http.StatusUnauthorized, apperror.CodeInvalidOTP})
+	}
+	// ครบจำนวนครั้งแล้วรหัสที่ถูกก็ใช้ไม่ได้ และรหัสถูกทิ้งไป
+	steps = append(steps,
+		otpStep{code, http.StatusTooManyRequests, apperror.CodeOTPTooManyAttempts},
+		otpStep{code, http.StatusUnauthorized, apperror.CodeInvalidOTP},
+	)
+	tt.verifySteps(t, phone, steps)
+}
+
+func TestLoginOTPResendCooldown(t *testing.T) {
+	tt := newOTPTest(t)
+	phone := *tt.createUser(t, "+66811111111").Phone
+	first := tt.requestLoginCode(t, phone)
+
+	status, code := tt.post(t, "/login/otp/request", "", gin.H{"phone": phone})
+	if status != http.StatusTooManyRequests || code != apperror.CodeOTPCooldown {
+		t.Fatalf("second request = %d %s, want 429 %s", status, code, apperror.CodeOTPCooldown)
+	}
+	if got := len(tt.sender.Messages()); got != 1 {
+		t.Fatalf("sent %d SMS, want 1", got)
+	}
+
+	// พ้น cooldown แล้วขอใหม่ได้ และรหัสใหม่แทนที่รหัสเดิม
+	tt.shiftLoginOTP(t, phone, models.OTPResendCooldown)
+	second := tt.requestLoginCode(t, phone)
+	if second != first {
+		tt.verifySteps(t, phone, []otpStep{{first, http.StatusUnauthorized, apperror.CodeInvalidOTP}})
+	}
+	tt.verifySteps(t, phone, []otpStep{{second, http.StatusOK, ""}})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/phones"
+	"meerank/sms"
+	"meerank/store"
+	"meerank/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- Phone number change ---
+
+// RequestPhoneChangeHandler ส่ง OTP ไปยังเบอร์ใหม่ที่ผู้ใช้ที่ล็อกอินอยู่ต้องการเปลี่ยนไปใช้
+// เบอร์จะเปลี่ยนจริงเมื่อยืนยันรหัสที่ VerifyPhoneChangeHandler
+func RequestPhoneChangeHandler(c *gin.Context, st store.Store, sender sms.Sender) {
+	uid := c.GetString("uid")
+
+	// 1. รับเบอร์ใหม่แล้วแปลงเป็น E.164
+	var payload struct {
+		Phone string `json:"phone" binding:"required"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+	phone, err := phones.Normalize(payload.Phone)
+	if err != nil {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeInvalidPhone, "Invalid phone number"))
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. เบอร์ใหม่ต้องไม่ใช่เบอร์เดิม และต้องไม่มีผู้ใช้คนอื่นใช้อยู่ (ปฏิเสธก่อนส่ง SMS ใดๆ)
+	// ตอนยืนยัน store ยังตรวจการจองเบอร์ซ้ำอีกครั้ง เผื่อมีคนได้เบอร์นี้ไประหว่างนั้น
+	user, err := st.GetUser(ctx, uid)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		log.Printf("Error querying user: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+	if user.Phone != nil && *user.Phone == phone {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodePhoneUnchanged, "This is already your phone number"))
+		return
+	}
+	owner, err := st.FindUserByPhone(ctx, phone)
+	if err == nil && owner.ID != uid {
+		apperror.Abort(c, apperror.Conflict(apperror.CodePhoneTaken, "Phone number already registered"))
+		return
+	}
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Error querying user: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+
+	// 3. ส่งรหัสที่ผูกกับผู้ใช้คนนี้ไปยังเบอร์ใหม่ (แยกจากรหัสล็อกอินของเบอร์นั้น)
+	otp := models.LoginOTP{ID: models.PhoneChangeOTPID(uid, phone), Phone: phone, UserID: uid}
+	if !sendOTP(c, ctx, st, sender, otp, i18n.MsgOTPPhoneChangeSMS) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": i18n.MsgPhoneChangeOTPSent, "message": i18n.Message(c, i18n.MsgPhoneChangeOTPSent), "phone": phone})
+}
+
+// VerifyPhoneChangeHandler ยืนยันรหัสที่ส่งไปยังเบอร์ใหม่แล้วเปลี่ยนเบอร์ของผู้ใช้
+// store ย้ายการจองเบอร์ใน Transaction เดียวกัน ถ้ามีคนใช้เบอร์นี้ไปก่อนจะตอบ 409
+func VerifyPhoneChangeHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+
+	// 1. รับเบอร์ใหม่และรหัส OTP
+	var payload struct {
+		Phone string `json:"phone" binding:"required"`
+		Code  string `json:"code" binding:"required"`
+	}
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+	phone, err := phones.Normalize(payload.Phone)
+	if err != nil {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodeInvalidPhone, "Invalid phone number"))
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. ตรวจรหัส (ต้องเป็นรหัสที่ออกให้ผู้ใช้คนนี้)
+	if !checkOTP(c, ctx, st, models.PhoneChangeOTPID(uid, phone), payload.Code) {
+		return
+	}
+
+	// 3. อ่านเบอร์เดิมไว้สำหรับ audit log แล้วเปลี่ยนเบอร์
+	before, err := st.GetUser(ctx, uid)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+	if err := st.UpdateUser(ctx, uid, store.UserUpdate{Phone: &phone}); err != nil {
+		if errors.Is(err, store.ErrPhoneTaken) {
+			apperror.Abort(c, apperror.Conflict(apperror.CodePhoneTaken, "Phone number already registered"))
+			return
+		}
+		log.Printf("Failed to change phone number of user %s: %v", uid, err)
+		apperror.Abort(c, apperror.Internal("Failed to update phone number"))
+		return
+	}
+
+	after := *before
+	after.Phone = &phone
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionPhoneChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Changes:    audit.Changes(before, after),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"code": i18n.MsgPhoneUpdated, "message": i18n.Message(c, i18n.MsgPhoneUpdated), "phone": phone})
+}
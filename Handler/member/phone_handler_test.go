@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	handlers "meerank/Handler/member"
+	"meerank/apperror"
+	"meerank/auth"
+	"meerank/config"
+	"meerank/middleware"
+	"meerank/models"
+	"meerank/periods"
+	"meerank/sms"
+	"meerank/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var otpCodePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// otpTest คือ router ของ endpoint ที่ส่งและตรวจ OTP โดย SMS ถูกเก็บไว้ใน sms.MemorySender
+// endpoint ที่ต้องล็อกอินอ่าน uid จาก header X-Test-UID แทน AuthMiddleware
+type otpTest struct {
+	st     *store.MemoryStore
+	sender *sms.MemorySender
+	router *gin.Engine
+}
+
+func newOTPTest(t *testing.T) *otpTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cal, err := periods.NewCalendar("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeyRing(config.JWT{Secret: "test-secret-test-secret-test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := config.Default().Auth.Tokens
+
+	tt := &otpTest{st: store.NewMemoryStore(cal), sender: sms.NewMemorySender(), router: gin.New()}
+	tt.router.Use(middleware.ErrorMiddleware())
+	tt.router.POST("/login/otp/request", func(c *gin.Context) { handlers.RequestLoginOTPHandler(c, tt.st, tt.sender) })
+	tt.router.POST("/login/otp/verify", func(c *gin.Context) { handlers.VerifyLoginOTPHandler(c, tt.st, keys, tokens) })
+
+	profile := tt.router.Group("/profile")
+	profile.Use(func(c *gin.Context) { c.Set("uid", c.GetHeader("X-Test-UID")) })
+	{
+		profile.POST("/phone", func(c *gin.Context) { handlers.RequestPhoneChangeHandler(c, tt.st, tt.sender) })
+		profile.POST("/phone/verify", func(c *gin.Context) { handlers.VerifyPhoneChangeHandler(c, tt.st) })
+	}
+	return tt
+}
+
+// post ส่ง body ไปที่ path ในนามของ uid (ว่าง = ไม่ได้ล็อกอิน) แล้วคืน status และ code ของคำตอบ
+func (tt *otpTest) post(t *testing.T, path, uid string, body gin.H) (int, string) {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if uid != "" {
+		req.Header.Set("X-Test-UID", uid)
+	}
+	rec := httptest.NewRecorder()
+	tt.router.ServeHTTP(rec, req)
+
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp.Code
+}
+
+func (tt *otpTest) createUser(t *testing.T, phone string) *models.User {
+	t.Helper()
+	user := &models.User{Name: "Somchai", Role: models.RoleMember, Phone: &phone}
+	if _, err := tt.st.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// lastCode อ่านรหัส OTP จาก SMS ล่าสุดที่ส่งถึง phone
+func (tt *otpTest) lastCode(t *testing.T, phone string) string {
+	t.Helper()
+	msg, ok := tt.sender.Last(phone)
+	if !ok {
+		t.Fatalf("no SMS sent to %s", phone)
+	}
+	code := otpCodePattern.FindString(msg.Message)
+	if code == "" {
+		t.Fatalf("no code in SMS %q", msg.Message)
+	}
+	return code
+}
+
+func TestPhoneChangeToTakenPhoneKeepsLoginOTP(t *testing.T) {
+	tt := newOTPTest(t)
+	alice := tt.createUser(t, "+66811111111")
+	bob := tt.createUser(t, "+66822222222")
+
+	if status, code := tt.post(t, "/login/otp/request", "", gin.H{"phone": *alice.Phone}); status != http.StatusOK {
+		t.Fatalf("login OTP request = %d %s, want 200", status, code)
+	}
+	loginCode := tt.lastCode(t, *alice.Phone)
+	sent := len(tt.sender.Messages())
+
+	// bob ขอเปลี่ยนมาใช้เบอร์ของ alice ต้องถูกปฏิเสธก่อนส่ง SMS
+	status, code := tt.post(t, "/profile/phone", bob.ID, gin.H{"phone": "081-111-1111"})
+	if status != http.StatusConflict || code != apperror.CodePhoneTaken {
+		t.Fatalf("phone change request = %d %s, want 409 %s", status, code, apperror.CodePhoneTaken)
+	}
+	if got := len(tt.sender.Messages()); got != sent {
+		t.Errorf("sent %d SMS for a taken phone, want none", got-sent)
+	}
+
+	// รหัสล็อกอินของ alice ยังใช้ได้
+	if status, code := tt.post(t, "/login/otp/verify", "", gin.H{"phone": *alice.Phone, "code": loginCode}); status != http.StatusOK {
+		t.Fatalf("login OTP verify = %d %s, want 200", status, code)
+	}
+}
+
+func TestPhoneChangeOTPIsSeparateFromLoginOTP(t *testing.T) {
+	tt := newOTPTest(t)
+	alice := tt.createUser(t, "+66811111111")
+	fresh := "+66833333333"
+
+	if status, code := tt.post(t, "/profile/phone", alice.ID, gin.H{"phone": fresh}); status != http.StatusOK {
+		t.Fatalf("phone change request = %d %s, want 200", status, code)
+	}
+	changeCode := tt.lastCode(t, fresh)
+
+	// รหัสเปลี่ยนเบอร์ใช้ล็อกอินไม่ได้ และไม่ทำให้รหัสเปลี่ยนเบอร์ถูกใช้ไป
+	if status, _ := tt.post(t, "/login/otp/verify", "", gin.H{"phone": fresh, "code": changeCode}); status != http.StatusUnauthorized {
+		t.Fatalf("login with phone change code = %d, want 401", status)
+	}
+	if status, code := tt.post(t, "/profile/phone/verify", alice.ID, gin.H{"phone": fresh, "code": changeCode}); status != http.StatusOK {
+		t.Fatalf("phone change verify = %d %s, want 200", status, code)
+	}
+	user, err := tt.st.GetUser(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Phone == nil || *user.Phone != fresh {
+		t.Errorf("phone = %v, want %s", user.Phone, fresh)
+	}
+}
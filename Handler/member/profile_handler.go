@@ -4,266 +4,221 @@ import (
 	"context"
 	"errors"
 	"log"
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/config"
+	"meerank/i18n"
 	"meerank/models"
+	"meerank/store"
+	"meerank/validation"
 	"net/http"
+	"strings"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
+var errNotEnoughScore = apperror.InsufficientScore("Not enough score")
+
 // GetMyProfileHandler ดึงข้อมูลโปรไฟล์ของ user ที่ล็อกอินอยู่
-func GetMyProfileHandler(c *gin.Context, client *firestore.Client) {
+func GetMyProfileHandler(c *gin.Context, st store.Store) {
 	// 1. ดึง uid (string) ที่ได้จาก Middleware
 	uidValue, exists := c.Get("uid")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
 		return
 	}
 
 	uid, ok := uidValue.(string)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid User ID format in token"})
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid User ID format in token"))
 		return
 	}
 
 	ctx := context.Background()
 
-	// 2. ดึง document จาก Firestore
-	doc, err := client.Collection(models.CollectionUsers).Doc(uid).Get(ctx)
+	// 2. ดึงข้อมูลผู้ใช้จาก store (ID ถูกใส่มาให้แล้ว)
+	user, err := st.GetUser(ctx, uid)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		apperror.Abort(c, apperror.Internal("Database error"))
 		return
 	}
 
-	// 3. แปลงข้อมูลและส่งกลับ
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process user data"})
-		return
-	}
-	user.ID = doc.Ref.ID // เพิ่ม ID เข้าไปใน struct ก่อนส่งกลับ
-
+	// 3. ส่งข้อมูลกลับ
 	c.JSON(http.StatusOK, user)
 }
 
 // UpdateMyProfileHandler อัปเดตข้อมูลโปรไฟล์ของ user ที่ล็อกอินอยู่
-func UpdateMyProfileHandler(c *gin.Context, client *firestore.Client) {
+func UpdateMyProfileHandler(c *gin.Context, st store.Store) {
 	// 1. ดึง uid (string) จาก Middleware
 	uidValue, exists := c.Get("uid")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
 		return
 	}
 	uid, ok := uidValue.(string)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid User ID format in token"})
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid User ID format in token"))
 		return
 	}
 
+	// ข้อมูลที่ไม่ผ่านกฎใน tag binding ตอบ 422 พร้อมรายการ field ที่ผิด
 	var payload struct {
-		Name   *string `json:"name"`
+		Name   *string `json:"name" binding:"omitempty,notblank,max=100"`
 		Phone  *string `json:"phone"`
-		Age    *int    `json:"age"`
-		Gender *string `json:"gender"`
+		Age    *int    `json:"age" binding:"omitempty,min=1,max=120"`
+		Gender *string `json:"gender" binding:"omitempty,gender"`
+		// ความเป็นส่วนตัว: public, friends หรือ private และชื่อแทนบน leaderboard ("" = ใช้ชื่อจริง)
+		ProfileVisibility *string `json:"profile_visibility" binding:"omitempty,oneof=public friends private"`
+		LeaderboardAlias  *string `json:"leaderboard_alias" binding:"omitempty,max=32"`
+		// ภาษาของข้อความที่ส่งถึงผู้ใช้ ("" = ใช้ Accept-Language)
+		Locale *string `json:"locale" binding:"omitempty,locale"`
 	}
-
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+	if !validation.BindJSON(c, &payload) {
 		return
 	}
+	// เบอร์โทรเปลี่ยนได้หลังยืนยันเบอร์ใหม่ด้วย OTP เท่านั้น (ดู RequestPhoneChangeHandler)
+	if payload.Phone != nil {
+		apperror.Abort(c, apperror.BadRequest(apperror.CodePhoneChangeNeedsVerify, "Phone number changes must be verified, use POST /profile/phone"))
+		return
+	}
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		payload.Name = &name
+	}
+	if payload.LeaderboardAlias != nil {
+		alias := strings.TrimSpace(*payload.LeaderboardAlias)
+		// ชื่อที่ระบบใช้แทนผู้ใช้ที่ซ่อนหรือถูกลบ ห้ามใช้เป็นชื่อแทน เพื่อไม่ให้สับสน
+		if strings.EqualFold(alias, models.AnonymousName) || strings.EqualFold(alias, models.DeletedUserName) {
+			validation.Respond(c, validation.Errors{validation.Field("leaderboard_alias", validation.CodeReserved, "")})
+			return
+		}
+		payload.LeaderboardAlias = &alias
+	}
 
 	ctx := context.Background()
 
 	// 2. สร้างรายการอัปเดตเฉพาะ field ที่ส่งมา
-	var updates []firestore.Update
-	if payload.Name != nil {
-		updates = append(updates, firestore.Update{Path: "name", Value: *payload.Name})
-	}
-	if payload.Phone != nil {
-		updates = append(updates, firestore.Update{Path: "phone", Value: *payload.Phone})
-	}
-	if payload.Age != nil {
-		updates = append(updates, firestore.Update{Path: "age", Value: *payload.Age})
-	}
-	if payload.Gender != nil {
-		updates = append(updates, firestore.Update{Path: "gender", Value: *payload.Gender})
+	update := store.UserUpdate{
+		Name:   payload.Name,
+		Age:    payload.Age,
+		Gender: payload.Gender,
+
+		ProfileVisibility: payload.ProfileVisibility,
+		LeaderboardAlias:  payload.LeaderboardAlias,
+		Locale:            payload.Locale,
 	}
 
 	// 3. ถ้าไม่มีข้อมูลให้อัปเดต ก็ไม่ต้องทำอะไร
-	if len(updates) == 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "No fields to update"})
+	if update == (store.UserUpdate{}) {
+		c.JSON(http.StatusOK, gin.H{"code": i18n.MsgNoFieldsToUpdate, "message": i18n.Message(c, i18n.MsgNoFieldsToUpdate)})
 		return
 	}
 
-	// 4. บันทึกการเปลี่ยนแปลงลง Firestore
-	_, err := client.Collection(models.CollectionUsers).Doc(uid).Update(ctx, updates)
+	// 4. อ่านค่าเดิมไว้เทียบสำหรับ audit log แล้วบันทึกการเปลี่ยนแปลง
+	before, err := st.GetUser(ctx, uid)
 	if err != nil {
-		log.Printf("Failed to update profile: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
-}
-
-// UpdateUserActivityHandler อัปเดตคะแนนและนาทีจากการออกกำลังกาย
-func UpdateUserActivityHandler(c *gin.Context, client *firestore.Client) {
-	// 1. ดึง uid (string) จาก Middleware
-	uidValue, exists := c.Get("uid")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		apperror.Abort(c, apperror.Internal("Database error"))
 		return
 	}
-	uid, ok := uidValue.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid User ID format in token"})
+	if err := st.UpdateUser(ctx, uid, update); err != nil {
+		log.Printf("Failed to update profile: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to update profile"))
 		return
 	}
 
-	var payload struct {
-		Minute int `json:"minute"`
-		Score  int `json:"score"`
-	}
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data, 'minute' and 'score' are required"})
-		return
+	after := *before
+	if update.Name != nil {
+		after.Name = *update.Name
 	}
-
-	ctx := context.Background()
-
-	// 2. ใช้ firestore.Increment เพื่อบวกค่าแบบ Atomic
-	updates := []firestore.Update{
-		{Path: "minute", Value: firestore.Increment(payload.Minute)},
-		{Path: "score", Value: firestore.Increment(payload.Score)},
+	if update.Age != nil {
+		after.Age = update.Age
 	}
-
-	// 3. อัปเดตข้อมูลใน Firestore
-	_, err := client.Collection(models.CollectionUsers).Doc(uid).Update(ctx, updates)
-	if err != nil {
-		log.Printf("Failed to update user activity: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user activity"})
-		return
+	if update.Gender != nil {
+		after.Gender = update.Gender
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Score and minute updated successfully"})
-}
-
-// AddTreeHandler เพิ่มจำนวนต้นไม้สะสมของผู้ใช้
-func AddTreeHandler(c *gin.Context, client *firestore.Client) {
-	// 1. ดึง uid (string) จาก Middleware
-	uidValue, exists := c.Get("uid")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-		return
+	if update.ProfileVisibility != nil {
+		after.ProfileVisibility = *update.ProfileVisibility
 	}
-	uid, ok := uidValue.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid User ID format in token"})
-		return
+	if update.LeaderboardAlias != nil {
+		after.LeaderboardAlias = *update.LeaderboardAlias
 	}
-
-	ctx := context.Background()
-
-	// 2. ใช้ firestore.Increment เพื่อบวกค่า number_tree เพิ่มไป 1
-	update := firestore.Update{Path: "number_tree", Value: firestore.Increment(1)}
-
-	_, err := client.Collection(models.CollectionUsers).Doc(uid).Update(ctx, []firestore.Update{update})
-	if err != nil {
-		log.Printf("Failed to update tree count: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tree count"})
-		return
+	if update.Locale != nil {
+		after.Locale = *update.Locale
+		c.Set("locale", after.Locale) // <-- ตอบด้วยภาษาที่เพิ่งเลือก
 	}
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionProfileUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   uid,
+		Changes:    audit.Changes(before, after),
+	})
 
-	c.JSON(http.StatusOK, gin.H{"message": "Tree count updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"code": i18n.MsgProfileUpdated, "message": i18n.Message(c, i18n.MsgProfileUpdated)})
 }
 
 // WaterTreeHandler จัดการการรดน้ำต้นไม้ (ใช้ Transaction)
-func WaterTreeHandler(c *gin.Context, client *firestore.Client) {
+// คะแนนที่รดสะสมครบ trees.PointsPerTree จะกลายเป็นต้นไม้ 1 ต้น
+func WaterTreeHandler(c *gin.Context, st store.Store, trees config.Trees) {
 	// 1. ดึง uid (string) จาก Middleware
 	uidValue, exists := c.Get("uid")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
 		return
 	}
 	uid, ok := uidValue.(string)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid User ID format in token"})
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid User ID format in token"))
 		return
 	}
 
 	var payload struct {
-		Amount int `json:"amount"`
+		Amount int `json:"amount" binding:"min=1"`
 	}
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
-		return
-	}
-
-	if payload.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be positive"})
+	if !validation.BindJSON(c, &payload) {
 		return
 	}
 
 	ctx := context.Background()
-	userRef := client.Collection(models.CollectionUsers).Doc(uid)
-
-	var finalUser models.User
 
 	// 2. ใช้ Transaction เพื่อความปลอดภัยของข้อมูล
-	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		doc, err := tx.Get(userRef)
-		if err != nil {
-			return err
-		}
-
-		var user models.User
-		if err := doc.DataTo(&user); err != nil {
-			return err
-		}
-
+	finalUser, err := st.UpdateUserStats(ctx, uid, func(user *models.User) error {
 		// ตรรกะทางธุรกิจ
 		if user.Score < payload.Amount {
-			return errors.New("not enough score")
+			return errNotEnoughScore
 		}
 
 		user.Score -= payload.Amount
 		user.TreeProgress += payload.Amount
 
-		if user.TreeProgress >= 1000 {
+		if user.TreeProgress >= trees.PointsPerTree {
 			user.NumberTree += 1
-			user.TreeProgress -= 1000
+			user.TreeProgress -= trees.PointsPerTree
 		}
-
-		// เก็บข้อมูลล่าสุดเพื่อส่งกลับ
-		finalUser = user
-
-		// ทำการอัปเดตใน transaction
-		return tx.Update(userRef, []firestore.Update{
-			{Path: "score", Value: user.Score},
-			{Path: "tree_progress", Value: user.TreeProgress},
-			{Path: "number_tree", Value: user.NumberTree},
-		})
+		return nil
 	})
 
 	// 3. จัดการผลลัพธ์ของ Transaction
 	if err != nil {
-		if err.Error() == "not enough score" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Not enough score"})
+		if errors.Is(err, errNotEnoughScore) {
+			apperror.Abort(c, err)
 			return
 		}
 		log.Printf("WaterTree transaction failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user data"})
+		apperror.Abort(c, apperror.Internal("Failed to update user data"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "Tree watered successfully",
+		"code":          i18n.MsgTreeWatered,
+		"message":       i18n.Message(c, i18n.MsgTreeWatered),
 		"new_score":     finalUser.Score,
 		"tree_progress": finalUser.TreeProgress,
 		"number_tree":   finalUser.NumberTree,
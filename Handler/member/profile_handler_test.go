@@ -0,0 +1,182 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handlers "meerank/Handler/member"
+	"meerank/config"
+	"meerank/middleware"
+	"meerank/models"
+	"meerank/periods"
+	"meerank/scoring"
+	"meerank/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type profileTest struct {
+	st     *store.MemoryStore
+	user   *models.User
+	router *gin.Engine
+}
+
+// newProfileTest สร้าง router ของกลุ่ม /profile โดยข้าม AuthMiddleware
+// แล้วใส่ uid ของผู้ใช้ที่ทดสอบให้ทุก request
+func newProfileTest(t *testing.T) *profileTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cal, err := periods.NewCalendar("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemoryStore(cal)
+	scorer, err := scoring.NewEngine(scoring.DefaultRules(), cal.Location())
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone := "+66811112222"
+	user := &models.User{Name: "Somchai", Role: models.RoleMember, Phone: &phone}
+	if _, err := st.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := &profileTest{st: st, user: user, router: gin.New()}
+	tt.router.Use(middleware.ErrorMiddleware())
+	profile := tt.router.Group("/profile")
+	profile.Use(func(c *gin.Context) { c.Set("uid", user.ID) })
+	{
+		profile.GET("/me", func(c *gin.Context) { handlers.GetMyProfileHandler(c, st) })
+		profile.PUT("/me", func(c *gin.Context) { handlers.UpdateMyProfileHandler(c, st) })
+		profile.POST("/activity", func(c *gin.Context) { handlers.UpdateUserActivityHandler(c, st, scorer) })
+		profile.POST("/tree/water", func(c *gin.Context) { handlers.WaterTreeHandler(c, st, config.Default().Trees) })
+	}
+	return tt
+}
+
+func (tt *profileTest) do(t *testing.T, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	tt.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (tt *profileTest) reload(t *testing.T) *models.User {
+	t.Helper()
+	user, err := tt.st.GetUser(context.Background(), tt.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func (tt *profileTest) setScore(t *testing.T, score int) {
+	t.Helper()
+	if _, err := tt.st.UpdateUserStats(context.Background(), tt.user.ID, func(u *models.User) error {
+		u.Score = score
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProfileMe(t *testing.T) {
+	tt := newProfileTest(t)
+
+	rec := tt.do(t, http.MethodPut, "/profile/me", gin.H{"name": "Somsak", "age": 30})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d (%s), want 200", rec.Code, rec.Body)
+	}
+
+	rec = tt.do(t, http.MethodGet, "/profile/me", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d (%s), want 200", rec.Code, rec.Body)
+	}
+	var got models.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != tt.user.ID || got.Name != "Somsak" || got.Age == nil || *got.Age != 30 {
+		t.Errorf("profile = %+v, want %s named Somsak aged 30", got, tt.user.ID)
+	}
+	// field ที่ไม่ได้ส่งมาต้องคงเดิม
+	if got.Phone == nil || *got.Phone != "+66811112222" {
+		t.Errorf("phone = %v, want it unchanged", got.Phone)
+	}
+}
+
+func TestUpdateUserActivity(t *testing.T) {
+	tt := newProfileTest(t)
+	start := time.Now().Add(-2 * time.Hour).Truncate(time.Minute)
+
+	// คะแนนคิดจากระยะเวลา score ที่ client อ้างมาไม่ถูกนำมาบวก
+	for i := 0; i < 2; i++ {
+		from := start.Add(time.Duration(i) * time.Hour)
+		rec := tt.do(t, http.MethodPost, "/profile/activity", gin.H{
+			"type":       "exercise",
+			"started_at": from,
+			"ended_at":   from.Add(30 * time.Minute),
+			"score":      300,
+		})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body)
+		}
+	}
+	if got := tt.reload(t); got.Minute != 60 || got.Score != 600 {
+		t.Errorf("minute/score = %d/%d, want 60/600", got.Minute, got.Score)
+	}
+
+	// ช่วงเวลาทับกิจกรรมเดิมต้องไม่ได้คะแนน
+	rec := tt.do(t, http.MethodPost, "/profile/activity", gin.H{
+		"type":       "exercise",
+		"started_at": start.Add(15 * time.Minute),
+		"ended_at":   start.Add(45 * time.Minute),
+	})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("overlap status = %d (%s), want 409", rec.Code, rec.Body)
+	}
+	if got := tt.reload(t); got.Minute != 60 || got.Score != 600 {
+		t.Errorf("minute/score = %d/%d after overlap, want 60/600", got.Minute, got.Score)
+	}
+}
+
+func TestWaterTree(t *testing.T) {
+	tt := newProfileTest(t)
+	tt.setScore(t, 1200)
+
+	rec := tt.do(t, http.MethodPost, "/profile/tree/water", gin.H{"amount": 1100})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body)
+	}
+	got := tt.reload(t)
+	if got.Score != 100 || got.NumberTree != 1 || got.TreeProgress != 100 {
+		t.Errorf("score/trees/progress = %d/%d/%d, want 100/1/100", got.Score, got.NumberTree, got.TreeProgress)
+	}
+
+	// คะแนนไม่พอต้องไม่หักอะไรเลย
+	rec = tt.do(t, http.MethodPost, "/profile/tree/water", gin.H{"amount": 500})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d (%s), want 403", rec.Code, rec.Body)
+	}
+	if again := tt.reload(t); again.Score != 100 || again.TreeProgress != 100 {
+		t.Errorf("score/progress = %d/%d after rejected watering, want 100/100", again.Score, again.TreeProgress)
+	}
+
+	if rec := tt.do(t, http.MethodPost, "/profile/tree/water", gin.H{"amount": 0}); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("zero amount status = %d, want 422", rec.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"meerank/apperror"
+	"meerank/config"
+	"meerank/models"
+	"meerank/store"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSeasonsHandler ดึงรายการฤดูกาลทั้งหมด เรียงจากล่าสุด
+func GetSeasonsHandler(c *gin.Context, st store.Store) {
+	seasons, err := st.ListSeasons(context.Background())
+	if err != nil {
+		log.Printf("Failed to list seasons: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not retrieve seasons"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"seasons": seasons})
+}
+
+// GetSeasonLeaderboardHandler ดึงอันดับของฤดูกาลทีละหน้า
+// ฤดูกาลที่ปิดแล้วคืนอันดับสุดท้ายที่บันทึกไว้ ฤดูกาลที่ยังไม่ปิดคืนอันดับปัจจุบัน
+// query: limit (ค่าเริ่มต้นและค่าสูงสุดตาม config.Leaderboard), cursor (ค่า next_cursor จากหน้าก่อนหน้า)
+func GetSeasonLeaderboardHandler(c *gin.Context, st store.Store, board config.Leaderboard) {
+	// 1. อ่านเงื่อนไขการแบ่งหน้า
+	limit, ok := intQueryParam(c, "limit", board.DefaultPageSize, board.MaxPageSize)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. หาฤดูกาล
+	season, ok := getSeason(ctx, c, st)
+	if !ok {
+		return
+	}
+
+	// 3. ดึงอันดับหนึ่งหน้า
+	page, err := st.SeasonLeaderboard(ctx, season, store.LeaderboardQuery{Cursor: c.Query("cursor"), Limit: limit})
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			apperror.Abort(c, apperror.InvalidParameter("cursor", "Invalid cursor"))
+			return
+		}
+		log.Printf("Failed to fetch season leaderboard: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to fetch leaderboard data"))
+		return
+	}
+
+	// 4. แสดงชื่อตามการตั้งค่าความเป็นส่วนตัวของแต่ละคน
+	names, ok := leaderboardNames(ctx, c, st, page.Rows)
+	if !ok {
+		return
+	}
+
+	response := gin.H{
+		"season":  season,
+		"final":   season.Status == models.SeasonStatusClosed,
+		"entries": toLeaderboardEntries(page.Rows, names),
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetMySeasonLeaderboardHandler คืนอันดับของผู้ใช้ที่ล็อกอินอยู่ในฤดูกาล พร้อม n คนที่อยู่ด้านบนและด้านล่าง
+// query: n (ค่าเริ่มต้น 5)
+func GetMySeasonLeaderboardHandler(c *gin.Context, st store.Store) {
+	// 1. ดึง uid (string) จาก Middleware
+	uidValue, exists := c.Get("uid")
+	if !exists {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
+		return
+	}
+	uid, ok := uidValue.(string)
+	if !ok {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid User ID format in token"))
+		return
+	}
+
+	n, ok := intQueryParam(c, "n", defaultLeaderboardAround, maxLeaderboardAround)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+
+	// 2. หาฤดูกาล
+	season, ok := getSeason(ctx, c, st)
+	if !ok {
+		return
+	}
+
+	// 3. หาอันดับของตัวเองและคนรอบข้าง
+	around, err := st.SeasonLeaderboardAround(ctx, season, uid, n)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeNotInSeason, "You did not take part in this season"))
+			return
+		}
+		log.Printf("Failed to fetch season rank: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to fetch leaderboard data"))
+		return
+	}
+
+	// 4. แสดงชื่อตามการตั้งค่าความเป็นส่วนตัวของแต่ละคน
+	names, ok := leaderboardNames(ctx, c, st, []store.LeaderboardRow{around.Me}, around.Above, around.Below)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"season": season,
+		"final":  season.Status == models.SeasonStatusClosed,
+		"me":     toLeaderboardEntry(around.Me, names),
+		"above":  toLeaderboardEntries(around.Above, names),
+		"below":  toLeaderboardEntries(around.Below, names),
+	})
+}
+
+// getSeason อ่านฤดูกาลจาก path parameter :id
+// ถ้าไม่พบหรือเกิดข้อผิดพลาดจะตอบให้แล้วและคืน ok เป็น false
+func getSeason(ctx context.Context, c *gin.Context, st store.Store) (*models.Season, bool) {
+	season, err := st.GetSeason(ctx, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeSeasonNotFound, "Season not found"))
+			return nil, false
+		}
+		log.Printf("Failed to get season: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return nil, false
+	}
+	return season, true
+}
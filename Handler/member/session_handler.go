@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"meerank/apperror"
+	"meerank/audit"
+	"meerank/auth"
+	"meerank/config"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/store"
+	"meerank/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	errInvalidRefreshToken = errors.New("invalid refresh token")
+	errRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// --- Refresh Token Handler ---
+
+// RefreshTokenHandler ออก Access Token ใหม่จาก Refresh Token และหมุน Refresh Token ทุกครั้ง
+func RefreshTokenHandler(c *gin.Context, st store.Store, keys *auth.KeyRing, tokens config.Tokens) {
+	var payload struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if !validation.BindJSON(c, &payload) {
+		return
+	}
+
+	sessionID, secret, ok := strings.Cut(payload.RefreshToken, ".")
+	if !ok || sessionID == "" || secret == "" {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidRefreshToken, "Invalid refresh token"))
+		return
+	}
+
+	ctx := context.Background()
+	newSecret, err := randomToken()
+	if err != nil {
+		log.Printf("Failed to generate refresh token: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not generate token"))
+		return
+	}
+
+	// 1. ตรวจสอบและหมุน Refresh Token ภายใน Transaction
+	session, err := st.UpdateSession(ctx, sessionID, func(session *models.Session) error {
+		now := time.Now()
+		if !session.Active(now) {
+			return errInvalidRefreshToken
+		}
+		if subtle.ConstantTimeCompare([]byte(session.RefreshTokenHash), []byte(hashToken(secret))) != 1 {
+			return errRefreshTokenReused
+		}
+
+		session.RefreshTokenHash = hashToken(newSecret)
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(tokens.RefreshTTL)
+		return nil
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errRefreshTokenReused):
+		// มีการใช้ Refresh Token เก่าซ้ำ (อาจถูกขโมย) จึงเพิกถอนทั้ง session
+		log.Printf("Refresh token reuse detected for session %s, revoking", sessionID)
+		if existing, getErr := st.GetSession(ctx, sessionID); getErr == nil {
+			if err := st.RevokeSession(ctx, existing.UserID, sessionID); err != nil {
+				log.Printf("Failed to revoke session %s: %v", sessionID, err)
+			}
+			audit.Record(c, st, models.AuditEntry{
+				ActorID:    existing.UserID,
+				Action:     models.AuditActionRefreshTokenReused,
+				TargetType: models.AuditTargetSession,
+				TargetID:   sessionID,
+			})
+		}
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidRefreshToken, "Invalid or expired refresh token"))
+		return
+	case errors.Is(err, errInvalidRefreshToken), errors.Is(err, store.ErrNotFound):
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidRefreshToken, "Invalid or expired refresh token"))
+		return
+	default:
+		log.Printf("Refresh transaction failed: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+
+	// 2. อ่าน role ล่าสุดของผู้ใช้ เผื่อมีการเปลี่ยนแปลงหลังจากล็อกอิน
+	user, err := st.GetUser(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return
+	}
+	if inactive := InactiveAccountError(user); inactive != nil {
+		apperror.Abort(c, inactive)
+		return
+	}
+
+	// 3. ออก Access Token ใหม่
+	accessToken, err := issueAccessToken(keys, tokens.AccessTTL, session.UserID, user.Role, session.ID)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("Could not generate token"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         accessToken,
+		"refresh_token": session.ID + "." + newSecret,
+		"expires_in":    int(tokens.AccessTTL.Seconds()),
+	})
+}
+
+// --- Logout Handler ---
+
+// LogoutHandler เพิกถอน session ปัจจุบัน (ทั้ง Access Token และ Refresh Token ของ session นี้)
+func LogoutHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+	sessionID := c.GetString("sid")
+	if uid == "" || sessionID == "" {
+		apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Session not found in token"))
+		return
+	}
+
+	ctx := context.Background()
+	if err := st.RevokeSession(ctx, uid, sessionID); err != nil {
+		log.Printf("Failed to revoke session %s: %v", sessionID, err)
+		apperror.Abort(c, apperror.Internal("Failed to log out"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionLogout,
+		TargetType: models.AuditTargetSession,
+		TargetID:   sessionID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"code": i18n.MsgLoggedOut, "message": i18n.Message(c, i18n.MsgLoggedOut)})
+}
+
+// --- Session Management Handlers ---
+
+// GetMySessionsHandler แสดงรายการ session ที่ยังใช้งานอยู่ของผู้ใช้
+func GetMySessionsHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+	currentSessionID := c.GetString("sid")
+
+	ctx := context.Background()
+	userSessions, err := st.ListUserSessions(ctx, uid)
+	if err != nil {
+		log.Printf("Failed to list sessions: %v", err)
+		apperror.Abort(c, apperror.Internal("Could not retrieve sessions"))
+		return
+	}
+
+	type sessionResponse struct {
+		models.Session
+		Current bool `json:"current"`
+	}
+	sessions := []sessionResponse{}
+	now := time.Now()
+
+	for _, session := range userSessions {
+		// แสดงเฉพาะ session ที่ยังไม่ถูกเพิกถอนและยังไม่หมดอายุ
+		if !session.Active(now) {
+			continue
+		}
+		sessions = append(sessions, sessionResponse{Session: session, Current: session.ID == currentSessionID})
+	}
+
+	// เรียงจากที่ใช้งานล่าสุดก่อน
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeMySessionHandler เพิกถอน session ที่ระบุ (เช่น อุปกรณ์ที่หาย)
+func RevokeMySessionHandler(c *gin.Context, st store.Store) {
+	uid := c.GetString("uid")
+	sessionID := c.Param("id")
+
+	ctx := context.Background()
+	err := st.RevokeSession(ctx, uid, sessionID)
+	if errors.Is(err, store.ErrNotFound) {
+		apperror.Abort(c, apperror.NotFound(apperror.CodeSessionNotFound, "Session not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to revoke session %s: %v", sessionID, err)
+		apperror.Abort(c, apperror.Internal("Failed to revoke session"))
+		return
+	}
+
+	audit.Record(c, st, models.AuditEntry{
+		Action:     models.AuditActionSessionRevoke,
+		TargetType: models.AuditTargetSession,
+		TargetID:   sessionID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"code": i18n.MsgSessionRevoked, "message": i18n.Message(c, i18n.MsgSessionRevoked)})
+}
+
+// --- Helpers ---
+
+// createSession สร้าง session ใหม่อายุ ttl และคืน Refresh Token ในรูปแบบ "<session id>.<secret>"
+func createSession(c *gin.Context, ctx context.Context, st store.Store, uid string, ttl time.Duration) (string, string, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:           uid,
+		RefreshTokenHash: hashToken(secret),
+		UserAgent:        c.Request.UserAgent(),
+		IP:               c.ClientIP(),
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(ttl),
+	}
+
+	id, err := st.CreateSession(ctx, &session)
+	if err != nil {
+		return "", "", err
+	}
+	return id, id + "." + secret, nil
+}
+
+// issueAccessToken สร้าง Access Token อายุสั้น (ttl) ที่ผูกกับ session
+func issueAccessToken(keys *auth.KeyRing, ttl time.Duration, uid, role, sessionID string) (string, error) {
+	claims := &Claims{
+		UserID:    uid,
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+	return keys.Sign(claims)
+}
+
+// randomToken สุ่มค่า secret สำหรับ Refresh Token
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken เก็บเฉพาะค่า hash ของ Refresh Token ลงฐานข้อมูล
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,53 +2,208 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
+	"meerank/apperror"
 	"meerank/models"
+	"meerank/permissions"
+	"meerank/store"
 	"net/http"
+	"slices"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// GetUserProfileHandler ดึงข้อมูลโปรไฟล์ผู้ใช้จาก Firestore
-func GetUserProfileHandler(c *gin.Context, client *firestore.Client) {
+// ความสัมพันธ์ของผู้เรียกกับเจ้าของโปรไฟล์ (ตอบกลับใน field relationship เมื่อผู้เรียกล็อกอินอยู่)
+const (
+	relationshipSelf            = "self"
+	relationshipFriend          = "friend"
+	relationshipRequestSent     = "request_sent"
+	relationshipRequestReceived = "request_received"
+	relationshipNone            = "none"
+)
+
+// GetUserProfileHandler ดึงข้อมูลโปรไฟล์สาธารณะของผู้ใช้ตามระดับการมองเห็นที่เจ้าของตั้งไว้
+// ไม่ต้องล็อกอิน แต่ถ้าส่ง Token มา (OptionalAuthMiddleware) จะเห็นข้อมูลมากขึ้นตามความสัมพันธ์
+//   - ทุกคนที่มีสิทธิ์เห็นโปรไฟล์: name, score, minute, number_tree
+//   - เพื่อน: เพิ่ม last_login_at
+//   - เจ้าของและผู้ที่มีสิทธิ์ users:read: เพิ่ม age, gender และการตั้งค่าความเป็นส่วนตัว
+//
+// โปรไฟล์ที่ผู้เรียกไม่มีสิทธิ์เห็นตอบ 404 เหมือนไม่มีผู้ใช้ เพื่อไม่ให้เดา ID ได้ว่ามีอยู่จริง
+func GetUserProfileHandler(c *gin.Context, st store.Store) {
 	// 1. ดึง uid (Document ID) จาก URL parameter (เป็น string)
 	uid := c.Param("uid")
 
 	ctx := context.Background()
 
-	// 2. ค้นหา document ใน collection "users" ด้วย uid
-	doc, err := client.Collection("users").Doc(uid).Get(ctx)
+	// 2. ค้นหาผู้ใช้ด้วย uid
+	user, err := st.GetUser(ctx, uid)
 	if err != nil {
 		// 2.1 ตรวจสอบว่าเป็น error "ไม่พบข้อมูล" หรือไม่
-		if status.Code(err) == codes.NotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
 			return
 		}
 
 		// 2.2 ถ้าเป็น error อื่นๆ
-		log.Printf("Failed to get user from Firestore: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		log.Printf("Failed to get user: %v", err)
+		apperror.Abort(c, apperror.Internal("Database error"))
 		return
 	}
 
-	// 3. แปลงข้อมูลจาก document ไปยัง struct model.User
-	var user models.User // <-- บรรทัดนี้ที่เคย error
-	if err := doc.DataTo(&user); err != nil {
-		log.Printf("Failed to map user data: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process user data"})
+	// 2.3 ผู้ใช้ที่ถูกลบแล้วถือว่าไม่มีอยู่
+	if user.DeletedAt != nil {
+		apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
 		return
 	}
 
-	// 4. สร้างข้อมูลที่จะตอบกลับ (Response)
+	// 3. ตรวจว่าผู้เรียกเห็นโปรไฟล์นี้ได้หรือไม่
+	v, ok := loadViewer(ctx, c, st)
+	if !ok {
+		return
+	}
+	if !v.canSeeProfile(user) {
+		apperror.Abort(c, apperror.NotFound(apperror.CodeUserNotFound, "User not found"))
+		return
+	}
+
+	// 4. สร้างข้อมูลที่จะตอบกลับ (Response) ตามความสัมพันธ์
 	response := gin.H{
-		"name":   user.Name,
-		"score":  user.Score,
-		"minute": user.Minute,
+		"uid":         user.ID,
+		"name":        user.Name,
+		"score":       user.Score,
+		"minute":      user.Minute,
+		"number_tree": user.NumberTree,
+	}
+	relationship := v.relationship(user.ID)
+	if relationship != "" {
+		response["relationship"] = relationship
+	}
+	if relationship == relationshipFriend || relationship == relationshipSelf || v.staff {
+		response["last_login_at"] = user.LastLoginAt
+	}
+	if relationship == relationshipSelf || v.staff {
+		response["age"] = user.Age
+		response["gender"] = user.Gender
+		response["profile_visibility"] = user.Visibility()
+		response["leaderboard_alias"] = user.LeaderboardAlias
 	}
 
 	// 5. ส่งข้อมูลกลับไปเป็น JSON
 	c.JSON(http.StatusOK, response)
 }
+
+// --- Viewer ---
+
+// viewer คือผู้เรียก API สาธารณะ ใช้ตัดสินว่าเห็นโปรไฟล์และชื่อบน leaderboard ของใครได้บ้าง
+type viewer struct {
+	uid     string // ว่าง = ไม่ได้ล็อกอิน
+	staff   bool   // มีสิทธิ์ users:read
+	friends map[string]*models.Friendship
+}
+
+// loadViewer อ่านผู้เรียกจาก context (uid ที่ AuthMiddleware หรือ OptionalAuthMiddleware ใส่ไว้)
+// และความสัมพันธ์ทั้งหมดของผู้เรียก ถ้าอ่านไม่สำเร็จจะตอบ 500 ให้แล้วและคืน ok เป็น false
+func loadViewer(ctx context.Context, c *gin.Context, st store.Store) (*viewer, bool) {
+	v := &viewer{uid: c.GetString("uid"), friends: map[string]*models.Friendship{}}
+	if v.uid == "" {
+		return v, true
+	}
+	if granted, ok := c.Get("permissions"); ok {
+		perms, _ := granted.([]string)
+		v.staff = slices.Contains(perms, permissions.UsersRead)
+	}
+
+	friendships, err := st.ListFriendships(ctx, v.uid)
+	if err != nil {
+		log.Printf("Failed to list friendships of user %s: %v", v.uid, err)
+		apperror.Abort(c, apperror.Internal("Database error"))
+		return nil, false
+	}
+	for i := range friendships {
+		v.friends[friendships[i].Other(v.uid)] = &friendships[i]
+	}
+	return v, true
+}
+
+// relationship คืนความสัมพันธ์ของผู้เรียกกับผู้ใช้ uid (ว่างถ้าผู้เรียกไม่ได้ล็อกอิน)
+func (v *viewer) relationship(uid string) string {
+	switch {
+	case v.uid == "":
+		return ""
+	case v.uid == uid:
+		return relationshipSelf
+	}
+	friendship, ok := v.friends[uid]
+	switch {
+	case !ok:
+		return relationshipNone
+	case friendship.Status == models.FriendshipAccepted:
+		return relationshipFriend
+	case friendship.RequestedBy == v.uid:
+		return relationshipRequestSent
+	default:
+		return relationshipRequestReceived
+	}
+}
+
+// canSeeProfile บอกว่าผู้เรียกเห็นโปรไฟล์ (และชื่อจริง) ของ user ได้หรือไม่
+func (v *viewer) canSeeProfile(user *models.User) bool {
+	if v.staff || (v.uid != "" && v.uid == user.ID) {
+		return true
+	}
+	switch user.Visibility() {
+	case models.VisibilityPublic:
+		return true
+	case models.VisibilityFriends:
+		return v.relationship(user.ID) == relationshipFriend
+	default:
+		return false
+	}
+}
+
+// leaderboardName คืนชื่อของ user ที่ผู้เรียกเห็นบน leaderboard
+// เจ้าของและ staff เห็นชื่อจริง คนอื่นเห็น alias ถ้าตั้งไว้ ไม่งั้นเห็นชื่อจริงเฉพาะเมื่อเห็นโปรไฟล์ได้
+func (v *viewer) leaderboardName(user *models.User) string {
+	switch {
+	case user.DeletedAt != nil:
+		return models.DeletedUserName
+	case v.staff || (v.uid != "" && v.uid == user.ID):
+		return user.Name
+	case user.LeaderboardAlias != "":
+		return user.LeaderboardAlias
+	case v.canSeeProfile(user):
+		return user.Name
+	default:
+		return models.AnonymousName
+	}
+}
+
+// leaderboardNames คืนชื่อที่ผู้เรียกเห็นของทุกคนใน rows (map ตาม user ID)
+// ผู้ใช้ที่ถูกลบถาวรไปแล้วไม่อยู่ใน map จึงใช้ชื่อที่ store ซ่อนไว้แล้วใน row
+// ถ้าอ่านไม่สำเร็จจะตอบ 500 ให้แล้วและคืน ok เป็น false
+func leaderboardNames(ctx context.Context, c *gin.Context, st store.Store, rows ...[]store.LeaderboardRow) (map[string]string, bool) {
+	v, ok := loadViewer(ctx, c, st)
+	if !ok {
+		return nil, false
+	}
+
+	var ids []string
+	for _, group := range rows {
+		for _, row := range group {
+			ids = append(ids, row.UserID)
+		}
+	}
+	users, err := st.GetUsers(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to load leaderboard users: %v", err)
+		apperror.Abort(c, apperror.Internal("Failed to fetch leaderboard data"))
+		return nil, false
+	}
+
+	names := make(map[string]string, len(users))
+	for id, user := range users {
+		names[id] = v.leaderboardName(&user)
+	}
+	return names, true
+}
@@ -0,0 +1,123 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kind คือประเภทของ error ซึ่งกำหนด HTTP status ของคำตอบ
+type Kind string
+
+const (
+	KindBadRequest   Kind = "bad_request"
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	// KindInsufficientScore คือคะแนนสะสมไม่พอสำหรับการกระทำ (ตอบ 403 เหมือนที่ client รุ่นเก่าคาดไว้)
+	KindInsufficientScore Kind = "insufficient_score"
+	KindNotFound          Kind = "not_found"
+	KindConflict          Kind = "conflict"
+	KindTooManyRequests   Kind = "too_many_requests"
+	// KindUpstream คือบริการภายนอก (เช่นผู้ให้บริการ SMS) ทำงานไม่สำเร็จ
+	KindUpstream Kind = "upstream"
+	KindInternal Kind = "internal"
+)
+
+var statuses = map[Kind]int{
+	KindBadRequest:        http.StatusBadRequest,
+	KindValidation:        http.StatusUnprocessableEntity,
+	KindUnauthorized:      http.StatusUnauthorized,
+	KindForbidden:         http.StatusForbidden,
+	KindInsufficientScore: http.StatusForbidden,
+	KindNotFound:          http.StatusNotFound,
+	KindConflict:          http.StatusConflict,
+	KindTooManyRequests:   http.StatusTooManyRequests,
+	KindUpstream:          http.StatusBadGateway,
+	KindInternal:          http.StatusInternalServerError,
+}
+
+// Error คือ error ที่ส่งถึง client ได้ Code เป็นค่าคงที่ (ดู codes.go) ให้แอปใช้ตัดสินใจแทนการอ่าน Message
+// Details คือข้อมูลเพิ่มเติมของ error นั้นๆ เช่นรายการ field ที่ไม่ผ่านการตรวจ
+//
+// ค่าที่ประกาศเป็นตัวแปรระดับ package ใช้เทียบด้วย errors.Is ได้ (เทียบ pointer เดียวกัน)
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details any
+}
+
+func (e *Error) Error() string { return e.Code + ": " + e.Message }
+
+// Status คืน HTTP status ตามประเภทของ error
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// WithDetails คืนสำเนาของ error พร้อม Details (ไม่แก้ค่าเดิมที่อาจใช้ร่วมกันอยู่)
+func (e *Error) WithDetails(details any) *Error {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+// New สร้าง Error ตามประเภทที่กำหนด
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// ตัวสร้าง Error ของแต่ละประเภท
+func BadRequest(code, message string) *Error      { return New(KindBadRequest, code, message) }
+func Unauthorized(code, message string) *Error    { return New(KindUnauthorized, code, message) }
+func Forbidden(code, message string) *Error       { return New(KindForbidden, code, message) }
+func NotFound(code, message string) *Error        { return New(KindNotFound, code, message) }
+func Conflict(code, message string) *Error        { return New(KindConflict, code, message) }
+func TooManyRequests(code, message string) *Error { return New(KindTooManyRequests, code, message) }
+func Upstream(code, message string) *Error        { return New(KindUpstream, code, message) }
+
+// InsufficientScore คือคะแนนไม่พอ
+func InsufficientScore(message string) *Error {
+	return New(KindInsufficientScore, CodeInsufficientScore, message)
+}
+
+// Internal คือความผิดพลาดฝั่งเซิร์ฟเวอร์ ผู้เรียกควร log สาเหตุไว้เอง เพราะสาเหตุไม่ถูกส่งถึง client
+func Internal(message string) *Error { return New(KindInternal, CodeInternal, message) }
+
+// Validation คือ payload ไม่ผ่านการตรวจ fields คือรายการ field ที่ผิด (ดู validation.Errors)
+func Validation(fields any) *Error {
+	return New(KindValidation, CodeValidationFailed, "Validation failed").WithDetails(gin.H{"fields": fields})
+}
+
+// InvalidParameter คือค่าใน query string หรือ path ไม่ถูกต้อง
+func InvalidParameter(parameter, message string) *Error {
+	return BadRequest(CodeInvalidParameter, message).WithDetails(gin.H{"parameter": parameter})
+}
+
+// Abort ส่ง err ให้ ErrorMiddleware เขียนคำตอบ แล้วหยุด handler ที่เหลือในสาย
+// err ที่ไม่ใช่ *Error ถูกตอบเป็น internal_error (และถูก log ไว้)
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// As คืน *Error ที่อยู่ใน chain ของ err
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// Response คือรูปแบบคำตอบเมื่อเกิด error ของทุก endpoint
+type Response struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id"`
+}
@@ -0,0 +1,157 @@
+package apperror
+
+// รหัสของ error (Error.Code) ที่ส่งถึง client ห้ามเปลี่ยนค่าที่ปล่อยไปแล้ว เพราะแอปใช้ตัดสินใจจากรหัสเหล่านี้
+const (
+	// ทั่วไป
+	CodeInternal         = "internal_error"
+	CodeInvalidInput     = "invalid_input"     // อ่าน body ไม่ได้หรือไม่ใช่ JSON ที่ถูกต้อง
+	CodeInvalidParameter = "invalid_parameter" // ค่าใน query string หรือ path ไม่ถูกต้อง (details.parameter)
+	CodeValidationFailed = "validation_failed" // payload ไม่ผ่านการตรวจ (details.fields)
+	CodePermissionDenied = "permission_denied"
+	CodeNoFieldsToUpdate = "no_fields_to_update"
+
+	// การยืนยันตัวตนและ session
+	CodeAuthorizationRequired = "authorization_required"
+	CodeInvalidToken          = "invalid_token"
+	CodeInvalidRefreshToken   = "invalid_refresh_token"
+	CodeInvalidIDToken        = "invalid_id_token"
+	CodeSessionRevoked        = "session_revoked"
+	CodeSessionNotFound       = "session_not_found"
+	CodeAccountSuspended      = "account_suspended"
+	CodeAccountDeleted        = "account_deleted"
+	CodeFirebaseAccountLinked = "firebase_account_linked" // บัญชีที่ใช้เบอร์นี้ผูกกับบัญชี Firebase อื่นอยู่แล้ว
+
+	// OTP และเบอร์โทรศัพท์
+	CodeInvalidOTP             = "invalid_otp"
+	CodeOTPExpired             = "otp_expired"
+	CodeOTPTooManyAttempts     = "otp_too_many_attempts"
+	CodeOTPCooldown            = "otp_cooldown"
+	CodeSMSFailed              = "sms_failed"
+	CodePhoneNotRegistered     = "phone_not_registered"
+	CodeInvalidPhone           = "invalid_phone"
+	CodePhoneTaken             = "phone_taken"
+	CodePhoneUnchanged         = "phone_unchanged"
+	CodePhoneChangeNeedsVerify = "phone_change_needs_verification"
+
+	// ผู้ใช้ คะแนน และกิจกรรม
+	CodeUserNotFound         = "user_not_found"
+	CodeInsufficientScore    = "insufficient_score"
+	CodeActivityUnknownType  = "activity_unknown_type"
+	CodeActivityTooShort     = "activity_too_short"
+	CodeActivityTooLong      = "activity_too_long"
+	CodeActivityInFuture     = "activity_in_future"
+	CodeActivityOverlap      = "activity_overlap"
+	CodeActivityRejected     = "activity_rejected"
+	CodeNotOnLeaderboard     = "not_on_leaderboard"
+	CodeNoActivityInPeriod   = "no_activity_in_period"
+	CodeSeasonNotFound       = "season_not_found"
+	CodeNotInSeason          = "not_in_season"
+	CodeFriendshipNotFound   = "friendship_not_found"
+	CodeCannotFriendSelf     = "cannot_friend_self"
+	CodeAlreadyFriends       = "already_friends"
+	CodeFriendRequestPending = "friend_request_pending"
+
+	// admin
+	CodeCannotSuspendSelf     = "cannot_suspend_self"
+	CodeCannotDeleteSelf      = "cannot_delete_self"
+	CodeUserAlreadySuspended  = "user_already_suspended"
+	CodeUserNotSuspended      = "user_not_suspended"
+	CodeUserDeleted           = "user_deleted"
+	CodeUserNotDeleted        = "user_not_deleted"
+	CodeRestoreWindowPassed   = "restore_window_passed"
+	CodeLastAdmin             = "last_admin"
+	CodeAdminMustBeDemoted    = "admin_must_be_demoted"
+	CodeJobNotFound           = "job_not_found"
+	CodeJobNotUndoable        = "job_not_undoable"
+	CodeJobNotResumable       = "job_not_resumable"
+	CodeJobInProgress         = "job_in_progress"
+	CodeReviewNotFound        = "review_not_found"
+	CodeReviewAlreadyResolved = "review_already_resolved"
+	CodeSeasonOverlap         = "season_overlap"
+	CodeSeasonNotEnded        = "season_not_ended"
+	CodeSeasonAlreadyClosed   = "season_already_closed"
+
+	// Idempotency-Key
+	CodeIdempotencyKeyTooLong     = "idempotency_key_too_long"
+	CodeIdempotencyKeyReused      = "idempotency_key_reused"
+	CodeIdempotencyInProgress     = "idempotency_in_progress"
+	CodeIdempotencyOriginalFailed = "idempotency_original_failed"
+)
+
+// Codes คือรหัสทั้งหมดข้างบน ใช้ตรวจตอนเริ่มโปรแกรมว่าทุกรหัสมีข้อความครบทุกภาษา (ดู i18n.Validate)
+var Codes = []string{
+	// ทั่วไป
+	CodeInternal,
+	CodeInvalidInput,
+	CodeInvalidParameter,
+	CodeValidationFailed,
+	CodePermissionDenied,
+	CodeNoFieldsToUpdate,
+
+	// การยืนยันตัวตนและ session
+	CodeAuthorizationRequired,
+	CodeInvalidToken,
+	CodeInvalidRefreshToken,
+	CodeInvalidIDToken,
+	CodeSessionRevoked,
+	CodeSessionNotFound,
+	CodeAccountSuspended,
+	CodeAccountDeleted,
+	CodeFirebaseAccountLinked,
+
+	// OTP และเบอร์โทรศัพท์
+	CodeInvalidOTP,
+	CodeOTPExpired,
+	CodeOTPTooManyAttempts,
+	CodeOTPCooldown,
+	CodeSMSFailed,
+	CodePhoneNotRegistered,
+	CodeInvalidPhone,
+	CodePhoneTaken,
+	CodePhoneUnchanged,
+	CodePhoneChangeNeedsVerify,
+
+	// ผู้ใช้ คะแนน และกิจกรรม
+	CodeUserNotFound,
+	CodeInsufficientScore,
+	CodeActivityUnknownType,
+	CodeActivityTooShort,
+	CodeActivityTooLong,
+	CodeActivityInFuture,
+	CodeActivityOverlap,
+	CodeActivityRejected,
+	CodeNotOnLeaderboard,
+	CodeNoActivityInPeriod,
+	CodeSeasonNotFound,
+	CodeNotInSeason,
+	CodeFriendshipNotFound,
+	CodeCannotFriendSelf,
+	CodeAlreadyFriends,
+	CodeFriendRequestPending,
+
+	// admin
+	CodeCannotSuspendSelf,
+	CodeCannotDeleteSelf,
+	CodeUserAlreadySuspended,
+	CodeUserNotSuspended,
+	CodeUserDeleted,
+	CodeUserNotDeleted,
+	CodeRestoreWindowPassed,
+	CodeLastAdmin,
+	CodeAdminMustBeDemoted,
+	CodeJobNotFound,
+	CodeJobNotUndoable,
+	CodeJobNotResumable,
+	CodeJobInProgress,
+	CodeReviewNotFound,
+	CodeReviewAlreadyResolved,
+	CodeSeasonOverlap,
+	CodeSeasonNotEnded,
+	CodeSeasonAlreadyClosed,
+
+	// Idempotency-Key
+	CodeIdempotencyKeyTooLong,
+	CodeIdempotencyKeyReused,
+	CodeIdempotencyInProgress,
+	CodeIdempotencyOriginalFailed,
+}
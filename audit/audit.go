@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"meerank/models"
+	"meerank/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Record เติมข้อมูลของคำขอ (ผู้กระทำ, IP, User-Agent, request id และเวลา) ลงใน entry แล้วบันทึก
+// ถ้าไม่ได้ระบุ ActorID/ActorRole จะใช้ uid และ role ที่ AuthMiddleware ใส่ไว้
+// การบันทึกไม่สำเร็จจะถูก log ไว้ แต่ไม่ทำให้คำขอล้มเหลว
+func Record(c *gin.Context, st store.AuditStore, entry models.AuditEntry) {
+	if entry.ActorID == "" {
+		entry.ActorID = c.GetString("uid")
+	}
+	if entry.ActorRole == "" {
+		entry.ActorRole = c.GetString("role")
+	}
+	entry.IP = c.ClientIP()
+	entry.UserAgent = truncate(c.Request.UserAgent(), 512)
+	entry.RequestID = c.GetString("request_id")
+	entry.CreatedAt = time.Now().UTC()
+
+	if err := st.AppendAudit(context.Background(), &entry); err != nil {
+		log.Printf("Failed to write audit entry %s (actor %s, target %s): %v", entry.Action, entry.ActorID, entry.TargetID, err)
+	}
+}
+
+// redacted แทนค่าของข้อมูลส่วนบุคคลใน AuditChange
+const redacted = "[redacted]"
+
+// personalFields คือ field ของผู้ใช้ที่เป็นข้อมูลส่วนบุคคล audit log ไม่ถูกลบตอนลบผู้ใช้ถาวร
+// จึงบันทึกแค่ว่า field เปลี่ยน ไม่บันทึกค่า
+var personalFields = map[string]bool{
+	"phone":  true,
+	"age":    true,
+	"gender": true,
+}
+
+// Changes เทียบ before กับ after ตามชื่อ field ใน JSON แล้วคืนเฉพาะ field ที่ค่าเปลี่ยน เรียงตามชื่อ
+// ทั้งสองค่าควรเป็น struct ชนิดเดียวกัน (หรือ pointer ไปยัง struct)
+// ค่าของ field ที่เป็นข้อมูลส่วนบุคคลถูกแทนด้วย redacted (ค่าว่างยังคงเป็น null)
+func Changes(before, after interface{}) []models.AuditChange {
+	beforeFields, err := fields(before)
+	if err != nil {
+		log.Printf("Failed to diff audit values: %v", err)
+		return nil
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		log.Printf("Failed to diff audit values: %v", err)
+		return nil
+	}
+
+	names := make(map[string]struct{}, len(beforeFields))
+	for name := range beforeFields {
+		names[name] = struct{}{}
+	}
+	for name := range afterFields {
+		names[name] = struct{}{}
+	}
+
+	var changes []models.AuditChange
+	for name := range names {
+		if reflect.DeepEqual(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		change := models.AuditChange{Field: name, Before: beforeFields[name], After: afterFields[name]}
+		if personalFields[name] {
+			change.Before, change.After = redact(change.Before), redact(change.After)
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func redact(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return redacted
+}
+
+// fields แปลงค่าเป็น map ของ field ตามรูปแบบ JSON เพื่อให้ชื่อ field ตรงกับที่ API ใช้
+func fields(value interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
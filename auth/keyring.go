@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"meerank/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LegacyKeyID คือ kid ที่ใช้ตรวจ Token รุ่นเก่าที่ไม่มี kid ใน header
+// ถ้าต้องการให้ Token เก่ายังใช้ได้ ให้ใส่ secret เดิมไว้ภายใต้ kid นี้
+const LegacyKeyID = "default"
+
+// MinSecretLength คือความยาวขั้นต่ำของ secret สำหรับ HS256
+const MinSecretLength = 32
+
+// SigningKey คือกุญแจหนึ่งดอกใน KeyRing
+type SigningKey struct {
+	ID     string
+	Secret []byte
+	// ExpiresAt (ถ้ามี) คือเวลาที่เลิกยอมรับ Token ที่เซ็นด้วยกุญแจนี้
+	ExpiresAt *time.Time
+}
+
+// KeyRing เก็บกุญแจสำหรับเซ็นและตรวจสอบ JWT
+// Token ใหม่จะถูกเซ็นด้วยกุญแจ active เสมอ ส่วนกุญแจอื่นที่ยังอยู่ใน ring ใช้ตรวจสอบได้อย่างเดียว
+type KeyRing struct {
+	activeID string
+	keys     map[string]SigningKey
+}
+
+// NewKeyRing สร้าง KeyRing และตรวจสอบความถูกต้องของกุญแจทั้งหมด
+func NewKeyRing(activeID string, keys []SigningKey) (*KeyRing, error) {
+	ring := &KeyRing{activeID: activeID, keys: make(map[string]SigningKey, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("jwt key id must not be empty")
+		}
+		if len(key.Secret) < MinSecretLength {
+			return nil, fmt.Errorf("jwt key %q must be at least %d bytes", key.ID, MinSecretLength)
+		}
+		if _, dup := ring.keys[key.ID]; dup {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q not found", activeID)
+	}
+	if active.ExpiresAt != nil {
+		return nil, fmt.Errorf("active jwt key %q must not have an expiry", activeID)
+	}
+	return ring, nil
+}
+
+// ActiveKeyID คืน kid ของกุญแจที่ใช้เซ็น Token ใหม่
+func (r *KeyRing) ActiveKeyID() string {
+	return r.activeID
+}
+
+// Sign เซ็น claims ด้วยกุญแจ active และใส่ kid ลงใน header
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = r.activeID
+	return token.SignedString(r.keys[r.activeID].Secret)
+}
+
+// Parse ตรวจสอบ Token ด้วยกุญแจตาม kid ใน header แล้วอ่านค่าลงใน claims
+func (r *KeyRing) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, r.keyFunc, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+}
+
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown jwt key id %q", kid)
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, fmt.Errorf("jwt key %q has been retired", kid)
+	}
+	return key.Secret, nil
+}
+
+// --- Loading ---
+
+// keyFile คือรูปแบบไฟล์ JSON ที่ระบุใน JWT_KEYS_FILE
+//
+//	{
+//	  "active_kid": "2025-10",
+//	  "keys": [
+//	    {"kid": "2025-10", "secret": "..."},
+//	    {"kid": "2025-09", "secret": "...", "expires_at": "2025-11-01T00:00:00Z"}
+//	  ]
+//	}
+type keyFile struct {
+	ActiveKID string `json:"active_kid"`
+	Keys      []struct {
+		KID       string     `json:"kid"`
+		Secret    string     `json:"secret"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	} `json:"keys"`
+}
+
+// LoadKeyRing โหลดกุญแจจากค่าตั้งตามลำดับ:
+//  1. KeysFile - path ของไฟล์ JSON (ดู keyFile)
+//  2. Keys     - รายการ "kid:secret" คั่นด้วยจุลภาค ใช้คู่กับ ActiveKID (ค่าเริ่มต้นคือตัวแรก)
+//  3. Secret   - กุญแจเดียว ใช้ kid เป็น LegacyKeyID
+func LoadKeyRing(cfg config.JWT) (*KeyRing, error) {
+	if cfg.KeysFile != "" {
+		return loadKeyRingFile(cfg.KeysFile)
+	}
+
+	if list := cfg.Keys; list != "" {
+		var keys []SigningKey
+		for _, entry := range strings.Split(list, ",") {
+			kid, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:secret", entry)
+			}
+			keys = append(keys, SigningKey{ID: kid, Secret: []byte(secret)})
+		}
+		activeID := cfg.ActiveKID
+		if activeID == "" {
+			activeID = keys[0].ID
+		}
+		return NewKeyRing(activeID, keys)
+	}
+
+	if secret := cfg.Secret; secret != "" {
+		return NewKeyRing(LegacyKeyID, []SigningKey{{ID: LegacyKeyID, Secret: []byte(secret)}})
+	}
+
+	return nil, errors.New("no JWT signing key configured, set JWT_KEYS_FILE, JWT_KEYS or JWT_SECRET")
+}
+
+func loadKeyRingFile(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt key file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse jwt key file: %w", err)
+	}
+
+	keys := make([]SigningKey, 0, len(file.Keys))
+	for _, k := range file.Keys {
+		keys = append(keys, SigningKey{ID: k.KID, Secret: []byte(k.Secret), ExpiresAt: k.ExpiresAt})
+	}
+	return NewKeyRing(file.ActiveKID, keys)
+}
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config คือค่าตั้งทั้งหมดของเซิร์ฟเวอร์ โหลดครั้งเดียวตอนเริ่มโปรแกรมด้วย Load แล้วส่งต่อให้ router และ handler
+// ไม่มี package อื่นอ่าน Environment Variable เอง
+type Config struct {
+	Server      Server      `yaml:"server"`
+	Database    Database    `yaml:"database"`
+	Auth        Auth        `yaml:"auth"`
+	SMS         SMS         `yaml:"sms"`
+	Scoring     Scoring     `yaml:"scoring"`
+	Permissions Permissions `yaml:"permissions"`
+	Leaderboard Leaderboard `yaml:"leaderboard"`
+	Trees       Trees       `yaml:"trees"`
+	Bootstrap   Bootstrap   `yaml:"bootstrap_admin"`
+}
+
+// Server คือค่าของ HTTP server
+type Server struct {
+	Port int `yaml:"port"`
+	// CORSAllowedOrigins คือ origin ที่เรียก API จาก browser ได้ ("*" = ทุก origin)
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"`
+}
+
+// Addr คือ address ที่ server รอรับคำขอ เช่น ":8080"
+func (s Server) Addr() string {
+	return ":" + strconv.Itoa(s.Port)
+}
+
+// AllowAllOrigins บอกว่าเปิด CORS ให้ทุก origin หรือไม่
+func (s Server) AllowAllOrigins() bool {
+	return slices.Contains(s.CORSAllowedOrigins, "*")
+}
+
+// backend ของฐานข้อมูล (Database.Driver)
+const (
+	DriverFirestore = "firestore"
+	DriverMySQL     = "mysql"
+	DriverSQLite    = "sqlite"
+	DriverMemory    = "memory"
+)
+
+// Database คือค่าของฐานข้อมูล
+//   - firestore ใช้ Cloud Firestore และเปิดการล็อกอินด้วย Firebase Authentication (ต้องมี Firebase)
+//   - mysql / sqlite ใช้ฐานข้อมูล SQL ผ่าน GORM ตาม DSN (MySQL ต้องใส่ parseTime=true ใน DSN)
+//   - memory เก็บข้อมูลในหน่วยความจำ สำหรับทดสอบบนเครื่องเท่านั้น
+type Database struct {
+	Driver   string   `yaml:"driver"`
+	DSN      string   `yaml:"dsn"`
+	Firebase Firebase `yaml:"firebase"`
+}
+
+// Firebase คือค่าที่ใช้สร้าง Firebase App
+type Firebase struct {
+	CredentialsFile string `yaml:"credentials_file"`
+	ProjectID       string `yaml:"project_id"`
+}
+
+// Auth คือค่าของ Token และกุญแจที่ใช้เซ็น JWT
+type Auth struct {
+	Tokens Tokens `yaml:"tokens"`
+	JWT    JWT    `yaml:"jwt"`
+}
+
+// Tokens คืออายุของ Access Token และ Refresh Token
+type Tokens struct {
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+// JWT คือแหล่งของกุญแจสำหรับเซ็น JWT ใช้ตัวแรกที่ตั้งไว้ตามลำดับ (ดู auth.LoadKeyRing)
+//  1. KeysFile  - path ของไฟล์ JSON
+//  2. Keys      - รายการ "kid:secret" คั่นด้วยจุลภาค ใช้คู่กับ ActiveKID (ค่าเริ่มต้นคือตัวแรก)
+//  3. Secret    - กุญแจเดียว
+type JWT struct {
+	KeysFile  string `yaml:"keys_file"`
+	Keys      string `yaml:"keys"`
+	ActiveKID string `yaml:"active_kid"`
+	Secret    string `yaml:"secret"`
+}
+
+// SMS คือผู้ให้บริการส่ง SMS (ดู sms.NewSender)
+type SMS struct {
+	Provider string `yaml:"provider"`
+}
+
+// Scoring คือไฟล์กติกาการคิดคะแนน (ว่าง = scoring.DefaultRules)
+type Scoring struct {
+	RulesFile string `yaml:"rules_file"`
+}
+
+// Permissions คือไฟล์สิทธิ์ของแต่ละ role (ว่าง = permissions.DefaultMapping)
+type Permissions struct {
+	File string `yaml:"file"`
+}
+
+// Leaderboard คือค่าของตารางอันดับ
+type Leaderboard struct {
+	// Timezone ใช้ตัดรอบ leaderboard รายวัน/สัปดาห์/เดือน และรอบวันของเพดานคะแนนรายวัน (scoring)
+	Timezone string `yaml:"timezone"`
+	// DefaultPageSize คือจำนวนอันดับต่อหน้าเมื่อไม่ส่ง limit มา ส่วน MaxPageSize คือ limit สูงสุด
+	DefaultPageSize int `yaml:"default_page_size"`
+	MaxPageSize     int `yaml:"max_page_size"`
+}
+
+// Trees คือค่าของการปลูกต้นไม้
+type Trees struct {
+	// PointsPerTree คือคะแนนที่ต้องรดน้ำสะสมจนได้ต้นไม้ 1 ต้น
+	PointsPerTree int `yaml:"points_per_tree"`
+}
+
+// Bootstrap คือ admin คนแรกของระบบ สร้างหรือเลื่อนขั้นตอนเริ่มโปรแกรมเฉพาะเมื่อยังไม่มี admin เลย
+type Bootstrap struct {
+	AdminPhone string `yaml:"admin_phone"`
+	AdminName  string `yaml:"admin_name"`
+}
+
+// Default คือค่าเริ่มต้นของทุก field ที่ไม่ได้ตั้งไว้
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:               8080,
+			CORSAllowedOrigins: []string{"*"},
+		},
+		Database: Database{
+			Driver: DriverFirestore,
+		},
+		Auth: Auth{
+			Tokens: Tokens{
+				AccessTTL:  15 * time.Minute,
+				RefreshTTL: 30 * 24 * time.Hour,
+			},
+		},
+		SMS: SMS{
+			Provider: "console",
+		},
+		Leaderboard: Leaderboard{
+			Timezone:        "Asia/Bangkok",
+			DefaultPageSize: 10,
+			MaxPageSize:     100,
+		},
+		Trees: Trees{
+			PointsPerTree: 1000,
+		},
+		Bootstrap: Bootstrap{
+			AdminName: "Admin",
+		},
+	}
+}
+
+// Validate ตรวจค่าทั้งหมดและรวบรวมทุกปัญหาไว้ใน error เดียว (ไม่หยุดที่ปัญหาแรก)
+// ค่าที่ต้องเปิดไฟล์หรือเชื่อมต่อภายนอกจึงจะรู้ (เช่นไฟล์กุญแจ หรือ timezone) ตรวจตอนสร้าง component นั้นแทน
+func (c *Config) Validate() error {
+	var p Problems
+	c.validate(&p)
+	return p.Err()
+}
+
+func (c *Config) validate(p *Problems) {
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		p.Add("PORT", "must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if len(c.Server.CORSAllowedOrigins) == 0 {
+		p.Add("CORS_ALLOWED_ORIGINS", "must list at least one origin, or * for any origin")
+	}
+
+	switch c.Database.Driver {
+	case DriverFirestore:
+		if c.Database.Firebase.CredentialsFile == "" {
+			p.Add("GOOGLE_APPLICATION_CREDENTIALS", "is required when DB_DRIVER is firestore")
+		}
+		if c.Database.Firebase.ProjectID == "" {
+			p.Add("GOOGLE_PROJECT_ID", "is required when DB_DRIVER is firestore")
+		}
+	case DriverMySQL:
+		if c.Database.DSN == "" {
+			p.Add("DB_DSN", "is required when DB_DRIVER is mysql")
+		}
+	case DriverSQLite, DriverMemory:
+	default:
+		p.Add("DB_DRIVER", "must be firestore, mysql, sqlite or memory, got %q", c.Database.Driver)
+	}
+
+	if c.Auth.Tokens.AccessTTL <= 0 {
+		p.Add("ACCESS_TOKEN_TTL", "must be positive, got %s", c.Auth.Tokens.AccessTTL)
+	}
+	if c.Auth.Tokens.RefreshTTL <= c.Auth.Tokens.AccessTTL {
+		p.Add("REFRESH_TOKEN_TTL", "must be longer than ACCESS_TOKEN_TTL (%s), got %s", c.Auth.Tokens.AccessTTL, c.Auth.Tokens.RefreshTTL)
+	}
+	jwt := c.Auth.JWT
+	if jwt.KeysFile == "" && jwt.Keys == "" && jwt.Secret == "" {
+		p.Add("JWT_SECRET", "no JWT signing key configured, set JWT_KEYS_FILE, JWT_KEYS or JWT_SECRET")
+	}
+
+	if c.Leaderboard.Timezone == "" {
+		p.Add("LEADERBOARD_TIMEZONE", "must not be empty")
+	}
+	if c.Leaderboard.DefaultPageSize < 1 {
+		p.Add("LEADERBOARD_PAGE_SIZE", "must be positive, got %d", c.Leaderboard.DefaultPageSize)
+	}
+	if c.Leaderboard.MaxPageSize < c.Leaderboard.DefaultPageSize {
+		p.Add("LEADERBOARD_MAX_PAGE_SIZE", "must not be less than LEADERBOARD_PAGE_SIZE (%d), got %d", c.Leaderboard.DefaultPageSize, c.Leaderboard.MaxPageSize)
+	}
+
+	if c.Trees.PointsPerTree < 1 {
+		p.Add("TREE_POINTS_PER_TREE", "must be positive, got %d", c.Trees.PointsPerTree)
+	}
+	if c.Bootstrap.AdminPhone != "" && strings.TrimSpace(c.Bootstrap.AdminName) == "" {
+		p.Add("BOOTSTRAP_ADMIN_NAME", "must not be blank when BOOTSTRAP_ADMIN_PHONE is set")
+	}
+}
+
+// Problems รวบรวมปัญหาของค่าตั้งหลายข้อเพื่อรายงานพร้อมกันทีเดียว
+// ใช้ตอนเริ่มโปรแกรมทั้งใน Validate และตอนสร้าง component จากค่าตั้ง (ดู main)
+type Problems []string
+
+// Add เพิ่มปัญหาของค่าตั้ง name (ชื่อ Environment Variable)
+func (p *Problems) Add(name, format string, args ...any) {
+	*p = append(*p, name+": "+fmt.Sprintf(format, args...))
+}
+
+// Has บอกว่ามีปัญหาของ name แล้วหรือยัง
+func (p Problems) Has(name string) bool {
+	for _, problem := range p {
+		if strings.HasPrefix(problem, name+": ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Err คืน nil ถ้าไม่มีปัญหา ไม่อย่างนั้นคืน error ที่แสดงทุกปัญหา บรรทัดละข้อ
+func (p Problems) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return p
+}
+
+func (p Problems) Error() string {
+	return fmt.Sprintf("%d configuration problem(s):\n  - %s", len(p), strings.Join(p, "\n  - "))
+}
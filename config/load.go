@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load โหลดค่าตั้งตามลำดับ (ค่าที่มาทีหลังทับค่าก่อนหน้า):
+//  1. Default
+//  2. ไฟล์ YAML ตาม CONFIG_FILE (ถ้าตั้งไว้ ดู config.example.yaml)
+//  3. Environment Variable รวมถึงค่าในไฟล์ .env (ถ้ามี) ซึ่งไม่ทับค่าที่ตั้งไว้ใน OS แล้ว
+//
+// ค่าที่อ่านไม่ได้หรือไม่ผ่าน Validate ถูกคืนใน Problems พร้อม cfg (ไม่หยุดที่ปัญหาแรก)
+// ให้ผู้เรียกรวมกับปัญหาที่พบตอนสร้าง component แล้วรายงานทีเดียว
+// ส่วน error คือไฟล์ .env หรือ CONFIG_FILE ที่อ่านไม่ได้เลย
+func Load() (*Config, Problems, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("read .env: %w", err)
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var p Problems
+	applyEnv(&cfg, &p)
+	cfg.validate(&p)
+	return &cfg, p, nil
+}
+
+// loadFile อ่านไฟล์ YAML ทับค่าใน cfg (field ที่ไม่ได้ระบุในไฟล์คงค่าเดิม)
+func loadFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true) // สะกดชื่อ field ผิดต้องเป็น error ไม่ใช่ถูกเมินเฉย
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv ทับค่าใน cfg ด้วย Environment Variable ที่ตั้งไว้ (ค่าว่าง = ไม่ได้ตั้ง)
+// ค่าที่แปลงชนิดไม่ได้ถูกเพิ่มลงใน p
+func applyEnv(cfg *Config, p *Problems) {
+	envInt(p, "PORT", &cfg.Server.Port)
+	envList("CORS_ALLOWED_ORIGINS", &cfg.Server.CORSAllowedOrigins)
+
+	envString("DB_DRIVER", &cfg.Database.Driver)
+	envString("DB_DSN", &cfg.Database.DSN)
+	envString("GOOGLE_APPLICATION_CREDENTIALS", &cfg.Database.Firebase.CredentialsFile)
+	envString("GOOGLE_PROJECT_ID", &cfg.Database.Firebase.ProjectID)
+
+	envDuration(p, "ACCESS_TOKEN_TTL", &cfg.Auth.Tokens.AccessTTL)
+	envDuration(p, "REFRESH_TOKEN_TTL", &cfg.Auth.Tokens.RefreshTTL)
+	envString("JWT_KEYS_FILE", &cfg.Auth.JWT.KeysFile)
+	envString("JWT_KEYS", &cfg.Auth.JWT.Keys)
+	envString("JWT_ACTIVE_KID", &cfg.Auth.JWT.ActiveKID)
+	envString("JWT_SECRET", &cfg.Auth.JWT.Secret)
+
+	envString("SMS_PROVIDER", &cfg.SMS.Provider)
+	envString("SCORING_RULES_FILE", &cfg.Scoring.RulesFile)
+	envString("ROLE_PERMISSIONS_FILE", &cfg.Permissions.File)
+
+	envString("LEADERBOARD_TIMEZONE", &cfg.Leaderboard.Timezone)
+	envInt(p, "LEADERBOARD_PAGE_SIZE", &cfg.Leaderboard.DefaultPageSize)
+	envInt(p, "LEADERBOARD_MAX_PAGE_SIZE", &cfg.Leaderboard.MaxPageSize)
+
+	envInt(p, "TREE_POINTS_PER_TREE", &cfg.Trees.PointsPerTree)
+
+	envString("BOOTSTRAP_ADMIN_PHONE", &cfg.Bootstrap.AdminPhone)
+	envString("BOOTSTRAP_ADMIN_NAME", &cfg.Bootstrap.AdminName)
+}
+
+func envString(name string, dst *string) {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		*dst = value
+	}
+}
+
+func envInt(p *Problems, name string, dst *int) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		p.Add(name, "must be an integer, got %q", value)
+		return
+	}
+	*dst = parsed
+}
+
+// envDuration อ่านระยะเวลาแบบ time.ParseDuration เช่น 15m หรือ 720h
+func envDuration(p *Problems, name string, dst *time.Duration) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		p.Add(name, "must be a duration such as 15m or 720h, got %q", value)
+		return
+	}
+	*dst = parsed
+}
+
+// envList อ่านรายการที่คั่นด้วยจุลภาค
+func envList(name string, dst *[]string) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
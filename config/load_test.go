@@ -0,0 +1,149 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"meerank/config"
+)
+
+// envNames คือทุกตัวแปรที่ Load อ่าน
+var envNames = []string{
+	"CONFIG_FILE",
+	"PORT", "CORS_ALLOWED_ORIGINS",
+	"DB_DRIVER", "DB_DSN", "GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_PROJECT_ID",
+	"ACCESS_TOKEN_TTL", "REFRESH_TOKEN_TTL",
+	"JWT_KEYS_FILE", "JWT_KEYS", "JWT_ACTIVE_KID", "JWT_SECRET",
+	"SMS_PROVIDER", "SCORING_RULES_FILE", "ROLE_PERMISSIONS_FILE",
+	"LEADERBOARD_TIMEZONE", "LEADERBOARD_PAGE_SIZE", "LEADERBOARD_MAX_PAGE_SIZE",
+	"TREE_POINTS_PER_TREE",
+	"BOOTSTRAP_ADMIN_PHONE", "BOOTSTRAP_ADMIN_NAME",
+}
+
+// isolate ล้างตัวแปรที่ Load อ่าน (คืนค่าเดิมเมื่อจบ test รวมถึงค่าที่ .env ตั้งให้)
+// แล้วย้ายไปไดเรกทอรีว่างเพื่อไม่ให้อ่าน .env ของเครื่อง
+func isolate(t *testing.T) string {
+	t.Helper()
+	for _, name := range envNames {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := isolate(t)
+
+	// ค่าเริ่มต้น < YAML < .env < Environment Variable ของ OS
+	yamlPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, yamlPath, `
+database:
+  driver: memory
+leaderboard:
+  default_page_size: 20
+  max_page_size: 50
+trees:
+  points_per_tree: 500
+`)
+	writeFile(t, filepath.Join(dir, ".env"), strings.Join([]string{
+		"JWT_SECRET=dotenv-secret-dotenv-secret-dotenv",
+		"LEADERBOARD_MAX_PAGE_SIZE=60",
+		"TREE_POINTS_PER_TREE=700",
+	}, "\n"))
+	t.Setenv("CONFIG_FILE", yamlPath)
+	t.Setenv("TREE_POINTS_PER_TREE", "800")
+
+	cfg, problems, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := problems.Err(); err != nil {
+		t.Fatalf("unexpected problems: %v", err)
+	}
+
+	defaults := config.Default()
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"timezone (default)", cfg.Leaderboard.Timezone, defaults.Leaderboard.Timezone},
+		{"access TTL (default)", cfg.Auth.Tokens.AccessTTL, 15 * time.Minute},
+		{"driver (yaml)", cfg.Database.Driver, config.DriverMemory},
+		{"page size (yaml)", cfg.Leaderboard.DefaultPageSize, 20},
+		{"max page size (.env over yaml)", cfg.Leaderboard.MaxPageSize, 60},
+		{"jwt secret (.env)", cfg.Auth.JWT.Secret, "dotenv-secret-dotenv-secret-dotenv"},
+		{"points per tree (env over .env)", cfg.Trees.PointsPerTree, 800},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	isolate(t)
+	t.Setenv("PORT", "eighty")
+	t.Setenv("ACCESS_TOKEN_TTL", "soon")
+	t.Setenv("DB_DRIVER", config.DriverMySQL)
+	t.Setenv("LEADERBOARD_PAGE_SIZE", "0")
+	t.Setenv("TREE_POINTS_PER_TREE", "-1")
+
+	_, problems, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"PORT", "ACCESS_TOKEN_TTL", "DB_DSN", "JWT_SECRET", "LEADERBOARD_PAGE_SIZE", "TREE_POINTS_PER_TREE"}
+	for _, name := range want {
+		if !problems.Has(name) {
+			t.Errorf("missing problem for %s in %v", name, problems)
+		}
+	}
+	if len(problems) != len(want) {
+		t.Errorf("got %d problems, want %d: %v", len(problems), len(want), problems)
+	}
+	if err := problems.Err(); err == nil || !strings.Contains(err.Error(), "6 configuration problem(s)") {
+		t.Errorf("Err() = %v, want all 6 problems in one error", err)
+	}
+}
+
+func TestLoadRejectsUnknownYAMLField(t *testing.T) {
+	dir := isolate(t)
+	yamlPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, yamlPath, "leaderboard:\n  timezon: UTC\n")
+	t.Setenv("CONFIG_FILE", yamlPath)
+
+	if _, _, err := config.Load(); err == nil {
+		t.Fatal("Load accepted a misspelled field")
+	}
+}
+
+func TestExampleConfigMatchesDefaults(t *testing.T) {
+	example, err := filepath.Abs("../config.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	isolate(t)
+	t.Setenv("CONFIG_FILE", example)
+
+	cfg, _, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := config.Default(); !reflect.DeepEqual(*cfg, want) {
+		t.Errorf("config.example.yaml = %+v, want the defaults %+v", *cfg, want)
+	}
+}
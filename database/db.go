@@ -2,47 +2,44 @@ package database
 
 import (
 	"context"
+	"errors"
 	"log"
-	"os"
+
+	"meerank/config"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go/v4"
-	"github.com/joho/godotenv"
+	"firebase.google.com/go/v4/auth"
 	"google.golang.org/api/option"
 )
 
-func SetupFirestoreClient() (*firestore.Client, error) {
-	err := godotenv.Load()
-	if err != nil {
-		log.Printf("Warning: .env file not found, will use environment variables from OS")
-	}
-
-	credentialsPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-	if credentialsPath == "" {
-		log.Fatal("GOOGLE_APPLICATION_CREDENTIALS environment variable not set.")
-	}
-	
-	// ✨ 1. อ่านค่า Project ID จาก Environment Variable ✨
-	projectID := os.Getenv("GOOGLE_PROJECT_ID")
-	if projectID == "" {
-		log.Fatal("GOOGLE_PROJECT_ID environment variable not set.")
+// SetupFirebaseApp สร้าง Firebase App จากค่าตั้ง (ตรวจแล้วว่ามีครบใน config.Validate)
+// ใช้ App เดียวกันทั้ง Firestore และ Firebase Authentication
+func SetupFirebaseApp(cfg config.Firebase) (*firebase.App, error) {
+	if cfg.CredentialsFile == "" || cfg.ProjectID == "" {
+		return nil, errors.New("firebase credentials file and project id are required")
 	}
 
 	ctx := context.Background()
-	opt := option.WithCredentialsFile(credentialsPath)
+	opt := option.WithCredentialsFile(cfg.CredentialsFile)
 
-	// ✨ 2. สร้าง Config เพื่อระบุ Project ID โดยตรง ✨
+	// ✨ 1. สร้าง Config เพื่อระบุ Project ID โดยตรง ✨
 	conf := &firebase.Config{
-		ProjectID: projectID,
+		ProjectID: cfg.ProjectID,
 	}
 
-	// ✨ 3. ส่ง Config เข้าไปตอนสร้าง App ✨
+	// ✨ 2. ส่ง Config เข้าไปตอนสร้าง App ✨
 	app, err := firebase.NewApp(ctx, conf, opt)
 	if err != nil {
 		log.Printf("Error initializing Firebase app: %v\n", err)
 		return nil, err
 	}
+	return app, nil
+}
 
+// SetupFirestoreClient สร้าง Firestore client จาก Firebase App
+func SetupFirestoreClient(app *firebase.App) (*firestore.Client, error) {
+	ctx := context.Background()
 	client, err := app.Firestore(ctx)
 	if err != nil {
 		log.Printf("Error creating Firestore client: %v\n", err)
@@ -51,4 +48,14 @@ func SetupFirestoreClient() (*firestore.Client, error) {
 	
 	log.Println("Successfully connected to Firestore.")
 	return client, nil
+}
+
+// SetupAuthClient สร้าง Firebase Authentication client สำหรับตรวจสอบ ID Token
+func SetupAuthClient(app *firebase.App) (*auth.Client, error) {
+	client, err := app.Auth(context.Background())
+	if err != nil {
+		log.Printf("Error creating Firebase Auth client: %v\n", err)
+		return nil, err
+	}
+	return client, nil
 }
\ No newline at end of file
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SetupSQLDatabase เปิดการเชื่อมต่อฐานข้อมูล SQL ผ่าน GORM
+// driver เป็น "mysql" หรือ "sqlite" (SQLite ใช้ driver แบบ pure Go จึงไม่ต้องใช้ cgo)
+func SetupSQLDatabase(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case "mysql":
+		if dsn == "" {
+			return nil, fmt.Errorf("DB_DSN is required for mysql")
+		}
+		dialector = mysql.Open(dsn)
+	case "sqlite":
+		if dsn == "" {
+			dsn = "meerank.db"
+		}
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported SQL driver %q", driver)
+	}
+
+	// ไม่ต้อง log กรณีไม่พบข้อมูล เพราะ store แปลงเป็น store.ErrNotFound ให้ handler จัดการเอง
+	gormLogger := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  logger.Warn,
+		IgnoreRecordNotFoundError: true,
+	})
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: gormLogger})
+	if err != nil {
+		log.Printf("Error connecting to %s: %v\n", driver, err)
+		return nil, err
+	}
+
+	log.Printf("Successfully connected to %s.", driver)
+	return db, nil
+}
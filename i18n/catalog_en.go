@@ -0,0 +1,121 @@
+package i18n
+
+var english = Catalog{
+	Errors: map[string]string{
+		// ทั่วไป
+		"internal_error":      "Something went wrong, please try again",
+		"invalid_input":       "Invalid request body",
+		"invalid_parameter":   "Invalid value for '{parameter}'",
+		"validation_failed":   "Some fields are invalid",
+		"permission_denied":   "Permission denied",
+		"no_fields_to_update": "No fields to update",
+
+		// การยืนยันตัวตนและ session
+		"authorization_required":  "Please log in",
+		"invalid_token":           "Invalid or expired token",
+		"invalid_refresh_token":   "Invalid or expired refresh token",
+		"invalid_id_token":        "Invalid or expired ID token",
+		"session_revoked":         "Session has been revoked, please log in again",
+		"session_not_found":       "Session not found",
+		"account_suspended":       "Account is suspended",
+		"account_deleted":         "Account has been deleted",
+		"firebase_account_linked": "This phone number is already linked to another sign-in account",
+
+		// OTP และเบอร์โทรศัพท์
+		"invalid_otp":                     "Invalid OTP",
+		"otp_expired":                     "OTP has expired",
+		"otp_too_many_attempts":           "Too many attempts, please request a new OTP",
+		"otp_cooldown":                    "Please wait before requesting a new OTP",
+		"sms_failed":                      "Could not send OTP, please try again",
+		"phone_not_registered":            "Phone number not found",
+		"invalid_phone":                   "Invalid phone number",
+		"phone_taken":                     "Phone number already registered",
+		"phone_unchanged":                 "This is already your phone number",
+		"phone_change_needs_verification": "Phone number changes must be verified, use POST /profile/phone",
+
+		// ผู้ใช้ คะแนน และกิจกรรม
+		"user_not_found":         "User not found",
+		"insufficient_score":     "Not enough score",
+		"activity_unknown_type":  "Unknown activity type",
+		"activity_too_short":     "Activity duration must be at least one minute",
+		"activity_too_long":      "Activity is longer than the allowed maximum",
+		"activity_in_future":     "Activity must not end in the future",
+		"activity_overlap":       "Activity overlaps an existing activity",
+		"activity_rejected":      "Activity rejected",
+		"not_on_leaderboard":     "You are not on the leaderboard",
+		"no_activity_in_period":  "No activity in this period yet",
+		"season_not_found":       "Season not found",
+		"not_in_season":          "You did not take part in this season",
+		"friendship_not_found":   "Friendship not found",
+		"cannot_friend_self":     "You cannot add yourself as a friend",
+		"already_friends":        "You are already friends",
+		"friend_request_pending": "Friend request already sent",
+
+		// admin
+		"cannot_suspend_self":     "You cannot suspend your own account",
+		"cannot_delete_self":      "You cannot delete your own account",
+		"user_already_suspended":  "User already suspended",
+		"user_not_suspended":      "User is not suspended",
+		"user_deleted":            "User has been deleted",
+		"user_not_deleted":        "User is not deleted",
+		"restore_window_passed":   "Purge window has passed, user can no longer be restored",
+		"last_admin":              "Cannot remove the last admin",
+		"admin_must_be_demoted":   "Admins must be demoted before deleting their account",
+		"job_not_found":           "Job not found",
+		"job_not_undoable":        "Only a completed reset that is not a dry run can be undone",
+		"job_not_resumable":       "Only a failed job can be resumed",
+		"job_in_progress":         "Another reset job is still running, wait for it to finish",
+		"review_not_found":        "Review not found",
+		"review_already_resolved": "Review already resolved",
+		"season_overlap":          "Season overlaps an existing season",
+		"season_not_ended":        "Season has not ended yet",
+		"season_already_closed":   "Season already closed",
+
+		// Idempotency-Key
+		"idempotency_key_too_long":    "Idempotency-Key is too long",
+		"idempotency_key_reused":      "Idempotency-Key was already used for a different request",
+		"idempotency_in_progress":     "A request with this Idempotency-Key is still in progress",
+		"idempotency_original_failed": "The original request failed, please retry",
+	},
+
+	Fields: map[string]string{
+		"required":       "This field is required",
+		"too_short":      "Must be at least {param} characters",
+		"too_long":       "Must be at most {param} characters",
+		"too_small":      "Must be at least {param}",
+		"too_large":      "Must be at most {param}",
+		"invalid_choice": "Must be one of: {param}",
+		"invalid_phone":  "Invalid phone number",
+		"invalid_type":   "Must be a {param}",
+		"reserved":       "This value is reserved",
+		"must_be_after":  "Must be after {param}",
+		"invalid":        "Invalid value",
+	},
+
+	Messages: map[string]string{
+		MsgRegistered:         "Registration successful",
+		MsgOTPSent:            "If the phone number is registered, an OTP has been sent",
+		MsgLoginSuccessful:    "Login successful",
+		MsgLoggedOut:          "Logged out successfully",
+		MsgSessionRevoked:     "Session revoked successfully",
+		MsgCancelDeletionHint: "Log in again with cancel_deletion to keep this account",
+
+		MsgProfileUpdated:           "Profile updated successfully",
+		MsgNoFieldsToUpdate:         "No fields to update",
+		MsgPhoneChangeOTPSent:       "OTP sent to the new phone number",
+		MsgPhoneUpdated:             "Phone number updated successfully",
+		MsgAccountDeletionScheduled: "Account scheduled for deletion, log in with cancel_deletion before purge_at to keep it",
+
+		MsgActivityRecorded: "Score and minute updated successfully",
+		MsgTreeWatered:      "Tree watered successfully",
+
+		MsgFriendRequestSent:     "Friend request sent",
+		MsgFriendRequestAccepted: "Friend request accepted",
+		MsgFriendshipRemoved:     "Friendship removed",
+
+		MsgUserDeleted: "User deleted",
+
+		MsgOTPLoginSMS:       "Your MeeRank login code is {code}. It expires in {minutes} minutes.",
+		MsgOTPPhoneChangeSMS: "Your MeeRank code to change your phone number is {code}. It expires in {minutes} minutes.",
+	},
+}
@@ -0,0 +1,121 @@
+package i18n
+
+var thai = Catalog{
+	Errors: map[string]string{
+		// ทั่วไป
+		"internal_error":      "เกิดข้อผิดพลาด กรุณาลองใหม่อีกครั้ง",
+		"invalid_input":       "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+		"invalid_parameter":   "ค่าของ '{parameter}' ไม่ถูกต้อง",
+		"validation_failed":   "ข้อมูลบางช่องไม่ถูกต้อง",
+		"permission_denied":   "คุณไม่มีสิทธิ์ทำรายการนี้",
+		"no_fields_to_update": "ไม่มีข้อมูลที่ต้องแก้ไข",
+
+		// การยืนยันตัวตนและ session
+		"authorization_required":  "กรุณาเข้าสู่ระบบ",
+		"invalid_token":           "Token ไม่ถูกต้องหรือหมดอายุ",
+		"invalid_refresh_token":   "Refresh token ไม่ถูกต้องหรือหมดอายุ",
+		"invalid_id_token":        "ID token ไม่ถูกต้องหรือหมดอายุ",
+		"session_revoked":         "Session ถูกยกเลิกแล้ว กรุณาเข้าสู่ระบบใหม่",
+		"session_not_found":       "ไม่พบ session",
+		"account_suspended":       "บัญชีถูกระงับการใช้งาน",
+		"account_deleted":         "บัญชีถูกลบแล้ว",
+		"firebase_account_linked": "เบอร์นี้ผูกกับบัญชีล็อกอินอื่นอยู่แล้ว",
+
+		// OTP และเบอร์โทรศัพท์
+		"invalid_otp":                     "รหัส OTP ไม่ถูกต้อง",
+		"otp_expired":                     "รหัส OTP หมดอายุแล้ว",
+		"otp_too_many_attempts":           "กรอกรหัสผิดหลายครั้งเกินไป กรุณาขอรหัส OTP ใหม่",
+		"otp_cooldown":                    "กรุณารอสักครู่ก่อนขอรหัส OTP ใหม่",
+		"sms_failed":                      "ส่งรหัส OTP ไม่สำเร็จ กรุณาลองใหม่อีกครั้ง",
+		"phone_not_registered":            "ไม่พบเบอร์โทรศัพท์นี้",
+		"invalid_phone":                   "เบอร์โทรศัพท์ไม่ถูกต้อง",
+		"phone_taken":                     "เบอร์โทรศัพท์นี้ถูกใช้สมัครแล้ว",
+		"phone_unchanged":                 "นี่คือเบอร์โทรศัพท์ปัจจุบันของคุณอยู่แล้ว",
+		"phone_change_needs_verification": "การเปลี่ยนเบอร์โทรศัพท์ต้องยืนยันด้วย OTP ผ่าน POST /profile/phone",
+
+		// ผู้ใช้ คะแนน และกิจกรรม
+		"user_not_found":         "ไม่พบผู้ใช้",
+		"insufficient_score":     "คะแนนไม่พอ",
+		"activity_unknown_type":  "ไม่รู้จักประเภทกิจกรรมนี้",
+		"activity_too_short":     "กิจกรรมต้องมีระยะเวลาอย่างน้อย 1 นาที",
+		"activity_too_long":      "กิจกรรมยาวเกินกว่าที่กำหนด",
+		"activity_in_future":     "เวลาสิ้นสุดของกิจกรรมต้องไม่อยู่ในอนาคต",
+		"activity_overlap":       "กิจกรรมนี้ทับซ้อนกับกิจกรรมที่บันทึกไว้แล้ว",
+		"activity_rejected":      "กิจกรรมนี้ไม่ได้รับการบันทึก",
+		"not_on_leaderboard":     "คุณยังไม่อยู่ในตารางอันดับ",
+		"no_activity_in_period":  "ยังไม่มีกิจกรรมในช่วงเวลานี้",
+		"season_not_found":       "ไม่พบฤดูกาล",
+		"not_in_season":          "คุณไม่ได้ร่วมฤดูกาลนี้",
+		"friendship_not_found":   "ไม่พบรายชื่อเพื่อนนี้",
+		"cannot_friend_self":     "ไม่สามารถเพิ่มตัวเองเป็นเพื่อนได้",
+		"already_friends":        "คุณเป็นเพื่อนกันอยู่แล้ว",
+		"friend_request_pending": "ส่งคำขอเป็นเพื่อนไปแล้ว",
+
+		// admin
+		"cannot_suspend_self":     "ไม่สามารถระงับบัญชีของตัวเองได้",
+		"cannot_delete_self":      "ไม่สามารถลบบัญชีของตัวเองได้",
+		"user_already_suspended":  "ผู้ใช้ถูกระงับอยู่แล้ว",
+		"user_not_suspended":      "ผู้ใช้ไม่ได้ถูกระงับ",
+		"user_deleted":            "ผู้ใช้ถูกลบแล้ว",
+		"user_not_deleted":        "ผู้ใช้ไม่ได้ถูกลบ",
+		"restore_window_passed":   "เลยกำหนดลบถาวรแล้ว ไม่สามารถกู้คืนผู้ใช้ได้",
+		"last_admin":              "ไม่สามารถถอด admin คนสุดท้ายได้",
+		"admin_must_be_demoted":   "admin ต้องถูกลดสิทธิ์ก่อนจึงจะลบบัญชีได้",
+		"job_not_found":           "ไม่พบงาน",
+		"job_not_undoable":        "ย้อนกลับได้เฉพาะการรีเซ็ตที่เสร็จแล้วและไม่ใช่การทดลอง (dry run)",
+		"job_not_resumable":       "ทำต่อได้เฉพาะงานที่ล้มเหลว",
+		"job_in_progress":         "มีงานรีเซ็ตอื่นกำลังทำอยู่ กรุณารอให้เสร็จก่อน",
+		"review_not_found":        "ไม่พบรายการตรวจสอบ",
+		"review_already_resolved": "รายการตรวจสอบนี้ถูกตัดสินไปแล้ว",
+		"season_overlap":          "ฤดูกาลทับซ้อนกับฤดูกาลที่มีอยู่แล้ว",
+		"season_not_ended":        "ฤดูกาลยังไม่สิ้นสุด",
+		"season_already_closed":   "ฤดูกาลถูกปิดไปแล้ว",
+
+		// Idempotency-Key
+		"idempotency_key_too_long":    "Idempotency-Key ยาวเกินไป",
+		"idempotency_key_reused":      "Idempotency-Key นี้ถูกใช้กับคำขออื่นไปแล้ว",
+		"idempotency_in_progress":     "คำขอที่ใช้ Idempotency-Key นี้ยังทำงานอยู่",
+		"idempotency_original_failed": "คำขอเดิมไม่สำเร็จ กรุณาลองใหม่อีกครั้ง",
+	},
+
+	Fields: map[string]string{
+		"required":       "กรุณากรอกข้อมูลนี้",
+		"too_short":      "ต้องมีอย่างน้อย {param} ตัวอักษร",
+		"too_long":       "ต้องมีไม่เกิน {param} ตัวอักษร",
+		"too_small":      "ต้องมีค่าอย่างน้อย {param}",
+		"too_large":      "ต้องมีค่าไม่เกิน {param}",
+		"invalid_choice": "ต้องเป็นค่าใดค่าหนึ่งต่อไปนี้: {param}",
+		"invalid_phone":  "เบอร์โทรศัพท์ไม่ถูกต้อง",
+		"invalid_type":   "ชนิดข้อมูลไม่ถูกต้อง ต้องเป็น {param}",
+		"reserved":       "ไม่สามารถใช้ค่านี้ได้",
+		"must_be_after":  "ต้องอยู่หลัง {param}",
+		"invalid":        "ข้อมูลไม่ถูกต้อง",
+	},
+
+	Messages: map[string]string{
+		MsgRegistered:         "สมัครสมาชิกสำเร็จ",
+		MsgOTPSent:            "ถ้าเบอร์โทรศัพท์นี้ลงทะเบียนไว้ ระบบได้ส่งรหัส OTP ไปแล้ว",
+		MsgLoginSuccessful:    "เข้าสู่ระบบสำเร็จ",
+		MsgLoggedOut:          "ออกจากระบบแล้ว",
+		MsgSessionRevoked:     "ยกเลิก session แล้ว",
+		MsgCancelDeletionHint: "เข้าสู่ระบบอีกครั้งโดยส่ง cancel_deletion เพื่อเก็บบัญชีนี้ไว้",
+
+		MsgProfileUpdated:           "แก้ไขโปรไฟล์สำเร็จ",
+		MsgNoFieldsToUpdate:         "ไม่มีข้อมูลที่ต้องแก้ไข",
+		MsgPhoneChangeOTPSent:       "ส่งรหัส OTP ไปที่เบอร์โทรศัพท์ใหม่แล้ว",
+		MsgPhoneUpdated:             "เปลี่ยนเบอร์โทรศัพท์สำเร็จ",
+		MsgAccountDeletionScheduled: "ตั้งเวลาลบบัญชีแล้ว เข้าสู่ระบบโดยส่ง cancel_deletion ก่อน purge_at เพื่อเก็บบัญชีไว้",
+
+		MsgActivityRecorded: "บันทึกคะแนนและเวลาสำเร็จ",
+		MsgTreeWatered:      "รดน้ำต้นไม้สำเร็จ",
+
+		MsgFriendRequestSent:     "ส่งคำขอเป็นเพื่อนแล้ว",
+		MsgFriendRequestAccepted: "ตอบรับคำขอเป็นเพื่อนแล้ว",
+		MsgFriendshipRemoved:     "ลบเพื่อนแล้ว",
+
+		MsgUserDeleted: "ลบผู้ใช้แล้ว",
+
+		MsgOTPLoginSMS:       "รหัสเข้าสู่ระบบ MeeRank ของคุณคือ {code} หมดอายุใน {minutes} นาที",
+		MsgOTPPhoneChangeSMS: "รหัสยืนยันการเปลี่ยนเบอร์โทรศัพท์ MeeRank ของคุณคือ {code} หมดอายุใน {minutes} นาที",
+	},
+}
@@ -0,0 +1,4 @@
+package i18n
+
+// MessageKeys ให้ test ภายนอก package เข้าถึง messageKeys
+var MessageKeys = messageKeys
@@ -0,0 +1,201 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ภาษาที่รองรับ
+const (
+	Thai    = "th"
+	English = "en"
+
+	// Default คือภาษาที่ใช้เมื่อไม่รู้ภาษาของผู้ใช้ (ผู้ใช้ส่วนใหญ่เป็นคนไทย)
+	Default = Thai
+)
+
+// Locales คือภาษาที่รองรับทั้งหมด
+var Locales = []string{Thai, English}
+
+// Supported บอกว่ารองรับภาษานี้หรือไม่
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Catalog คือข้อความของภาษาหนึ่ง แยกตามชนิดของรหัส ข้อความแทนค่าได้ด้วย {ชื่อ}
+type Catalog struct {
+	// Errors ใช้รหัสของ apperror (Error.Code) ค่าใน details ที่เป็น string แทนได้ เช่น {parameter}
+	Errors map[string]string
+	// Fields ใช้รหัสของ validation (FieldError.Code) แทน {param} ได้
+	Fields map[string]string
+	// Messages คือข้อความของคำตอบที่สำเร็จและข้อความอื่นถึงผู้ใช้ (ดู keys.go)
+	Messages map[string]string
+}
+
+var catalogs = map[string]*Catalog{
+	Thai:    &thai,
+	English: &english,
+}
+
+// Locale คืนภาษาของคำขอ เรียงตามลำดับ:
+//  1. ภาษาที่ผู้ใช้ตั้งไว้ในโปรไฟล์ (AuthMiddleware ใส่ไว้ใน context ที่ "locale")
+//  2. Accept-Language
+//  3. Default
+func Locale(c *gin.Context) string {
+	if locale := c.GetString("locale"); Supported(locale) {
+		return locale
+	}
+	return FromAcceptLanguage(c.GetHeader("Accept-Language"))
+}
+
+// FromAcceptLanguage เลือกภาษาที่รองรับและมีน้ำหนัก (q) สูงสุดจาก header Accept-Language
+// เช่น "en-US,en;q=0.9,th;q=0.8" ได้ en ถ้าไม่มีภาษาที่รองรับเลยคืน Default
+func FromAcceptLanguage(header string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !Supported(primary) {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+// Error คืนข้อความของรหัส error ในภาษา locale (ไม่มีคำแปลคืน fallback)
+func Error(locale, code string, details any, fallback string) string {
+	msg, ok := lookup(locale, func(c *Catalog) map[string]string { return c.Errors }, code)
+	if !ok {
+		return fallback
+	}
+	params, _ := details.(gin.H)
+	return replace(msg, func(name string) (string, bool) {
+		value, ok := params[name].(string)
+		return value, ok
+	})
+}
+
+// Field คืนข้อความของรหัสข้อผิดพลาดราย field ในภาษา locale
+func Field(locale, code, param string) string {
+	msg, ok := lookup(locale, func(c *Catalog) map[string]string { return c.Fields }, code)
+	if !ok {
+		return code
+	}
+	return strings.ReplaceAll(msg, "{param}", param)
+}
+
+// Message คืนข้อความของ key ในภาษาของคำขอ แทน {ชื่อ} ด้วย args ที่ส่งมาเป็นคู่ ชื่อ, ค่า
+func Message(c *gin.Context, key string, args ...any) string {
+	return MessageIn(Locale(c), key, args...)
+}
+
+// MessageIn เหมือน Message แต่ระบุภาษาเอง
+func MessageIn(locale, key string, args ...any) string {
+	msg, ok := lookup(locale, func(c *Catalog) map[string]string { return c.Messages }, key)
+	if !ok {
+		return key
+	}
+	params := make(map[string]string, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		params[fmt.Sprint(args[i])] = fmt.Sprint(args[i+1])
+	}
+	return replace(msg, func(name string) (string, bool) {
+		value, ok := params[name]
+		return value, ok
+	})
+}
+
+// lookup หาข้อความในภาษา locale ถ้าไม่มีใช้ภาษา Default
+func lookup(locale string, section func(*Catalog) map[string]string, key string) (string, bool) {
+	if catalog, ok := catalogs[locale]; ok {
+		if msg, ok := section(catalog)[key]; ok {
+			return msg, true
+		}
+	}
+	msg, ok := section(catalogs[Default])[key]
+	return msg, ok
+}
+
+// replace แทน {ชื่อ} ในข้อความด้วยค่าที่ value คืนมา ชื่อที่ไม่มีค่าคงไว้ตามเดิม
+func replace(msg string, value func(name string) (string, bool)) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(msg, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(msg[start:], '}')
+		if end < 0 {
+			break
+		}
+		b.WriteString(msg[:start])
+		if v, ok := value(msg[start+1 : start+end]); ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(msg[start : start+end+1])
+		}
+		msg = msg[start+end+1:]
+	}
+	b.WriteString(msg)
+	return b.String()
+}
+
+// Validate ตรวจว่าทุกภาษามีข้อความของทุกรหัส (errorCodes จาก apperror.Codes, fieldCodes จาก validation.Codes
+// และ key ของข้อความใน keys.go) และไม่มีข้อความของรหัสที่ไม่มีอยู่แล้ว
+// เรียกตอนเริ่มโปรแกรม เพื่อไม่ให้ปล่อยรหัสใหม่ที่ยังไม่มีคำแปลออกไป
+func Validate(errorCodes, fieldCodes []string) error {
+	var problems []string
+	for _, locale := range Locales {
+		catalog, ok := catalogs[locale]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: catalog is missing", locale))
+			continue
+		}
+		problems = append(problems, checkSection(locale, "error", catalog.Errors, errorCodes)...)
+		problems = append(problems, checkSection(locale, "field", catalog.Fields, fieldCodes)...)
+		problems = append(problems, checkSection(locale, "message", catalog.Messages, messageKeys)...)
+	}
+	if len(problems) > 0 {
+		return errors.New("i18n: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func checkSection(locale, section string, messages map[string]string, keys []string) []string {
+	var problems []string
+	known := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		known[key] = true
+		if strings.TrimSpace(messages[key]) == "" {
+			problems = append(problems, fmt.Sprintf("%s: no %s message for %q", locale, section, key))
+		}
+	}
+	var unknown []string
+	for key := range messages {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fmt.Sprintf("%s: %s message for unknown code %q", locale, section, key))
+	}
+	return problems
+}
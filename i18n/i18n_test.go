@@ -0,0 +1,85 @@
+package i18n_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"meerank/apperror"
+	"meerank/i18n"
+	"meerank/validation"
+)
+
+func TestValidate(t *testing.T) {
+	if err := i18n.Validate(apperror.Codes, validation.Codes); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// รายการที่เขียนด้วยมือ (apperror.Codes, validation.Codes, messageKeys) ต้องมีค่าคงที่ครบทุกตัว
+// ไม่อย่างนั้นรหัสที่ตกหล่นจะผ่าน Validate ไปได้ทั้งที่ไม่มีคำแปล
+func TestCodeListsComplete(t *testing.T) {
+	tests := []struct {
+		file   string
+		prefix string
+		list   []string
+	}{
+		{"../apperror/codes.go", "Code", apperror.Codes},
+		{"../validation/validation.go", "Code", validation.Codes},
+		{"keys.go", "Msg", i18n.MessageKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			consts := stringConsts(t, tt.file, tt.prefix)
+			if len(consts) == 0 {
+				t.Fatalf("no %s* constants found", tt.prefix)
+			}
+			for name, value := range consts {
+				if !slices.Contains(tt.list, value) {
+					t.Errorf("%s (%q) is missing from the list passed to i18n.Validate", name, value)
+				}
+			}
+			if len(tt.list) != len(consts) {
+				t.Errorf("list has %d entries, want %d (one per %s* constant)", len(tt.list), len(consts), tt.prefix)
+			}
+		})
+	}
+}
+
+// stringConsts คืนค่าคงที่ชนิด string ในไฟล์ที่ชื่อขึ้นต้นด้วย prefix (ชื่อ -> ค่า)
+func stringConsts(t *testing.T, path, prefix string) map[string]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consts := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			for i, name := range value.Names {
+				if !strings.HasPrefix(name.Name, prefix) || i >= len(value.Values) {
+					continue
+				}
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				unquoted, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				consts[name.Name] = unquoted
+			}
+		}
+	}
+	return consts
+}
@@ -0,0 +1,64 @@
+package i18n
+
+// key ของข้อความในคำตอบที่สำเร็จ (ส่งใน "code" คู่กับ "message") และข้อความอื่นที่ส่งถึงผู้ใช้
+// ห้ามเปลี่ยนค่าที่ปล่อยไปแล้ว เพราะแอปใช้ตัดสินใจจากค่าเหล่านี้
+const (
+	// สมาชิกและการล็อกอิน
+	MsgRegistered         = "registered"
+	MsgOTPSent            = "otp_sent"
+	MsgLoginSuccessful    = "login_successful"
+	MsgLoggedOut          = "logged_out"
+	MsgSessionRevoked     = "session_revoked"
+	MsgCancelDeletionHint = "cancel_deletion_hint"
+
+	// โปรไฟล์และบัญชี
+	MsgProfileUpdated           = "profile_updated"
+	MsgNoFieldsToUpdate         = "no_fields_to_update"
+	MsgPhoneChangeOTPSent       = "phone_change_otp_sent"
+	MsgPhoneUpdated             = "phone_updated"
+	MsgAccountDeletionScheduled = "account_deletion_scheduled"
+
+	// กิจกรรมและต้นไม้
+	MsgActivityRecorded = "activity_recorded"
+	MsgTreeWatered      = "tree_watered"
+
+	// เพื่อน
+	MsgFriendRequestSent     = "friend_request_sent"
+	MsgFriendRequestAccepted = "friend_request_accepted"
+	MsgFriendshipRemoved     = "friendship_removed"
+
+	// admin
+	MsgUserDeleted = "user_deleted"
+
+	// SMS รหัส OTP แทน {code} และ {minutes}
+	MsgOTPLoginSMS       = "otp_login_sms"
+	MsgOTPPhoneChangeSMS = "otp_phone_change_sms"
+)
+
+// messageKeys คือ key ทั้งหมดข้างบน ใช้ใน Validate
+var messageKeys = []string{
+	MsgRegistered,
+	MsgOTPSent,
+	MsgLoginSuccessful,
+	MsgLoggedOut,
+	MsgSessionRevoked,
+	MsgCancelDeletionHint,
+
+	MsgProfileUpdated,
+	MsgNoFieldsToUpdate,
+	MsgPhoneChangeOTPSent,
+	MsgPhoneUpdated,
+	MsgAccountDeletionScheduled,
+
+	MsgActivityRecorded,
+	MsgTreeWatered,
+
+	MsgFriendRequestSent,
+	MsgFriendRequestAccepted,
+	MsgFriendshipRemoved,
+
+	MsgUserDeleted,
+
+	MsgOTPLoginSMS,
+	MsgOTPPhoneChangeSMS,
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"meerank/store"
+)
+
+// PurgeInterval คือความถี่ที่ Purger ตรวจหาผู้ใช้ที่ครบกำหนดลบถาวร
+const PurgeInterval = time.Hour
+
+// PurgeDeletedUsers ลบถาวรผู้ใช้ที่ถูก soft-delete และครบกำหนดแล้ว คืนจำนวนผู้ใช้ที่ถูกลบ
+// ผู้ใช้ที่ถูกกู้คืนระหว่างทางจะถูกข้าม
+func PurgeDeletedUsers(ctx context.Context, st store.Store, now time.Time) (int, error) {
+	purged := 0
+	for {
+		users, err := st.ListUsersToPurge(ctx, now, BatchSize)
+		if err != nil {
+			return purged, err
+		}
+		progressed := false
+		for _, user := range users {
+			err := st.PurgeUser(ctx, user.ID, now)
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return purged, err
+			}
+			purged++
+			progressed = true
+		}
+		if len(users) < BatchSize || !progressed {
+			return purged, nil
+		}
+	}
+}
+
+// RunPurger เรียก PurgeDeletedUsers ทุก interval จนกว่า ctx จะถูกยกเลิก ควรเรียกใน goroutine ตอนเริ่มเซิร์ฟเวอร์
+func RunPurger(ctx context.Context, st store.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := PurgeDeletedUsers(ctx, st, time.Now())
+		if err != nil {
+			log.Printf("Failed to purge deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"meerank/models"
+	"meerank/store"
+)
+
+// BatchSize คือจำนวนผู้ใช้ต่อหนึ่ง batch
+// ต้องไม่เกิน 249 เพราะ Firestore transaction เขียนได้ไม่เกิน 500 ครั้ง (ดู store.ResetStatsBatch)
+const BatchSize = 200
+
+var (
+	// ErrNotUndoable ถูกคืนเมื่อสั่ง undo งานที่ยังไม่เสร็จ เป็น dry run หรือ undo ไปแล้ว
+	ErrNotUndoable = errors.New("jobs: job cannot be undone")
+	// ErrNotResumable ถูกคืนเมื่อสั่ง resume งานที่ไม่ได้ล้มเหลว
+	ErrNotResumable = errors.New("jobs: job cannot be resumed")
+	// ErrInProgress ถูกคืนเมื่อสั่งรีเซ็ต, undo หรือ resume ขณะที่มีงานรีเซ็ตอื่นกำลังทำอยู่
+	ErrInProgress = errors.New("jobs: another reset job is in progress")
+)
+
+// Runner ทำงานเบื้องหลังของ admin ทีละ batch ผ่าน store
+// ความคืบหน้าถูกบันทึกพร้อมกับทุก batch จึงทำต่อได้หลังล้มเหลวหรือเซิร์ฟเวอร์ถูกปิดกลางทาง
+type Runner struct {
+	st store.Store
+
+	mu     sync.Mutex
+	active map[string]bool // งานที่กำลังทำอยู่ใน process นี้
+
+	// startMu ให้การตรวจงานที่ค้างอยู่และการเริ่มงานใหม่ใน process นี้ทำทีละคำขอ
+	startMu sync.Mutex
+}
+
+func NewRunner(st store.Store) *Runner {
+	return &Runner{st: st, active: map[string]bool{}}
+}
+
+// StartReset สร้างงานรีเซ็ตสถิติของผู้ใช้ทุกคนและเริ่มทำงานเบื้องหลัง
+// ถ้า dryRun เป็น true จะนับอย่างเดียวว่ามีใครและยอดเท่าไรที่จะถูกล้าง โดยไม่แก้ข้อมูล
+// คืน ErrInProgress ถ้ามีงานรีเซ็ตกำลังทำหรือกำลัง undo อยู่
+func (r *Runner) StartReset(ctx context.Context, createdBy string, dryRun bool) (*models.Job, error) {
+	r.startMu.Lock()
+	defer r.startMu.Unlock()
+
+	if err := r.checkNoActiveReset(ctx, ""); err != nil {
+		return nil, err
+	}
+	total, err := r.st.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.Job{
+		Type:      models.JobTypeResetStats,
+		Status:    models.JobStatusRunning,
+		DryRun:    dryRun,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Total:     total,
+	}
+	if err := r.st.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+	r.launch(job)
+	return job, nil
+}
+
+// Undo คืนสถิติที่งานรีเซ็ตล้างไปให้ผู้ใช้ทุกคน (เฉพาะงานที่ทำเสร็จแล้วและไม่ใช่ dry run)
+func (r *Runner) Undo(ctx context.Context, id string) (*models.Job, error) {
+	r.startMu.Lock()
+	defer r.startMu.Unlock()
+
+	if err := r.checkNoActiveReset(ctx, id); err != nil {
+		return nil, err
+	}
+	job, err := r.st.UpdateJob(ctx, id, func(job *models.Job) error {
+		if job.Type != models.JobTypeResetStats || job.DryRun || job.Status != models.JobStatusCompleted {
+			return ErrNotUndoable
+		}
+		job.Status = models.JobStatusUndoing
+		job.FinishedAt = nil
+		job.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.launch(job)
+	return job, nil
+}
+
+// Resume ทำงานที่ล้มเหลวต่อจาก batch สุดท้ายที่ commit แล้ว
+func (r *Runner) Resume(ctx context.Context, id string) (*models.Job, error) {
+	r.startMu.Lock()
+	defer r.startMu.Unlock()
+
+	if err := r.checkNoActiveReset(ctx, id); err != nil {
+		return nil, err
+	}
+	job, err := r.st.UpdateJob(ctx, id, func(job *models.Job) error {
+		switch job.Status {
+		case models.JobStatusFailed:
+			job.Status = models.JobStatusRunning
+		case models.JobStatusUndoFailed:
+			job.Status = models.JobStatusUndoing
+		default:
+			return ErrNotResumable
+		}
+		job.Error = ""
+		job.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.launch(job)
+	return job, nil
+}
+
+// checkNoActiveReset คืน ErrInProgress ถ้ามีงานรีเซ็ตอื่น (ไม่นับ exceptID) กำลังทำหรือกำลัง undo อยู่
+// สองงานที่ทำพร้อมกันจะเขียนทับสถิติและข้อมูลสำหรับ undo ของกันและกัน
+func (r *Runner) checkNoActiveReset(ctx context.Context, exceptID string) error {
+	jobs, err := r.st.ListJobsByStatus(ctx, models.JobStatusRunning, models.JobStatusUndoing)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Type == models.JobTypeResetStats && job.ID != exceptID {
+			return ErrInProgress
+		}
+	}
+	return nil
+}
+
+// ResumeInterrupted ทำงานที่ค้างอยู่ต่อ (เช่นเซิร์ฟเวอร์ถูกปิดระหว่างทำงาน) ควรเรียกตอนเริ่มเซิร์ฟเวอร์
+func (r *Runner) ResumeInterrupted(ctx context.Context) error {
+	jobs, err := r.st.ListJobsByStatus(ctx, models.JobStatusRunning, models.JobStatusUndoing)
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		log.Printf("Resuming interrupted job %s (%s)", jobs[i].ID, jobs[i].Status)
+		r.launch(&jobs[i])
+	}
+	return nil
+}
+
+// launch เริ่ม goroutine ของงาน (ถ้างานนี้ยังไม่ได้ทำอยู่ใน process นี้)
+func (r *Runner) launch(job *models.Job) {
+	r.mu.Lock()
+	if r.active[job.ID] {
+		r.mu.Unlock()
+		return
+	}
+	r.active[job.ID] = true
+	r.mu.Unlock()
+
+	id, undo := job.ID, job.Status == models.JobStatusUndoing
+	go func() {
+		ctx := context.Background()
+		err := r.run(ctx, id, undo)
+
+		r.mu.Lock()
+		delete(r.active, id)
+		r.mu.Unlock()
+
+		if err != nil {
+			log.Printf("Job %s failed: %v", id, err)
+			r.fail(ctx, id, err)
+		}
+	}()
+}
+
+// run ทำทีละ batch จนครบ แล้วเปลี่ยนสถานะเป็น completed (หรือ undone)
+func (r *Runner) run(ctx context.Context, id string, undo bool) error {
+	batch, finished := r.st.ResetStatsBatch, models.JobStatusCompleted
+	if undo {
+		batch, finished = r.st.RestoreStatsBatch, models.JobStatusUndone
+	}
+
+	for {
+		_, done, err := batch(ctx, id, BatchSize)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+
+	_, err := r.st.UpdateJob(ctx, id, func(job *models.Job) error {
+		now := time.Now()
+		job.Status = finished
+		job.FinishedAt = &now
+		job.UpdatedAt = now
+		return nil
+	})
+	return err
+}
+
+// fail บันทึกว่างานล้มเหลว (Cursor ยังชี้ batch สุดท้ายที่ commit แล้ว จึง resume ต่อได้)
+func (r *Runner) fail(ctx context.Context, id string, cause error) {
+	_, err := r.st.UpdateJob(ctx, id, func(job *models.Job) error {
+		switch job.Status {
+		case models.JobStatusRunning:
+			job.Status = models.JobStatusFailed
+		case models.JobStatusUndoing:
+			job.Status = models.JobStatusUndoFailed
+		}
+		job.Error = cause.Error()
+		job.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to mark job %s as failed: %v", id, err)
+	}
+}
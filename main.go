@@ -1,35 +1,200 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"meerank/apperror"
+	"meerank/auth"
+	"meerank/config"
 	"meerank/database"
+	"meerank/i18n"
+	"meerank/jobs"
+	"meerank/models"
+	"meerank/periods"
+	"meerank/permissions"
+	"meerank/phones"
 	"meerank/routers"
+	"meerank/scoring"
+	"meerank/sms"
+	"meerank/store"
+	"meerank/validation"
+	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// 1. เปลี่ยนไปเรียกใช้ฟังก์ชันเชื่อมต่อ Firestore
-	firestoreClient, err := database.SetupFirestoreClient()
+	// 0. ทุกรหัส error และข้อความต้องมีคำแปลครบทุกภาษา ไม่อย่างนั้นไม่ยอมเริ่มทำงาน
+	if err := i18n.Validate(apperror.Codes, validation.Codes); err != nil {
+		log.Fatalf("Incomplete message catalogs: %v", err)
+	}
+
+	// 1. โหลดค่าตั้งจากค่าเริ่มต้น, CONFIG_FILE, .env และ Environment Variable (ดู config.Load)
+	cfg, problems, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// 2. สร้าง component จากค่าตั้ง ปัญหาทุกข้อ (รวมถึงจาก config.Load) ถูกรวบรวมแล้วรายงานพร้อมกัน
+	// ก่อนเชื่อมต่อฐานข้อมูล
+	// ปฏิทินตัดรอบ leaderboard รายวัน/สัปดาห์/เดือน
+	cal, err := periods.NewCalendar(cfg.Leaderboard.Timezone)
+	if err != nil {
+		problems.Add("LEADERBOARD_TIMEZONE", "%v", err)
+	}
+	// ผู้ให้บริการส่ง SMS สำหรับรหัส OTP (ค่าเริ่มต้นพิมพ์ลง log)
+	smsSender, err := sms.NewSender(cfg.SMS.Provider)
+	if err != nil {
+		problems.Add("SMS_PROVIDER", "%v", err)
+	}
+	// กุญแจสำหรับเซ็น JWT (รองรับการหมุนกุญแจด้วย kid) ถ้าไม่ได้ตั้งไว้เลย config.Load รายงานไว้แล้ว
+	keys, err := auth.LoadKeyRing(cfg.Auth.JWT)
+	if err != nil && !problems.Has("JWT_SECRET") {
+		problems.Add("JWT_KEYS_FILE/JWT_KEYS/JWT_SECRET", "%v", err)
+	}
+	// กติกาการคิดคะแนนกิจกรรม ตัดรอบวันของเพดานรายวันตาม timezone เดียวกับ leaderboard
+	// (ถ้า timezone ใช้ไม่ได้ ตรวจไฟล์กติกากับ UTC ไปก่อน ปัญหาของ timezone ถูกรายงานไว้แล้ว)
+	loc := time.UTC
+	if cal != nil {
+		loc = cal.Location()
+	}
+	scorer, err := scoring.NewEngineFromFile(cfg.Scoring.RulesFile, loc)
 	if err != nil {
-		// ใช้ log.Fatalf จะแสดง error และหยุดการทำงานทันที
-		log.Fatalf("Failed to connect to Firestore: %v", err)
+		problems.Add("SCORING_RULES_FILE", "%v", err)
+	}
+	// สิทธิ์ของแต่ละ role
+	policy, err := permissions.NewPolicyFromFile(cfg.Permissions.File)
+	if err != nil {
+		problems.Add("ROLE_PERMISSIONS_FILE", "%v", err)
+	}
+	if cfg.Bootstrap.AdminPhone != "" {
+		if _, err := phones.Normalize(cfg.Bootstrap.AdminPhone); err != nil {
+			problems.Add("BOOTSTRAP_ADMIN_PHONE", "%v", err)
+		}
+	}
+	if err := problems.Err(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// 3. เชื่อมต่อฐานข้อมูลตาม DB_DRIVER
+	st, idTokenVerifier := setupStore(cfg.Database, cal)
+	// เพิ่ม defer เพื่อปิดการเชื่อมต่อเมื่อจบการทำงาน
+	defer st.Close()
+
+	// สร้าง admin คนแรกจาก BOOTSTRAP_ADMIN_PHONE ถ้ายังไม่มี admin ในระบบ
+	if err := bootstrapAdmin(context.Background(), st, cfg.Bootstrap); err != nil {
+		log.Fatalf("Failed to bootstrap admin: %v", err)
 	}
-	// 2. เพิ่ม defer เพื่อปิดการเชื่อมต่อเมื่อจบการทำงาน
-	defer firestoreClient.Close()
 
+	// งานเบื้องหลังของ admin (เช่นรีเซ็ตสถิติ) ที่ค้างอยู่จากการรันครั้งก่อนจะถูกทำต่อ
+	jobRunner := jobs.NewRunner(st)
+	if err := jobRunner.ResumeInterrupted(context.Background()); err != nil {
+		log.Printf("Failed to resume interrupted jobs: %v", err)
+	}
+	// ลบถาวรผู้ใช้ที่ถูก soft-delete เมื่อครบกำหนด
+	go jobs.RunPurger(context.Background(), st, jobs.PurgeInterval)
+
+	// 4. ส่ง store และค่าตั้งเข้าไปใน SetupRouter (รวมถึง CORS)
 	r := gin.Default()
+	routers.SetupRouter(r, cfg, st, smsSender, keys, idTokenVerifier, scorer, jobRunner, policy)
+
+	// รันเซิร์ฟเวอร์ที่ PORT (ค่าเริ่มต้น 8080)
+	if err := r.Run(cfg.Server.Addr()); err != nil {
+		log.Fatalf("Server stopped: %v", err)
+	}
+}
+
+// setupStore เลือก backend ของฐานข้อมูลตาม db.Driver (ดู config.Database)
+// คืน IDTokenVerifier เป็น nil ถ้า backend นั้นไม่ได้ใช้ Firebase
+func setupStore(db config.Database, cal *periods.Calendar) (store.Store, auth.IDTokenVerifier) {
+	switch driver := db.Driver; driver {
+	case config.DriverFirestore:
+		// ใช้ Firebase App เดียวกันทั้ง Firestore และ Firebase Authentication
+		firebaseApp, err := database.SetupFirebaseApp(db.Firebase)
+		if err != nil {
+			log.Fatalf("Failed to initialize Firebase: %v", err)
+		}
+		firestoreClient, err := database.SetupFirestoreClient(firebaseApp)
+		if err != nil {
+			// ใช้ log.Fatalf จะแสดง error และหยุดการทำงานทันที
+			log.Fatalf("Failed to connect to Firestore: %v", err)
+		}
+		authClient, err := database.SetupAuthClient(firebaseApp)
+		if err != nil {
+			log.Fatalf("Failed to set up Firebase Auth: %v", err)
+		}
+		firestoreStore := store.NewFirestoreStore(firestoreClient, cal)
+		if err := firestoreStore.Migrate(context.Background()); err != nil {
+			log.Fatalf("Failed to migrate Firestore data: %v", err)
+		}
+		return firestoreStore, auth.NewFirebaseVerifier(authClient)
+
+	case config.DriverMySQL, config.DriverSQLite:
+		gormDB, err := database.SetupSQLDatabase(driver, db.DSN)
+		if err != nil {
+			log.Fatalf("Failed to connect to %s: %v", driver, err)
+		}
+		sqlStore := store.NewSQLStore(gormDB, cal)
+		if err := sqlStore.Migrate(); err != nil {
+			log.Fatalf("Failed to migrate %s schema: %v", driver, err)
+		}
+		return sqlStore, nil
+
+	case config.DriverMemory:
+		log.Println("Warning: using in-memory store, all data will be lost on shutdown")
+		return store.NewMemoryStore(cal), nil
+
+	default:
+		log.Fatalf("Unknown DB_DRIVER %q (expected firestore, mysql, sqlite or memory)", driver)
+		return nil, nil
+	}
+}
 
-	// ✨ ส่วนของการตั้งค่า CORS ของคุณถูกต้องดีแล้ว ไม่ต้องแก้ไขครับ ✨
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true 
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	r.Use(cors.New(config))
+// bootstrapAdmin ตั้ง admin คนแรกของระบบจากค่าตั้ง (ทำเฉพาะตอนที่ยังไม่มี admin เลย)
+//   - AdminPhone เบอร์โทรของ admin (ว่าง = ไม่ทำอะไร) ถ้ามีผู้ใช้เบอร์นี้อยู่แล้วจะเลื่อนเป็น admin
+//     ถ้ายังไม่มีจะสร้างผู้ใช้ใหม่ ซึ่งล็อกอินด้วย OTP ได้ตามปกติ
+//   - AdminName ชื่อของผู้ใช้ที่สร้างใหม่ (ค่าเริ่มต้น Admin)
+func bootstrapAdmin(ctx context.Context, st store.Store, bootstrap config.Bootstrap) error {
+	phone := bootstrap.AdminPhone
+	if phone == "" {
+		return nil
+	}
+	phone, err := phones.Normalize(phone)
+	if err != nil {
+		return fmt.Errorf("invalid BOOTSTRAP_ADMIN_PHONE: %w", err)
+	}
 
-	// 3. ส่ง firestoreClient (ตัวใหม่) เข้าไปใน SetupRouter แทนที่ db (ตัวเก่า)
-	routers.SetupRouter(r, firestoreClient)
+	admins, err := st.SearchUsers(ctx, store.UserQuery{Role: models.RoleAdmin, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(admins.Users) > 0 {
+		return nil
+	}
+
+	user, err := st.FindUserByPhone(ctx, phone)
+	switch {
+	case err == nil:
+		if user.DeletedAt != nil {
+			return errors.New("user with BOOTSTRAP_ADMIN_PHONE has been deleted")
+		}
+		if _, err := st.ChangeUserRole(ctx, user.ID, models.RoleAdmin, func(*models.User, []models.User) error { return nil }); err != nil {
+			return err
+		}
+		log.Printf("Promoted user %s to admin from BOOTSTRAP_ADMIN_PHONE", user.ID)
+		return nil
 
-	// รันเซิร์ฟเวอร์ (แนะนำให้ระบุ port)
-	r.Run(":8080") 
-}
\ No newline at end of file
+	case errors.Is(err, store.ErrNotFound):
+		id, err := st.CreateUser(ctx, &models.User{Name: bootstrap.AdminName, Phone: &phone, Role: models.RoleAdmin})
+		if err != nil {
+			return err
+		}
+		log.Printf("Created admin %s from BOOTSTRAP_ADMIN_PHONE", id)
+		return nil
+
+	default:
+		return err
+	}
+}
@@ -1,27 +1,37 @@
 package middleware
 
 import (
-	// ✨ 1. Import handlers/member เพื่อใช้ Claims และ jwtKey จากที่เดียว ✨
+	"context"
+	"errors"
+	"log"
+	// ✨ 1. Import handlers/member เพื่อใช้ Claims จากที่เดียว ✨
 	handlers "meerank/Handler/member"
-	"net/http"
+	"meerank/apperror"
+	"meerank/auth"
+	"meerank/permissions"
+	"meerank/store"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// ❌ ไม่ต้องประกาศ jwtKey และ Claims struct ที่นี่แล้ว ❌
+// ❌ ไม่ต้องประกาศ Claims struct ที่นี่แล้ว ❌
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware ตรวจสอบ JWT ด้วย KeyRing
+// Token ที่เซ็นด้วยกุญแจเก่า (ที่ยังอยู่ใน ring และยังไม่หมดอายุ) ยังใช้งานได้ระหว่างการหมุนกุญแจ
+// Token ต้องผูกกับ session ที่ยังไม่ถูกเพิกถอน และบัญชีต้องไม่ถูกระงับหรือถูกลบ
+// สิทธิ์ (permissions) ไม่ได้ฝังใน Token แต่คำนวณจาก role ล่าสุดของผู้ใช้ด้วย policy ทุกครั้ง
+func AuthMiddleware(keys *auth.KeyRing, st store.Store, policy *permissions.Policy) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeAuthorizationRequired, "Authorization header is required"))
 			return
 		}
 
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid token format"))
 			return
 		}
 
@@ -29,20 +39,73 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// ✨ 2. ใช้ handlers.Claims ที่มี UserID เป็น string ✨
 		claims := &handlers.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// ✨ 3. ใช้ handlers.jwtKey ตัวเดียวกับตอนสร้าง Token ✨
-			return handlers.JwtKey, nil
-		})
+		// ✨ 3. เลือกกุญแจตาม kid ใน header ของ Token ✨
+		token, err := keys.Parse(tokenString, claims)
 
 		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid or expired token"))
 			return
 		}
 
-		// ✨ 4. ส่งต่อข้อมูลที่ถูกต้องไปให้ Handler ตัวถัดไป ✨
+		// ✨ 4. ตรวจสอบว่า session ของ Token ยังไม่ถูกเพิกถอน (เช่น logout หรือสั่งปิดจากอุปกรณ์อื่น) ✨
+		if claims.SessionID == "" {
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid or expired token"))
+			return
+		}
+		session, err := st.GetSession(context.Background(), claims.SessionID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				apperror.Abort(c, apperror.Unauthorized(apperror.CodeSessionRevoked, "Session has been revoked"))
+				return
+			}
+			log.Printf("Failed to load session %s: %v", claims.SessionID, err)
+			apperror.Abort(c, apperror.Internal("Database error"))
+			return
+		}
+		if session.UserID != claims.UserID || !session.Active(time.Now()) {
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeSessionRevoked, "Session has been revoked"))
+			return
+		}
+
+		// ✨ 5. ตรวจสอบว่าบัญชียังไม่ถูกระงับหรือถูกลบ (มีผลทันทีกับ Token ที่ออกไปแล้ว) ✨
+		user, err := st.GetUser(context.Background(), claims.UserID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				apperror.Abort(c, apperror.Unauthorized(apperror.CodeUserNotFound, "User not found"))
+				return
+			}
+			log.Printf("Failed to load user %s: %v", claims.UserID, err)
+			apperror.Abort(c, apperror.Internal("Database error"))
+			return
+		}
+		if inactive := handlers.InactiveAccountError(user); inactive != nil {
+			apperror.Abort(c, inactive)
+			return
+		}
+
+		// ✨ 6. ส่งต่อข้อมูลที่ถูกต้องไปให้ Handler ตัวถัดไป ✨
 		c.Set("uid", claims.UserID)
-		c.Set("role", claims.Role) // <-- เพิ่มการส่ง role ไปด้วย
+		c.Set("role", user.Role) // <-- ใช้ role ล่าสุดจากฐานข้อมูล การเปลี่ยน role จึงมีผลทันที
+		c.Set("sid", claims.SessionID)
+		c.Set("permissions", policy.Permissions(user.Role))
+		if user.Locale != "" {
+			c.Set("locale", user.Locale) // <-- ภาษาที่ผู้ใช้ตั้งไว้มาก่อน Accept-Language (ดู i18n.Locale)
+		}
 
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware ใช้กับ endpoint สาธารณะที่แสดงข้อมูลมากขึ้นเมื่อผู้เรียกล็อกอินอยู่
+// ถ้าไม่มี Authorization header จะผ่านไปโดยไม่มี uid ใน context
+// ถ้ามี header จะตรวจเหมือน AuthMiddleware ทุกอย่าง (Token ที่ไม่ถูกต้องถูกปฏิเสธ ไม่ถูกเมินเฉย)
+func OptionalAuthMiddleware(keys *auth.KeyRing, st store.Store, policy *permissions.Policy) gin.HandlerFunc {
+	required := AuthMiddleware(keys, st, policy)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		required(c)
+	}
+}
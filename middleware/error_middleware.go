@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log"
+
+	"meerank/apperror"
+	"meerank/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMiddleware เขียนคำตอบของ error ที่ handler หรือ middleware ตัวอื่นส่งมาด้วย apperror.Abort
+// ในรูปแบบเดียวกันทุก endpoint: {code, message, details, request_id}
+// ต้องใช้ก่อน middleware ตัวอื่น (ต่อจาก RequestIDMiddleware) เพื่อให้ครอบทุกคำขอ
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		writeError(c)
+	}
+}
+
+// writeError เขียน error ล่าสุดของคำขอ ถ้ายังไม่มีคำตอบถูกเขียนออกไป
+// error ที่ไม่ใช่ *apperror.Error ถูก log ไว้และตอบเป็น internal_error โดยไม่เปิดเผยสาเหตุ
+// ข้อความเป็นภาษาของผู้ใช้ตามรหัส (ดู i18n.Locale) ส่วน Message ของ error ใช้เมื่อรหัสนั้นไม่มีคำแปล
+func writeError(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	err := c.Errors.Last().Err
+	appErr, ok := apperror.As(err)
+	if !ok {
+		log.Printf("Unhandled error on %s %s: %v", c.Request.Method, c.FullPath(), err)
+		appErr = apperror.Internal("Internal server error")
+	}
+	c.JSON(appErr.Status(), apperror.Response{
+		Code:      appErr.Code,
+		Message:   i18n.Error(i18n.Locale(c), appErr.Code, appErr.Details, appErr.Message),
+		Details:   appErr.Details,
+		RequestID: c.GetString("request_id"),
+	})
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"meerank/apperror"
+	"meerank/models"
+	"meerank/store"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader คือ header ที่ client ใช้ระบุคำขอซ้ำ
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader ถูกใส่ในคำตอบที่ตอบซ้ำจากผลลัพธ์เดิม
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyWaitTimeout คือเวลาที่คำขอซ้ำรอให้คำขอแรกทำเสร็จ ก่อนตอบ 409
+	idempotencyWaitTimeout = 10 * time.Second
+	idempotencyPollEvery   = 100 * time.Millisecond
+)
+
+// IdempotencyMiddleware ทำให้คำขอที่แก้ไขข้อมูล (POST/PUT/PATCH/DELETE) ที่มี Idempotency-Key
+// ถูกประมวลผลเพียงครั้งเดียวต่อ user + key ภายใน 24 ชั่วโมง
+//   - คำขอแรกจองกุญแจไว้ แล้วเก็บผลลัพธ์ (ยกเว้น 5xx ซึ่งจะปล่อยให้ retry ใหม่ได้)
+//   - คำขอซ้ำได้ผลลัพธ์เดิมกลับไปพร้อม header Idempotent-Replayed: true
+//   - คำขอซ้ำที่มาระหว่างคำขอแรกยังทำไม่เสร็จจะรอจนเสร็จ แทนที่จะถูกทำซ้ำ
+//
+// ต้องใช้หลัง AuthMiddleware เพราะผูกกุญแจกับ uid
+func IdempotencyMiddleware(st store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			apperror.Abort(c, apperror.BadRequest(apperror.CodeIdempotencyKeyTooLong, "Idempotency-Key is too long"))
+			return
+		}
+
+		uid := c.GetString("uid")
+		if uid == "" {
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "User ID not found in token"))
+			return
+		}
+
+		// 1. อ่าน body มาทำ hash แล้วใส่คืน เพื่อให้ handler อ่านได้ตามปกติ
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apperror.Abort(c, apperror.BadRequest(apperror.CodeInvalidInput, "Invalid request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		record := &models.IdempotencyRecord{
+			ID:          idempotencyRecordID(uid, key),
+			UserID:      uid,
+			Key:         key,
+			RequestHash: requestHash(c.Request.Method, c.Request.URL.Path, body),
+			Status:      models.IdempotencyInProgress,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(models.IdempotencyLockTTL),
+		}
+
+		ctx := context.Background()
+
+		// 2. จองกุญแจ ถ้ามีคนจองไว้แล้วให้ตอบจากผลลัพธ์เดิม
+		existing, err := st.BeginIdempotentRequest(ctx, record, now)
+		if err != nil {
+			log.Printf("Failed to reserve idempotency key: %v", err)
+			apperror.Abort(c, apperror.Internal("Database error"))
+			return
+		}
+		if existing != nil {
+			replayIdempotent(c, st, existing, record.RequestHash)
+			return
+		}
+
+		// 3. ทำงานจริงพร้อมเก็บคำตอบไว้
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			// handler panic หรือ error ฝั่งเซิร์ฟเวอร์: ปล่อยกุญแจเพื่อให้ retry ทำงานใหม่ได้
+			if !completed {
+				if err := st.DeleteIdempotentRequest(ctx, record.ID); err != nil {
+					log.Printf("Failed to release idempotency key: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+		// error ที่ handler ส่งให้ ErrorMiddleware ต้องถูกเขียนตรงนี้ เพื่อให้เก็บไว้ตอบซ้ำได้
+		writeError(c)
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		// 4. บันทึกผลลัพธ์ไว้ตอบซ้ำ 24 ชั่วโมง
+		record.Status = models.IdempotencyCompleted
+		record.ResponseStatus = c.Writer.Status()
+		record.ResponseContentType = c.Writer.Header().Get("Content-Type")
+		record.ResponseBody = recorder.body.Bytes()
+		record.ExpiresAt = time.Now().Add(models.IdempotencyTTL)
+		if err := st.CompleteIdempotentRequest(ctx, record); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replayIdempotent ตอบคำขอซ้ำ (รอถ้าคำขอแรกยังทำไม่เสร็จ)
+func replayIdempotent(c *gin.Context, st store.Store, record *models.IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		apperror.Abort(c, apperror.New(apperror.KindValidation, apperror.CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request"))
+		return
+	}
+
+	deadline := time.Now().Add(idempotencyWaitTimeout)
+	for record.Status != models.IdempotencyCompleted {
+		if time.Now().After(deadline) {
+			apperror.Abort(c, apperror.Conflict(apperror.CodeIdempotencyInProgress, "A request with this Idempotency-Key is still in progress"))
+			return
+		}
+		time.Sleep(idempotencyPollEvery)
+
+		current, err := st.GetIdempotentRequest(context.Background(), record.ID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				// คำขอแรกล้มเหลวและปล่อยกุญแจแล้ว ให้ client ลองใหม่
+				apperror.Abort(c, apperror.Conflict(apperror.CodeIdempotencyOriginalFailed, "The original request failed, please retry"))
+				return
+			}
+			log.Printf("Failed to load idempotency key: %v", err)
+			apperror.Abort(c, apperror.Internal("Database error"))
+			return
+		}
+		record = current
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(record.ResponseStatus, record.ResponseContentType, record.ResponseBody)
+	c.Abort()
+}
+
+// idempotencyRecordID ใช้ hash เป็น ID เพื่อไม่ให้ key จาก client มีอักขระต้องห้ามของ Firestore
+func idempotencyRecordID(uid, key string) string {
+	sum := sha256.Sum256([]byte(uid + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestHash ใช้ตรวจว่า key เดิมถูกใช้กับคำขอเดิมจริง
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder เก็บสำเนาของ body ที่เขียนออกไป
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
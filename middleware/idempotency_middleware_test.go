@@ -0,0 +1,185 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"meerank/apperror"
+	"meerank/middleware"
+	"meerank/periods"
+	"meerank/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyTest คือ router ที่มี IdempotencyMiddleware ครอบ handler ซึ่งนับจำนวนครั้งที่ถูกเรียก
+// uid อ่านจาก header X-Test-UID แทน AuthMiddleware
+type idempotencyTest struct {
+	router *gin.Engine
+	calls  atomic.Int32
+	// handle คือการทำงานของ handler (ค่าเริ่มต้นตอบ 201 พร้อมลำดับการเรียก)
+	handle func(c *gin.Context, call int32)
+}
+
+func newIdempotencyTest(t *testing.T) *idempotencyTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cal, err := periods.NewCalendar("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemoryStore(cal)
+
+	tt := &idempotencyTest{router: gin.New()}
+	tt.handle = func(c *gin.Context, call int32) {
+		c.JSON(http.StatusCreated, gin.H{"call": call})
+	}
+	tt.router.Use(middleware.ErrorMiddleware())
+	tt.router.Use(func(c *gin.Context) { c.Set("uid", c.GetHeader("X-Test-UID")) })
+	tt.router.Use(middleware.IdempotencyMiddleware(st))
+	tt.router.POST("/profile/tree/water", func(c *gin.Context) {
+		tt.handle(c, tt.calls.Add(1))
+	})
+	return tt
+}
+
+func (tt *idempotencyTest) post(uid, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/profile/tree/water", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-UID", uid)
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	tt.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	tt := newIdempotencyTest(t)
+
+	first := tt.post("alice", "key-1", `{"amount":100}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first = %d %s, want 201", first.Code, first.Body)
+	}
+	if first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Error("first response is marked as replayed")
+	}
+
+	second := tt.post("alice", "key-1", `{"amount":100}`)
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+		t.Error("replay is not marked as replayed")
+	}
+	if got := second.Header().Get("Content-Type"); got != first.Header().Get("Content-Type") {
+		t.Errorf("replay content type = %q, want %q", got, first.Header().Get("Content-Type"))
+	}
+	if got := tt.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+
+	// key เดียวกันของผู้ใช้อื่น และคำขอที่ไม่มี key ไม่ถูกตอบซ้ำ
+	if rec := tt.post("bob", "key-1", `{"amount":100}`); rec.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Error("another user's request was replayed")
+	}
+	tt.post("alice", "", `{"amount":100}`)
+	if got := tt.calls.Load(); got != 3 {
+		t.Errorf("handler ran %d times, want 3", got)
+	}
+}
+
+func TestIdempotencyRejectsDifferentRequest(t *testing.T) {
+	tt := newIdempotencyTest(t)
+
+	if rec := tt.post("alice", "key-1", `{"amount":100}`); rec.Code != http.StatusCreated {
+		t.Fatalf("first = %d %s, want 201", rec.Code, rec.Body)
+	}
+	rec := tt.post("alice", "key-1", `{"amount":900}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), apperror.CodeIdempotencyKeyReused) {
+		t.Errorf("different body = %d %s, want 422 %s", rec.Code, rec.Body, apperror.CodeIdempotencyKeyReused)
+	}
+	if got := tt.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	tt := newIdempotencyTest(t)
+	tt.handle = func(c *gin.Context, call int32) {
+		if call == 1 {
+			apperror.Abort(c, apperror.Internal("Database error"))
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": call})
+	}
+
+	if rec := tt.post("alice", "key-1", `{"amount":100}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("first = %d %s, want 500", rec.Code, rec.Body)
+	}
+	// retry ต้องทำงานใหม่ ไม่ใช่ได้ 500 เดิมกลับไป
+	rec := tt.post("alice", "key-1", `{"amount":100}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Errorf("retry = %d %s, want a fresh 201", rec.Code, rec.Body)
+	}
+	if got := tt.calls.Load(); got != 2 {
+		t.Errorf("handler ran %d times, want 2", got)
+	}
+}
+
+func TestIdempotencySerializesConcurrentDuplicates(t *testing.T) {
+	tt := newIdempotencyTest(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tt.handle = func(c *gin.Context, call int32) {
+		if call == 1 {
+			close(started)
+		}
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"call": call})
+	}
+
+	const duplicates = 8
+	responses := make([]*httptest.ResponseRecorder, duplicates)
+	var wg sync.WaitGroup
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = tt.post("alice", "key-1", `{"amount":100}`)
+		}()
+	}
+
+	// ปล่อยคำขอแรกหลังคำขอซ้ำมีเวลาเข้ามารอ
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler never ran")
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := tt.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+	replayed := 0
+	for _, rec := range responses {
+		if rec.Code != http.StatusCreated || rec.Body.String() != `{"call":1}` {
+			t.Errorf("response = %d %s, want 201 {\"call\":1}", rec.Code, rec.Body)
+		}
+		if rec.Header().Get(middleware.IdempotentReplayedHeader) == "true" {
+			replayed++
+		}
+	}
+	if replayed != duplicates-1 {
+		t.Errorf("%d responses replayed, want %d", replayed, duplicates-1)
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"slices"
+
+	"meerank/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission สร้าง Middleware ที่ต้องการสิทธิ์ครบทุกข้อที่กำหนด (ดู permissions.Policy)
+// ต้องใช้ต่อจาก AuthMiddleware ซึ่งเป็นผู้ใส่สิทธิ์ของผู้ใช้ไว้ใน context
+func RequirePermission(required ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// ดึงสิทธิ์ที่ถูกตั้งค่าไว้โดย AuthMiddleware
+		value, exists := c.Get("permissions")
+		if !exists {
+			apperror.Abort(c, apperror.Unauthorized(apperror.CodeInvalidToken, "Permissions not found in token"))
+			return
+		}
+
+		granted, _ := value.([]string)
+		for _, perm := range required {
+			if !slices.Contains(granted, perm) {
+				apperror.Abort(c, apperror.Forbidden(apperror.CodePermissionDenied, "Permission denied"))
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader คือ header ที่ใช้ส่ง request id (client ส่งมาเองได้ ไม่งั้นเซิร์ฟเวอร์สร้างให้)
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 64
+)
+
+// RequestIDMiddleware ใส่ request id ให้ทุกคำขอ (ใน context ที่ "request_id" และใน header ของคำตอบ)
+// ใช้ผูก log และ audit log เข้ากับคำขอ
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err == nil {
+				id = hex.EncodeToString(buf)
+			}
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
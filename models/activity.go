@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Activity คือบันทึกการออกกำลังกายหนึ่งครั้ง
+// ใน Firestore เก็บใน subcollection users/{uid}/activities
+type Activity struct {
+	ID              string    `firestore:"-" json:"id" gorm:"primaryKey;size:64"`
+	UserID          string    `firestore:"user_id" json:"-" gorm:"size:64;not null;index:idx_activities_user_started,priority:1"`
+	Type            string    `firestore:"type" json:"type" gorm:"size:32;not null"`
+	StartedAt       time.Time `firestore:"started_at" json:"started_at" gorm:"not null;index:idx_activities_user_started,priority:2"`
+	EndedAt         time.Time `firestore:"ended_at" json:"ended_at" gorm:"not null"`
+	DurationMinutes int       `firestore:"duration_minutes" json:"duration_minutes" gorm:"not null"`
+	ScoreAwarded    int       `firestore:"score_awarded" json:"score_awarded" gorm:"not null"`
+	SourceDevice    string    `firestore:"source_device" json:"source_device" gorm:"size:255"`
+	CreatedAt       time.Time `firestore:"created_at" json:"created_at"`
+
+	// Flags คือเหตุผลที่ระบบคิดคะแนนส่งกิจกรรมนี้เข้าคิวตรวจสอบ
+	Flags []string `firestore:"flags,omitempty" json:"flags,omitempty" gorm:"type:text;serializer:json"`
+	// ReviewStatus ว่างถ้าไม่ต้องตรวจสอบ มิฉะนั้นเป็นสถานะของ ActivityReview
+	ReviewStatus string `firestore:"review_status,omitempty" json:"review_status,omitempty" gorm:"size:16"`
+}
+
+func (Activity) TableName() string { return SubcollectionActivities }
+
+const (
+	SubcollectionActivities = "activities"
+)
+
+// ประเภทกิจกรรมเริ่มต้น เมื่อ client ไม่ได้ระบุ
+const (
+	ActivityTypeExercise = "exercise"
+)
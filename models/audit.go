@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// AuditEntry คือบันทึกการกระทำของ admin หรือเหตุการณ์ด้านความปลอดภัยหนึ่งครั้ง
+// เพิ่มได้อย่างเดียว ห้ามแก้หรือลบ (รวมถึงตอนลบผู้ใช้ถาวร) เพื่อใช้ตรวจสอบย้อนหลังตาม PDPA
+type AuditEntry struct {
+	ID         string `firestore:"-" json:"id" gorm:"primaryKey;size:64"`
+	ActorID    string `firestore:"actor_id" json:"actor_id" gorm:"size:64;index:idx_audit_log_actor_created,priority:1"`
+	ActorRole  string `firestore:"actor_role" json:"actor_role" gorm:"size:32"`
+	Action     string `firestore:"action" json:"action" gorm:"size:64;not null;index:idx_audit_log_action_created,priority:1"`
+	TargetType string `firestore:"target_type,omitempty" json:"target_type,omitempty" gorm:"size:32"`
+	TargetID   string `firestore:"target_id,omitempty" json:"target_id,omitempty" gorm:"size:64;index:idx_audit_log_target_created,priority:1"`
+	Reason     string `firestore:"reason,omitempty" json:"reason,omitempty" gorm:"size:500"`
+	// Changes คือ field ที่เปลี่ยน (ค่าก่อนและหลัง) ของการแก้ไข ไม่เก็บค่าของข้อมูลส่วนบุคคล (ดู audit.Changes)
+	Changes []AuditChange `firestore:"changes,omitempty" json:"changes,omitempty" gorm:"type:text;serializer:json"`
+	// Metadata คือรายละเอียดอื่นของการกระทำ เช่น query ที่ใช้ค้นหา หรือ ID ของงานที่สร้าง
+	Metadata  map[string]string `firestore:"metadata,omitempty" json:"metadata,omitempty" gorm:"type:text;serializer:json"`
+	IP        string            `firestore:"ip" json:"ip" gorm:"size:64"`
+	UserAgent string            `firestore:"user_agent" json:"user_agent" gorm:"size:512"`
+	RequestID string            `firestore:"request_id" json:"request_id" gorm:"size:64"`
+	CreatedAt time.Time         `firestore:"created_at" json:"created_at" gorm:"index;index:idx_audit_log_actor_created,priority:2;index:idx_audit_log_action_created,priority:2;index:idx_audit_log_target_created,priority:2"`
+}
+
+func (AuditEntry) TableName() string { return CollectionAuditLog }
+
+// AuditChange คือค่าก่อนและหลังของ field หนึ่ง
+type AuditChange struct {
+	Field  string      `firestore:"field" json:"field"`
+	Before interface{} `firestore:"before" json:"before"`
+	After  interface{} `firestore:"after" json:"after"`
+}
+
+const (
+	CollectionAuditLog = "audit_log"
+)
+
+// ประเภทของสิ่งที่ถูกกระทำ (AuditEntry.TargetType)
+const (
+	AuditTargetUser    = "user"
+	AuditTargetSession = "session"
+	AuditTargetJob     = "job"
+	AuditTargetReview  = "review"
+	AuditTargetSeason  = "season"
+)
+
+// การกระทำที่ถูกบันทึก (AuditEntry.Action)
+const (
+	// การยืนยันตัวตน
+	AuditActionLogin              = "auth.login"
+	AuditActionLoginBlocked       = "auth.login_blocked"
+	AuditActionLogout             = "auth.logout"
+	AuditActionRefreshTokenReused = "auth.refresh_token_reused"
+	AuditActionSessionRevoke      = "session.revoke"
+
+	// ผู้ใช้จัดการข้อมูลและบัญชีของตัวเอง
+	AuditActionProfileUpdate         = "profile.update"
+	AuditActionPhoneChange           = "profile.phone_change"
+	AuditActionProfileExport         = "profile.export"
+	AuditActionAccountDelete         = "account.delete"
+	AuditActionAccountDeletionCancel = "account.deletion_cancel"
+
+	// admin
+	AuditActionUserSearch     = "user.search"
+	AuditActionUserView       = "user.view"
+	AuditActionUserUpdate     = "user.update"
+	AuditActionUserSuspend    = "user.suspend"
+	AuditActionUserUnsuspend  = "user.unsuspend"
+	AuditActionUserDelete     = "user.delete"
+	AuditActionUserRestore    = "user.restore"
+	AuditActionUserRoleChange = "user.role_change"
+	AuditActionStatsReset     = "stats.reset"
+	AuditActionJobView        = "job.view"
+	AuditActionJobUndo        = "job.undo"
+	AuditActionJobResume      = "job.resume"
+	AuditActionReviewList     = "review.list"
+	AuditActionReviewResolve  = "review.resolve"
+	AuditActionSeasonCreate   = "season.create"
+	AuditActionSeasonClose    = "season.close"
+	AuditActionAuditView      = "audit.view"
+)
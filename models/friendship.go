@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Friendship คือความเป็นเพื่อนของผู้ใช้สองคน (หนึ่งเอกสารต่อคู่ ไม่ว่าใครเป็นคนขอ)
+// เริ่มจาก pending เมื่อมีคนส่งคำขอ และเป็น accepted เมื่ออีกฝ่ายตอบรับ
+type Friendship struct {
+	// ID คือ FriendshipID ของคู่ผู้ใช้
+	ID string `firestore:"-" json:"-" gorm:"primaryKey;size:160"`
+	// UserA และ UserB คือ ID ของทั้งสองคน เรียงจากน้อยไปมาก
+	UserA       string     `firestore:"user_a" json:"-" gorm:"size:64;not null;index"`
+	UserB       string     `firestore:"user_b" json:"-" gorm:"size:64;not null;index"`
+	RequestedBy string     `firestore:"requested_by" json:"requested_by" gorm:"size:64;not null"`
+	Status      string     `firestore:"status" json:"status" gorm:"size:16;not null"`
+	CreatedAt   time.Time  `firestore:"created_at" json:"created_at"`
+	AcceptedAt  *time.Time `firestore:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+}
+
+func (Friendship) TableName() string { return CollectionFriendships }
+
+const (
+	CollectionFriendships = "friendships"
+)
+
+// สถานะของ Friendship
+const (
+	FriendshipPending  = "pending"
+	FriendshipAccepted = "accepted"
+)
+
+// NewFriendship สร้างคำขอเป็นเพื่อนจาก requester ถึง other
+func NewFriendship(requester, other string, now time.Time) *Friendship {
+	a, b := orderedPair(requester, other)
+	return &Friendship{
+		ID:          FriendshipID(requester, other),
+		UserA:       a,
+		UserB:       b,
+		RequestedBy: requester,
+		Status:      FriendshipPending,
+		CreatedAt:   now,
+	}
+}
+
+// FriendshipID คือ ID ของ Friendship ระหว่างผู้ใช้สองคน (ได้ค่าเดียวกันไม่ว่าจะสลับลำดับหรือไม่)
+func FriendshipID(userID, otherID string) string {
+	a, b := orderedPair(userID, otherID)
+	return a + "_" + b
+}
+
+// Other คืน ID ของอีกฝ่ายในความสัมพันธ์
+func (f *Friendship) Other(userID string) string {
+	if f.UserA == userID {
+		return f.UserB
+	}
+	return f.UserA
+}
+
+func orderedPair(x, y string) (string, string) {
+	if x < y {
+		return x, y
+	}
+	return y, x
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// IdempotencyRecord เก็บผลลัพธ์ของคำขอแรกต่อ user + Idempotency-Key
+// เพื่อตอบซ้ำเมื่อ client ส่งคำขอเดิมมาอีก (เช่น retry เพราะเน็ตหลุด)
+type IdempotencyRecord struct {
+	// ID คือ hash ของ user ID + key (ดู middleware.IdempotencyMiddleware)
+	ID          string `firestore:"-" json:"id" gorm:"primaryKey;size:64"`
+	UserID      string `firestore:"user_id" json:"user_id" gorm:"size:64;not null"`
+	Key         string `firestore:"key" json:"key" gorm:"size:255;not null"`
+	RequestHash string `firestore:"request_hash" json:"request_hash" gorm:"size:64;not null"`
+	Status      string `firestore:"status" json:"status" gorm:"size:16;not null"`
+
+	ResponseStatus      int    `firestore:"response_status,omitempty" json:"response_status,omitempty"`
+	ResponseContentType string `firestore:"response_content_type,omitempty" json:"response_content_type,omitempty" gorm:"size:255"`
+	ResponseBody        []byte `firestore:"response_body,omitempty" json:"-"`
+
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+	// ExpiresAt ระหว่างประมวลผลคือเวลาที่ล็อกหมดอายุ เมื่อเสร็จแล้วคือเวลาที่เลิกตอบซ้ำ
+	// (ตั้ง TTL policy ของ Firestore ที่ field นี้เพื่อลบ document เก่าอัตโนมัติ)
+	ExpiresAt time.Time `firestore:"expires_at" json:"expires_at" gorm:"index"`
+}
+
+func (IdempotencyRecord) TableName() string { return CollectionIdempotencyKeys }
+
+const (
+	CollectionIdempotencyKeys = "idempotency_keys"
+)
+
+// สถานะของ IdempotencyRecord
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+const (
+	// IdempotencyTTL คือระยะเวลาที่เก็บผลลัพธ์ไว้ตอบซ้ำ
+	IdempotencyTTL = 24 * time.Hour
+	// IdempotencyLockTTL คือเวลาที่คำขอแรกถือล็อกได้ ถ้าเกินนี้ (เช่นเซิร์ฟเวอร์ล่ม) คำขอถัดไปจะทำแทน
+	IdempotencyLockTTL = time.Minute
+)
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Job คืองานเบื้องหลังที่ admin สั่ง (ตอนนี้มีแค่รีเซ็ตสถิติของผู้ใช้ทุกคน)
+// ทำทีละ batch ตามลำดับ user ID และบันทึก Cursor พร้อมกับ batch ที่ commit แล้ว
+// ถ้าล้มเหลวกลางทางจึงทำต่อจาก batch ล่าสุดได้โดยไม่ทำซ้ำ
+type Job struct {
+	ID         string     `firestore:"-" json:"id" gorm:"primaryKey;size:64"`
+	Type       string     `firestore:"type" json:"type" gorm:"size:32;not null"`
+	Status     string     `firestore:"status" json:"status" gorm:"size:16;not null;index"`
+	DryRun     bool       `firestore:"dry_run" json:"dry_run"`
+	CreatedBy  string     `firestore:"created_by" json:"created_by" gorm:"size:64"`
+	CreatedAt  time.Time  `firestore:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `firestore:"updated_at" json:"updated_at"`
+	FinishedAt *time.Time `firestore:"finished_at,omitempty" json:"finished_at,omitempty"`
+	Error      string     `firestore:"error,omitempty" json:"error,omitempty" gorm:"size:1000"`
+
+	// Total คือจำนวนผู้ใช้ตอนเริ่มงาน Processed คือจำนวนที่ทำเสร็จแล้ว
+	Total     int `firestore:"total" json:"total"`
+	Processed int `firestore:"processed" json:"processed"`
+	// Changed คือจำนวนผู้ใช้ที่มีสถิติไม่เป็น 0 (ถูกรีเซ็ต หรือจะถูกรีเซ็ตถ้าเป็น dry run)
+	// และ Cleared* คือยอดรวมที่ถูกล้างออก
+	Changed             int `firestore:"changed" json:"changed"`
+	ClearedMinute       int `firestore:"cleared_minute" json:"cleared_minute"`
+	ClearedScore        int `firestore:"cleared_score" json:"cleared_score"`
+	ClearedNumberTree   int `firestore:"cleared_number_tree" json:"cleared_number_tree"`
+	ClearedTreeProgress int `firestore:"cleared_tree_progress" json:"cleared_tree_progress"`
+	// Cursor คือ user ID สุดท้ายของ batch ที่ commit แล้ว
+	Cursor string `firestore:"cursor" json:"-" gorm:"size:64"`
+
+	// Restored และ UndoCursor ใช้กับการ undo แบบเดียวกับ Processed และ Cursor
+	Restored   int    `firestore:"restored" json:"restored"`
+	UndoCursor string `firestore:"undo_cursor" json:"-" gorm:"size:64"`
+}
+
+func (Job) TableName() string { return CollectionJobs }
+
+// StatsSnapshot คือสถิติของผู้ใช้หนึ่งคนก่อนถูกรีเซ็ต ใช้คืนค่าตอน undo
+// ใน Firestore เก็บที่ jobs/{job_id}/snapshots/{user_id}
+type StatsSnapshot struct {
+	// ID คือ "<job_id>_<user_id>" (ใช้เฉพาะ SQL)
+	ID           string `firestore:"-" json:"-" gorm:"primaryKey;size:160"`
+	JobID        string `firestore:"job_id" json:"job_id" gorm:"size:64;not null;index:idx_stats_snapshots_job,priority:1"`
+	UserID       string `firestore:"user_id" json:"user_id" gorm:"size:64;not null;index:idx_stats_snapshots_job,priority:2"`
+	Minute       int    `firestore:"minute" json:"minute"`
+	Score        int    `firestore:"score" json:"score"`
+	NumberTree   int    `firestore:"number_tree" json:"number_tree"`
+	TreeProgress int    `firestore:"tree_progress" json:"tree_progress"`
+}
+
+func (StatsSnapshot) TableName() string { return "stats_snapshots" }
+
+const (
+	CollectionJobs        = "jobs"
+	SubcollectionSnapshot = "snapshots"
+)
+
+// ประเภทของงาน
+const (
+	JobTypeResetStats = "reset_stats"
+)
+
+// สถานะของงาน
+//   - running → completed (หรือ failed ซึ่งสั่ง resume ให้กลับไป running ได้)
+//   - completed → undoing → undone (หรือ undo_failed ซึ่งสั่ง resume ให้กลับไป undoing ได้)
+const (
+	JobStatusRunning    = "running"
+	JobStatusCompleted  = "completed"
+	JobStatusFailed     = "failed"
+	JobStatusUndoing    = "undoing"
+	JobStatusUndone     = "undone"
+	JobStatusUndoFailed = "undo_failed"
+)
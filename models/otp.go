@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// LoginOTP เก็บรหัส OTP (แบบ hash) สำหรับการล็อกอินด้วยเบอร์โทรศัพท์ และการยืนยันเบอร์ใหม่ตอนเปลี่ยนเบอร์
+// มีได้ 1 รหัสต่อ ID ซึ่งแยกตามจุดประสงค์และเจ้าของ (ดู LoginOTPID และ PhoneChangeOTPID)
+// รหัสเปลี่ยนเบอร์ที่ส่งไปเบอร์ของคนอื่นจึงไม่ทับรหัสล็อกอินของเจ้าของเบอร์
+type LoginOTP struct {
+	// ID ใน Firestore ใช้ค่า hash ของ ID นี้เป็น Document ID
+	ID    string `firestore:"-" gorm:"primaryKey;size:128"`
+	Phone string `firestore:"phone" gorm:"size:32;not null;index"`
+	// UserID ไม่ว่างเมื่อเป็นรหัสยืนยันการเปลี่ยนเบอร์ของผู้ใช้คนนี้ (ใช้ล็อกอินไม่ได้)
+	UserID    string    `firestore:"user_id,omitempty" gorm:"size:64"`
+	CodeHash  string    `firestore:"code_hash" gorm:"size:255;not null"`
+	Attempts  int       `firestore:"attempts" gorm:"not null;default:0"`
+	ExpiresAt time.Time `firestore:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `firestore:"created_at" gorm:"not null"`
+}
+
+func (LoginOTP) TableName() string { return CollectionLoginOTPs }
+
+// LoginOTPID คือ ID ของรหัสล็อกอินของเบอร์ phone
+func LoginOTPID(phone string) string {
+	return "login:" + phone
+}
+
+// PhoneChangeOTPID คือ ID ของรหัสยืนยันที่ส่งไปยัง phone เมื่อผู้ใช้ userID ขอเปลี่ยนมาใช้เบอร์นี้
+func PhoneChangeOTPID(userID, phone string) string {
+	return "phone_change:" + userID + ":" + phone
+}
+
+const (
+	CollectionLoginOTPs = "login_otps"
+)
+
+// ค่าคงที่สำหรับ OTP
+const (
+	OTPLength         = 6
+	OTPTTL            = 5 * time.Minute
+	OTPMaxAttempts    = 5
+	OTPResendCooldown = 60 * time.Second
+)
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// PeriodStats คือยอดสะสมของผู้ใช้หนึ่งคนในช่วงเวลาหนึ่ง (วัน/สัปดาห์/เดือน/ฤดูกาล)
+// ใช้จัดอันดับ leaderboard รายช่วงเวลา อัปเดตทุกครั้งที่บันทึกกิจกรรมหรือต้นไม้โต
+// เก็บเฉพาะสมาชิก (role member) เหมือน leaderboard ตลอดกาล
+type PeriodStats struct {
+	// ID คือ "<period>_<period_key>_<user_id>" เช่น day_2026-01-31_abc
+	// สำหรับฤดูกาล period_key คือ ID ของ Season
+	ID         string    `firestore:"-" json:"id" gorm:"primaryKey;size:128"`
+	Period     string    `firestore:"period" json:"period" gorm:"size:8;not null;index:idx_period_stats_board,priority:1"`
+	PeriodKey  string    `firestore:"period_key" json:"period_key" gorm:"size:64;not null;index:idx_period_stats_board,priority:2"`
+	UserID     string    `firestore:"user_id" json:"user_id" gorm:"size:64;not null"`
+	Name       string    `firestore:"name" json:"name" gorm:"size:255"`
+	Minute     int       `firestore:"minute" json:"minute"`
+	Score      int       `firestore:"score" json:"score"`
+	NumberTree int       `firestore:"number_tree" json:"number_tree"`
+	UpdatedAt  time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+func (PeriodStats) TableName() string { return CollectionPeriodStats }
+
+const (
+	CollectionPeriodStats = "period_stats"
+)
+
+// ช่วงเวลาของ leaderboard (PeriodAll ใช้ยอดตลอดกาลจากเอกสาร users)
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodAll   = "all"
+	// PeriodSeason ไม่ได้ตัดรอบตามปฏิทิน แต่ตามช่วงของ Season ที่ยังเปิดอยู่
+	PeriodSeason = "season"
+)
+
+// AggregatedPeriods คือช่วงเวลาที่มีเอกสาร PeriodStats
+var AggregatedPeriods = []string{PeriodDay, PeriodWeek, PeriodMonth}
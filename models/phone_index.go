@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PhoneIndex จองเบอร์โทรศัพท์ (รูปแบบ E.164) ให้ผู้ใช้หนึ่งคน
+// ใช้เบอร์โทรเป็น Document ID / primary key จึงมีเจ้าของได้คนเดียว
+// store สร้างและย้ายการจองใน transaction เดียวกับที่บันทึก User.Phone
+type PhoneIndex struct {
+	Phone     string    `firestore:"-" gorm:"primaryKey;size:32"`
+	UserID    string    `firestore:"user_id" gorm:"size:64;not null;index"`
+	CreatedAt time.Time `firestore:"created_at"`
+}
+
+func (PhoneIndex) TableName() string { return CollectionPhoneIndex }
+
+const (
+	CollectionPhoneIndex = "phone_index"
+)
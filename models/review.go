@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ActivityReview คือรายการในคิวตรวจสอบกิจกรรมที่น่าสงสัย
+// ใช้ ID เดียวกับ Activity ที่ถูกตรวจ
+type ActivityReview struct {
+	ID              string     `firestore:"-" json:"id" gorm:"primaryKey;size:64"`
+	UserID          string     `firestore:"user_id" json:"user_id" gorm:"size:64;not null;index"`
+	ActivityType    string     `firestore:"activity_type" json:"activity_type" gorm:"size:32"`
+	StartedAt       time.Time  `firestore:"started_at" json:"started_at"`
+	DurationMinutes int        `firestore:"duration_minutes" json:"duration_minutes"`
+	ScoreAwarded    int        `firestore:"score_awarded" json:"score_awarded"`
+	Flags           []string   `firestore:"flags" json:"flags" gorm:"type:text;serializer:json"`
+	Status          string     `firestore:"status" json:"status" gorm:"size:16;not null;index:idx_activity_reviews_status_created,priority:1"`
+	CreatedAt       time.Time  `firestore:"created_at" json:"created_at" gorm:"index:idx_activity_reviews_status_created,priority:2"`
+	ReviewedBy      string     `firestore:"reviewed_by,omitempty" json:"reviewed_by,omitempty" gorm:"size:64"`
+	ReviewedAt      *time.Time `firestore:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	Note            string     `firestore:"note,omitempty" json:"note,omitempty" gorm:"size:500"`
+}
+
+func (ActivityReview) TableName() string { return CollectionActivityReviews }
+
+const (
+	CollectionActivityReviews = "activity_reviews"
+)
+
+// สถานะของการตรวจสอบ
+// ถ้า reject ระบบจะหักนาทีและคะแนนของกิจกรรมนั้นออกจากผู้ใช้
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
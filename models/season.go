@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Season คือฤดูกาลแข่งขันที่ admin สร้างไว้ (ช่วง StartsAt <= เวลา < EndsAt ห้ามทับกัน)
+// ระหว่างที่ยังเปิดอยู่ ยอดของสมาชิกสะสมใน PeriodStats (period = season, period_key = ID ของฤดูกาล)
+// เมื่อปิดฤดูกาล leaderboard สุดท้ายถูกบันทึกไว้ใน seasons/{id}/standings
+type Season struct {
+	ID        string     `firestore:"-" json:"id" gorm:"primaryKey;size:64"`
+	Name      string     `firestore:"name" json:"name" gorm:"size:255;not null"`
+	StartsAt  time.Time  `firestore:"starts_at" json:"starts_at" gorm:"index"`
+	EndsAt    time.Time  `firestore:"ends_at" json:"ends_at"`
+	Status    string     `firestore:"status" json:"status" gorm:"size:16;not null;index"`
+	CreatedBy string     `firestore:"created_by" json:"created_by" gorm:"size:64"`
+	CreatedAt time.Time  `firestore:"created_at" json:"created_at"`
+	ClosedBy  string     `firestore:"closed_by,omitempty" json:"closed_by,omitempty" gorm:"size:64"`
+	ClosedAt  *time.Time `firestore:"closed_at,omitempty" json:"closed_at,omitempty"`
+	// Participants คือจำนวนสมาชิกใน standings (ใส่ตอนปิดฤดูกาล)
+	Participants int `firestore:"participants" json:"participants"`
+}
+
+func (Season) TableName() string { return CollectionSeasons }
+
+// Contains บอกว่าเวลา t อยู่ในช่วงของฤดูกาลหรือไม่
+func (s *Season) Contains(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// SeasonStanding คืออันดับสุดท้ายของสมาชิกหนึ่งคนในฤดูกาลที่ปิดแล้ว
+// ใน Firestore เก็บที่ seasons/{season_id}/standings/{user_id}
+type SeasonStanding struct {
+	// ID คือ "<season_id>_<user_id>" (ใช้เฉพาะ SQL)
+	ID         string `firestore:"-" json:"-" gorm:"primaryKey;size:160"`
+	SeasonID   string `firestore:"season_id" json:"season_id" gorm:"size:64;not null;index"`
+	Rank       int    `firestore:"rank" json:"rank"`
+	UserID     string `firestore:"user_id" json:"user_id" gorm:"size:64;not null"`
+	Name       string `firestore:"name" json:"name" gorm:"size:255"`
+	Minute     int    `firestore:"minute" json:"minute"`
+	Score      int    `firestore:"score" json:"score"`
+	NumberTree int    `firestore:"number_tree" json:"number_tree"`
+}
+
+func (SeasonStanding) TableName() string { return "season_standings" }
+
+const (
+	CollectionSeasons      = "seasons"
+	SubcollectionStandings = "standings"
+)
+
+// สถานะของฤดูกาล
+// closing คือปิดรับยอดแล้วแต่ยังบันทึก standings ไม่ครบ (สั่งปิดซ้ำเพื่อทำต่อได้)
+const (
+	SeasonStatusOpen    = "open"
+	SeasonStatusClosing = "closing"
+	SeasonStatusClosed  = "closed"
+)
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Session คือการล็อกอินหนึ่งครั้งบนอุปกรณ์หนึ่งเครื่อง
+// ผูกกับ Refresh Token (เก็บเฉพาะค่า hash) และใช้เพิกถอน Access Token ที่ออกจาก session นี้
+type Session struct {
+	ID               string     `firestore:"-" json:"id" gorm:"primaryKey;size:64"`
+	UserID           string     `firestore:"user_id" json:"-" gorm:"size:64;index;not null"`
+	RefreshTokenHash string     `firestore:"refresh_token_hash" json:"-" gorm:"size:64;not null"`
+	UserAgent        string     `firestore:"user_agent" json:"user_agent" gorm:"size:512"`
+	IP               string     `firestore:"ip" json:"ip" gorm:"size:64"`
+	CreatedAt        time.Time  `firestore:"created_at" json:"created_at"`
+	LastUsedAt       time.Time  `firestore:"last_used_at" json:"last_used_at"`
+	ExpiresAt        time.Time  `firestore:"expires_at" json:"expires_at"`
+	RevokedAt        *time.Time `firestore:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+func (Session) TableName() string { return CollectionSessions }
+
+// Active บอกว่า session ยังใช้งานได้อยู่หรือไม่
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+const (
+	CollectionSessions = "sessions"
+)
@@ -5,29 +5,142 @@ import "time"
 // User struct สำหรับเก็บข้อมูลใน Firestore
 type User struct {
 	// เปลี่ยน UID เป็น ID ชนิด string และใช้ firestore tag
-	ID           string  `firestore:"-" json:"id"` // ID ของ Document จะไม่ถูกเก็บใน field ของ document เอง
-	Name         string  `firestore:"name" json:"name"`
-	Phone        *string `firestore:"phone,omitempty" json:"phone,omitempty"`
-	Age          *int    `firestore:"age,omitempty" json:"age,omitempty"`
-	Gender       *string `firestore:"gender,omitempty" json:"gender,omitempty"`
-	Minute       int     `firestore:"minute" json:"minute"`
-	Score        int     `firestore:"score" json:"score"`
-	NumberTree   int     `firestore:"number_tree" json:"number_tree"`
-	TreeProgress int     `firestore:"tree_progress" json:"tree_progress"`
-	Role         string  `firestore:"role" json:"role"`
-	LastLoginAt *time.Time `firestore:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+	// tag gorm ใช้กับ backend แบบ SQL (ดู store.SQLStore)
+	ID           string     `firestore:"-" json:"id" gorm:"primaryKey;size:64"` // ID ของ Document จะไม่ถูกเก็บใน field ของ document เอง
+	Name         string     `firestore:"name" json:"name" gorm:"size:255;not null"`
+	Phone        *string    `firestore:"phone,omitempty" json:"phone,omitempty" gorm:"size:32;index"`
+	Age          *int       `firestore:"age,omitempty" json:"age,omitempty"`
+	Gender       *string    `firestore:"gender,omitempty" json:"gender,omitempty" gorm:"size:16"`
+	Minute       int        `firestore:"minute" json:"minute" gorm:"not null;default:0"`
+	Score        int        `firestore:"score" json:"score" gorm:"not null;default:0"`
+	NumberTree   int        `firestore:"number_tree" json:"number_tree" gorm:"not null;default:0"`
+	TreeProgress int        `firestore:"tree_progress" json:"tree_progress" gorm:"not null;default:0"`
+	Role         string     `firestore:"role" json:"role" gorm:"size:32;index"`
+	LastLoginAt  *time.Time `firestore:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+	// UID ของ Firebase Authentication (มีเฉพาะผู้ใช้ที่เคยล็อกอินผ่าน Firebase)
+	FirebaseUID *string `firestore:"firebase_uid,omitempty" json:"-" gorm:"size:128;uniqueIndex"`
 
+	// ความเป็นส่วนตัว: ใครดูโปรไฟล์ได้ (ProfileVisibility* ค่าว่าง = public) และชื่อที่แสดงบน leaderboard แทนชื่อจริง
+	ProfileVisibility string `firestore:"profile_visibility,omitempty" json:"profile_visibility,omitempty" gorm:"size:16"`
+	LeaderboardAlias  string `firestore:"leaderboard_alias,omitempty" json:"leaderboard_alias,omitempty" gorm:"size:64"`
+
+	// ภาษาของข้อความที่ส่งถึงผู้ใช้ (i18n.Locales) ค่าว่าง = ใช้ Accept-Language ของคำขอ
+	Locale string `firestore:"locale,omitempty" json:"locale,omitempty" gorm:"size:8"`
+
+	// ระงับบัญชีโดย admin (ล็อกอินและใช้ Token เดิมไม่ได้จนกว่าจะยกเลิก)
+	SuspendedAt   *time.Time `firestore:"suspended_at,omitempty" json:"suspended_at,omitempty"`
+	SuspendedBy   string     `firestore:"suspended_by,omitempty" json:"suspended_by,omitempty" gorm:"size:64"`
+	SuspendReason string     `firestore:"suspend_reason,omitempty" json:"suspend_reason,omitempty" gorm:"size:500"`
+	// ลบแบบ soft-delete ข้อมูลยังอยู่จนถึง PurgeAt แล้วจึงถูกลบถาวร (กู้คืนได้ก่อนหน้านั้น)
+	DeletedAt *time.Time `firestore:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy string     `firestore:"deleted_by,omitempty" json:"deleted_by,omitempty" gorm:"size:64"`
+	PurgeAt   *time.Time `firestore:"purge_at,omitempty" json:"purge_at,omitempty" gorm:"index"`
+}
+
+// TableName ใช้ชื่อตารางเดียวกับชื่อ Collection ใน Firestore
+func (User) TableName() string { return CollectionUsers }
+
+// Status คืนสถานะของบัญชี (UserStatus*)
+func (u *User) Status() string {
+	switch {
+	case u.DeletedAt != nil:
+		return UserStatusDeleted
+	case u.SuspendedAt != nil:
+		return UserStatusSuspended
+	default:
+		return UserStatusActive
+	}
 }
 
 // --- Constants ---
 
-// ชื่อ Collection ใน Firestore
+// ชื่อ Collection ใน Firestore (และชื่อตารางใน SQL)
 const (
 	CollectionUsers = "users"
 )
 
 // Constants สำหรับ Role ยังคงใช้งานได้เหมือนเดิม
+// moderator ดูข้อมูลผู้ใช้และตรวจกิจกรรมที่ถูก flag ได้ แต่แก้ไขผู้ใช้หรือรีเซ็ตสถิติไม่ได้
+const (
+	RoleMember    = "member"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// ValidRole บอกว่า role เป็นค่าที่ระบบรู้จักหรือไม่
+func ValidRole(role string) bool {
+	switch role {
+	case RoleMember, RoleAdmin, RoleModerator:
+		return true
+	default:
+		return false
+	}
+}
+
+// เพศที่ผู้ใช้เลือกได้ (User.Gender)
+const (
+	GenderMale   = "male"
+	GenderFemale = "female"
+	GenderOther  = "other"
+)
+
+// Genders คือค่าของ User.Gender ที่ระบบรู้จัก เรียงตามที่แสดงในข้อความ error
+var Genders = []string{GenderMale, GenderFemale, GenderOther}
+
+// ValidGender บอกว่า gender เป็นค่าที่ระบบรู้จักหรือไม่
+func ValidGender(gender string) bool {
+	switch gender {
+	case GenderMale, GenderFemale, GenderOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// สถานะของบัญชี
 const (
-	RoleMember = "member"
-	RoleAdmin  = "admin"
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDeleted   = "deleted"
 )
+
+// ระดับการมองเห็นโปรไฟล์ (User.ProfileVisibility)
+// admin และผู้ที่มีสิทธิ์ users:read เห็นทุกโปรไฟล์ผ่าน API ของ admin เสมอ
+const (
+	VisibilityPublic  = "public"  // ทุกคนเห็น รวมถึงผู้ที่ไม่ได้ล็อกอิน
+	VisibilityFriends = "friends" // เฉพาะเพื่อน
+	VisibilityPrivate = "private" // เฉพาะเจ้าของ
+)
+
+// ValidVisibility บอกว่าเป็นระดับการมองเห็นที่ระบบรู้จักหรือไม่
+func ValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPublic, VisibilityFriends, VisibilityPrivate:
+		return true
+	default:
+		return false
+	}
+}
+
+// Visibility คืนระดับการมองเห็นโปรไฟล์ (ผู้ใช้ที่ยังไม่เคยตั้งค่าเป็น public)
+func (u *User) Visibility() string {
+	if u.ProfileVisibility == "" {
+		return VisibilityPublic
+	}
+	return u.ProfileVisibility
+}
+
+// AnonymousName คือชื่อที่แสดงบน leaderboard แทนผู้ใช้ที่ผู้ดูไม่มีสิทธิ์เห็นชื่อ
+const AnonymousName = "Anonymous"
+
+// UserPurgeWindow คือระยะเวลาหลัง soft-delete ก่อนข้อมูลของผู้ใช้ถูกลบถาวร
+// ผู้ใช้ที่ลบบัญชีเองยกเลิกได้ภายในช่วงนี้ด้วยการล็อกอินพร้อม cancel_deletion
+const UserPurgeWindow = 30 * 24 * time.Hour
+
+// DeletedUserName คือชื่อที่แสดงบน leaderboard และอันดับของฤดูกาลแทนชื่อของบัญชีที่ถูกลบ
+const DeletedUserName = "Deleted user"
+
+// DeletedBySelf บอกว่าผู้ใช้เป็นคนสั่งลบบัญชีของตัวเอง (ไม่ใช่ admin)
+func (u *User) DeletedBySelf() bool {
+	return u.DeletedAt != nil && u.DeletedBy == u.ID
+}
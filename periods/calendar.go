@@ -0,0 +1,67 @@
+package periods
+
+import (
+	"fmt"
+	"time"
+
+	"meerank/models"
+
+	// ฝังฐานข้อมูล timezone ไว้ในไบนารี เผื่อ image ที่ไม่มี /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+// Calendar แบ่งเวลาเป็นช่วงของ leaderboard ตาม timezone ที่กำหนด
+// สัปดาห์เริ่มวันจันทร์ตาม ISO 8601
+type Calendar struct {
+	loc *time.Location
+}
+
+// NewCalendar สร้าง Calendar จากชื่อ timezone แบบ IANA เช่น Asia/Bangkok
+func NewCalendar(timezone string) (*Calendar, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leaderboard timezone %q: %w", timezone, err)
+	}
+	return &Calendar{loc: loc}, nil
+}
+
+// Location คืน timezone ของ Calendar
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// Key คืนรหัสของช่วงเวลาที่ t อยู่ เช่น 2026-01-31 (day), 2026-W05 (week), 2026-01 (month)
+func (c *Calendar) Key(period string, t time.Time) string {
+	local := t.In(c.loc)
+	switch period {
+	case models.PeriodDay:
+		return local.Format(time.DateOnly)
+	case models.PeriodWeek:
+		year, week := local.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case models.PeriodMonth:
+		return local.Format("2006-01")
+	default:
+		return ""
+	}
+}
+
+// Bounds คืนเวลาเริ่มต้นและสิ้นสุด (ไม่รวม) ของช่วงเวลาที่ t อยู่
+func (c *Calendar) Bounds(period string, t time.Time) (start, end time.Time) {
+	local := t.In(c.loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.loc)
+	switch period {
+	case models.PeriodDay:
+		return day, day.AddDate(0, 0, 1)
+	case models.PeriodWeek:
+		// Weekday ของ Go เริ่มที่วันอาทิตย์ = 0 จึงเลื่อนให้วันจันทร์เป็นวันแรก
+		offset := (int(day.Weekday()) + 6) % 7
+		start = day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7)
+	case models.PeriodMonth:
+		start = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, c.loc)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return time.Time{}, time.Time{}
+	}
+}
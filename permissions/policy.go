@@ -0,0 +1,102 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"meerank/models"
+)
+
+// สิทธิ์ที่ route ต้องการ (ดู middleware.RequirePermission)
+const (
+	// UsersRead ดูรายชื่อและข้อมูลเต็มของผู้ใช้
+	UsersRead = "users:read"
+	// UsersWrite แก้ไข ระงับ ลบ และกู้คืนผู้ใช้
+	UsersWrite = "users:write"
+	// RolesWrite เปลี่ยน role ของผู้ใช้
+	RolesWrite = "roles:write"
+	// StatsReset รีเซ็ตสถิติของทุกคน และดู undo หรือ resume งานรีเซ็ต
+	StatsReset = "stats:reset"
+	// LeaderboardModerate ตรวจและตัดสินกิจกรรมที่ถูก flag
+	LeaderboardModerate = "leaderboard:moderate"
+	// SeasonsManage สร้างและปิดฤดูกาล
+	SeasonsManage = "seasons:manage"
+	// AuditRead ดู audit log
+	AuditRead = "audit:read"
+)
+
+// All คือสิทธิ์ทั้งหมดที่ระบบรู้จัก
+var All = []string{UsersRead, UsersWrite, RolesWrite, StatsReset, LeaderboardModerate, SeasonsManage, AuditRead}
+
+// DefaultMapping คือสิทธิ์ของแต่ละ role เมื่อไม่ได้ระบุไฟล์สิทธิ์ (config.Permissions.File)
+func DefaultMapping() map[string][]string {
+	return map[string][]string{
+		models.RoleAdmin:     slices.Clone(All),
+		models.RoleModerator: {UsersRead, LeaderboardModerate},
+		models.RoleMember:    {},
+	}
+}
+
+// Policy บอกว่าแต่ละ role มีสิทธิ์อะไรบ้าง
+type Policy struct {
+	roles map[string][]string
+}
+
+// NewPolicy สร้าง Policy หลังตรวจว่ามีแต่ role และสิทธิ์ที่ระบบรู้จัก
+// role ที่ไม่อยู่ใน mapping จะไม่มีสิทธิ์ใดเลย
+func NewPolicy(mapping map[string][]string) (*Policy, error) {
+	roles := make(map[string][]string, len(mapping))
+	for role, perms := range mapping {
+		if !models.ValidRole(role) {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		for _, perm := range perms {
+			if !slices.Contains(All, perm) {
+				return nil, fmt.Errorf("unknown permission %q for role %q", perm, role)
+			}
+		}
+		sorted := slices.Clone(perms)
+		slices.Sort(sorted)
+		roles[role] = slices.Compact(sorted)
+	}
+	return &Policy{roles: roles}, nil
+}
+
+// NewPolicyFromFile สร้าง Policy จากไฟล์ JSON ที่ path (เช่น {"moderator": ["users:read"]}) ว่าง = DefaultMapping
+// role ที่ระบุในไฟล์จะแทนที่สิทธิ์เริ่มต้นของ role นั้นทั้งหมด ส่วน role อื่นใช้ DefaultMapping
+func NewPolicyFromFile(path string) (*Policy, error) {
+	mapping := DefaultMapping()
+	if path == "" {
+		return NewPolicy(mapping)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read role permissions file: %w", err)
+	}
+	var overrides map[string][]string
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parse role permissions file: %w", err)
+	}
+	for role, perms := range overrides {
+		mapping[role] = perms
+	}
+	return NewPolicy(mapping)
+}
+
+// Permissions คืนสิทธิ์ทั้งหมดของ role (เรียงตามตัวอักษร)
+func (p *Policy) Permissions(role string) []string {
+	return slices.Clone(p.roles[role])
+}
+
+// Allows บอกว่า role มีสิทธิ์ครบทุกข้อใน perms หรือไม่
+func (p *Policy) Allows(role string, perms ...string) bool {
+	for _, perm := range perms {
+		if !slices.Contains(p.roles[role], perm) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package phones
+
+import (
+	"errors"
+	"strings"
+)
+
+// ThailandCallingCode คือรหัสประเทศไทย ใช้กับเบอร์ที่กรอกแบบในประเทศ (ขึ้นต้นด้วย 0)
+const ThailandCallingCode = "66"
+
+// ErrInvalid ถูกคืนเมื่อแปลงเบอร์โทรเป็นรูปแบบ E.164 ไม่ได้
+var ErrInvalid = errors.New("phones: invalid phone number")
+
+// Normalize แปลงเบอร์โทรเป็นรูปแบบ E.164 เช่น +66812345678 เพื่อใช้เก็บ ค้นหา และจองเบอร์
+// รับได้ทั้งแบบในประเทศ (0812345678, 081-234-5678, 02 123 4567), แบบมีรหัสประเทศ
+// (+66 81 234 5678, 66812345678, 0066812345678) และเบอร์ต่างประเทศที่ขึ้นต้นด้วย + หรือ 00
+// ช่องว่าง ขีด จุด และวงเล็บถูกตัดทิ้ง
+func Normalize(raw string) (string, error) {
+	number := stripSeparators(raw)
+
+	// 1. แปลงให้อยู่ในรูป + ตามด้วยตัวเลข
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number = number[1:]
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = ThailandCallingCode + number[1:]
+	case strings.HasPrefix(number, ThailandCallingCode) && (len(number) == 10 || len(number) == 11):
+		// เบอร์ไทยที่ใส่รหัสประเทศแต่ไม่มี +
+	default:
+		return "", ErrInvalid
+	}
+	if !digitsOnly(number) || number == "" || number[0] == '0' {
+		return "", ErrInvalid
+	}
+
+	// 2. เบอร์ไทยต้องเป็นเบอร์มือถือ (9 หลัก) หรือเบอร์บ้าน (8 หลัก) ที่ถูกต้อง
+	if national, ok := strings.CutPrefix(number, ThailandCallingCode); ok {
+		// คนมักใส่ 0 ซ้ำหลังรหัสประเทศ เช่น +66 081 234 5678
+		national = strings.TrimPrefix(national, "0")
+		if !validThaiNumber(national) {
+			return "", ErrInvalid
+		}
+		return "+" + ThailandCallingCode + national, nil
+	}
+
+	// 3. เบอร์ต่างประเทศตรวจได้แค่ความยาวตาม E.164 (รวมรหัสประเทศ 8-15 หลัก)
+	if len(number) < 8 || len(number) > 15 {
+		return "", ErrInvalid
+	}
+	return "+" + number, nil
+}
+
+// NormalizePrefix แปลงส่วนต้นของเบอร์โทร (สำหรับค้นหาแบบขึ้นต้นด้วย) ให้อยู่ในรูปเดียวกับที่ Normalize เก็บ
+// เช่น 081 เป็น +6681 ส่วนค่าที่แปลงไม่ได้คืนตามเดิม (หลังตัดตัวคั่น)
+func NormalizePrefix(prefix string) string {
+	number := stripSeparators(prefix)
+	switch {
+	case strings.HasPrefix(number, "+"):
+		return number
+	case strings.HasPrefix(number, "00"):
+		return "+" + number[2:]
+	case strings.HasPrefix(number, "0"):
+		return "+" + ThailandCallingCode + number[1:]
+	default:
+		return number
+	}
+}
+
+// validThaiNumber ตรวจเบอร์ไทยที่ตัด 0 นำหน้าแล้ว
+// มือถือ 9 หลักขึ้นต้นด้วย 6, 8 หรือ 9 และเบอร์บ้าน 8 หลักขึ้นต้นด้วย 2, 3, 4, 5 หรือ 7
+func validThaiNumber(national string) bool {
+	switch len(national) {
+	case 9:
+		return strings.ContainsRune("689", rune(national[0]))
+	case 8:
+		return strings.ContainsRune("23457", rune(national[0]))
+	default:
+		return false
+	}
+}
+
+func stripSeparators(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package phones_test
+
+import (
+	"errors"
+	"testing"
+
+	"meerank/phones"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"0812345678", "+66812345678"},
+		{"081-234-5678", "+66812345678"},
+		{"081 234 5678", "+66812345678"},
+		{" 081.234.5678 ", "+66812345678"},
+		{"+66812345678", "+66812345678"},
+		{"+66 81 234 5678", "+66812345678"},
+		{"+66 081 234 5678", "+66812345678"},
+		{"0066812345678", "+66812345678"},
+		{"0066-81-234-5678", "+66812345678"},
+		{"66812345678", "+66812345678"},
+		{"0612345678", "+66612345678"},
+		{"0912345678", "+66912345678"},
+		{"02 123 4567", "+6621234567"},
+		{"(02) 123-4567", "+6621234567"},
+		{"+44 20 7946 0958", "+442079460958"},
+		{"001 202 555 0143", "+12025550143"},
+	}
+	for _, tt := range tests {
+		got, err := phones.Normalize(tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"   ",
+		"081234567",    // สั้นไป
+		"08123456789",  // ยาวไป
+		"+66 8123",     // สั้นไป
+		"6681234567",   // มีรหัสประเทศแต่ขาดไป 1 หลัก
+		"0112345678",   // ไม่ใช่เบอร์มือถือไทย
+		"0512345678",   // 9 หลักที่ขึ้นต้นด้วย 5 ไม่ใช่เบอร์มือถือ
+		"0112345",      // ไม่ใช่เบอร์บ้านไทย
+		"+66012345678", // ไม่ใช่เบอร์มือถือไทยแม้ตัด 0 ซ้ำแล้ว
+		"08l2345678",   // มีตัวอักษร
+		"abc",
+		"+66 81 234 567a",
+		"812345678", // ไม่มี 0 หรือรหัสประเทศ
+		"+1234567",  // สั้นกว่า E.164
+		"+1234567890123456",
+	} {
+		if got, err := phones.Normalize(raw); !errors.Is(err, phones.ErrInvalid) {
+			t.Errorf("Normalize(%q) = %q, %v; want ErrInvalid", raw, got, err)
+		}
+	}
+}
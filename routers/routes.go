@@ -4,60 +4,192 @@ import (
 	// handlersadmin "meerank/Handler/"
 	handlersadmin "meerank/Handler/admin"
 	handlers "meerank/Handler/member"
+	"meerank/auth"
+	"meerank/config"
+	"meerank/jobs"
 	"meerank/middleware"
-	"meerank/models"
+	"meerank/permissions"
+	"meerank/scoring"
+	"meerank/sms"
+	"meerank/store"
 
-	"cloud.google.com/go/firestore" // 1. เปลี่ยน import จาก gorm
+	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRouter ฟังก์ชันสำหรับตั้งค่า routes ของแอป
-// 2. เปลี่ยนพารามิเตอร์จาก db *gorm.DB เป็น client *firestore.Client
-func SetupRouter(r *gin.Engine, client *firestore.Client) {
+// Handler ทุกตัวเข้าถึงข้อมูลผ่าน store.Store จึงไม่ผูกกับ Firestore โดยตรง
+// ค่าตั้งที่ handler ใช้ (อายุ Token, ขนาดหน้า leaderboard, คะแนนต่อต้นไม้, CORS) มาจาก cfg
+func SetupRouter(r *gin.Engine, cfg *config.Config, st store.Store, smsSender sms.Sender, keys *auth.KeyRing, idTokenVerifier auth.IDTokenVerifier, scorer *scoring.Engine, jobRunner *jobs.Runner, policy *permissions.Policy) {
+	r.Use(cors.New(corsConfig(cfg.Server)))
+	// ทุกคำขอมี request id ไว้ผูก log และ audit log
+	// ส่วน error ของทุก handler ถูกเขียนเป็นรูปแบบเดียวกันโดย ErrorMiddleware
+	r.Use(middleware.RequestIDMiddleware(), middleware.ErrorMiddleware())
 
-	// 3. เปลี่ยนการส่ง db เป็น client ในทุกๆ handler
+	// ส่ง store ให้ทุกๆ handler
 	r.POST("/register", func(c *gin.Context) {
-		handlers.RegisterHandler(c, client)
+		handlers.RegisterHandler(c, st)
 	})
 
-	r.POST("/login", func(c *gin.Context) {
-		handlers.LoginHandler(c, client)
+	// ล็อกอินแบบ 2 ขั้นตอน: ขอรหัส OTP ทาง SMS แล้วยืนยันรหัสเพื่อรับ Token
+	r.POST("/login/otp/request", func(c *gin.Context) {
+		handlers.RequestLoginOTPHandler(c, st, smsSender)
 	})
 
-	r.GET("/user/:uid", func(c *gin.Context) {
-		handlers.GetUserProfileHandler(c, client)
+	r.POST("/login/otp/verify", func(c *gin.Context) {
+		handlers.VerifyLoginOTPHandler(c, st, keys, cfg.Auth.Tokens)
 	})
 
-	r.GET("/leaderboard", func(c *gin.Context) { handlers.GetLeaderboardHandler(c, client) })
+	// ล็อกอินทางเลือกด้วย ID Token ของ Firebase Authentication (เฉพาะเมื่อ backend ใช้ Firebase)
+	if idTokenVerifier != nil {
+		r.POST("/auth/firebase", func(c *gin.Context) {
+			handlers.FirebaseLoginHandler(c, st, idTokenVerifier, keys, cfg.Auth.Tokens)
+		})
+	}
+
+	// --- Token & Session ---
+	r.POST("/auth/refresh", func(c *gin.Context) {
+		handlers.RefreshTokenHandler(c, st, keys, cfg.Auth.Tokens)
+	})
+
+	r.POST("/auth/logout", middleware.AuthMiddleware(keys, st, policy), func(c *gin.Context) {
+		handlers.LogoutHandler(c, st)
+	})
+
+	// --- Public Routes (ล็อกอินหรือไม่ก็ได้ ผู้ที่ล็อกอินเห็นข้อมูลมากขึ้นตามการตั้งค่าความเป็นส่วนตัว) ---
+	optionalAuth := middleware.OptionalAuthMiddleware(keys, st, policy)
+
+	r.GET("/user/:uid", optionalAuth, func(c *gin.Context) {
+		handlers.GetUserProfileHandler(c, st)
+	})
+
+	r.GET("/leaderboard", optionalAuth, func(c *gin.Context) { handlers.GetLeaderboardHandler(c, st, cfg.Leaderboard) })
+	r.GET("/leaderboard/me", middleware.AuthMiddleware(keys, st, policy), func(c *gin.Context) {
+		handlers.GetMyLeaderboardHandler(c, st)
+	})
+
+	// --- Seasons (อันดับของฤดูกาลที่ปิดแล้วดูย้อนหลังได้) ---
+	r.GET("/seasons", func(c *gin.Context) { handlers.GetSeasonsHandler(c, st) })
+	r.GET("/seasons/:id/leaderboard", optionalAuth, func(c *gin.Context) { handlers.GetSeasonLeaderboardHandler(c, st, cfg.Leaderboard) })
+	r.GET("/seasons/:id/leaderboard/me", middleware.AuthMiddleware(keys, st, policy), func(c *gin.Context) {
+		handlers.GetMySeasonLeaderboardHandler(c, st)
+	})
 
 	// --- Protected Routes (ต้องล็อกอิน) ---
 	profileGroup := r.Group("/profile")
-	profileGroup.Use(middleware.AuthMiddleware())
+	profileGroup.Use(middleware.AuthMiddleware(keys, st, policy))
+	// client ส่ง Idempotency-Key มาได้ เพื่อไม่ให้การ retry นับคะแนนหรือต้นไม้ซ้ำ
+	profileGroup.Use(middleware.IdempotencyMiddleware(st))
 	{
-		profileGroup.GET("/me", func(c *gin.Context) { handlers.GetMyProfileHandler(c, client) })
-		profileGroup.PUT("/me", func(c *gin.Context) { handlers.UpdateMyProfileHandler(c, client) })
-		profileGroup.POST("/activity", func(c *gin.Context) { handlers.UpdateUserActivityHandler(c, client) })
-		profileGroup.POST("/tree", func(c *gin.Context) { handlers.AddTreeHandler(c, client) })
-		profileGroup.POST("/tree/water", func(c *gin.Context) { handlers.WaterTreeHandler(c, client) })
+		profileGroup.GET("/me", func(c *gin.Context) { handlers.GetMyProfileHandler(c, st) })
+		profileGroup.PUT("/me", func(c *gin.Context) { handlers.UpdateMyProfileHandler(c, st) })
+		// เปลี่ยนเบอร์โทรต้องยืนยันเบอร์ใหม่ด้วย OTP ก่อน
+		profileGroup.POST("/phone", func(c *gin.Context) { handlers.RequestPhoneChangeHandler(c, st, smsSender) })
+		profileGroup.POST("/phone/verify", func(c *gin.Context) { handlers.VerifyPhoneChangeHandler(c, st) })
+		// ลบบัญชีแบบมีระยะผ่อนผัน (ยกเลิกได้ด้วยการล็อกอินพร้อม cancel_deletion) และส่งออกข้อมูลส่วนบุคคล
+		profileGroup.DELETE("/me", func(c *gin.Context) { handlers.DeleteMyAccountHandler(c, st) })
+		profileGroup.GET("/me/export", func(c *gin.Context) { handlers.ExportMyDataHandler(c, st) })
+		profileGroup.POST("/activity", func(c *gin.Context) { handlers.UpdateUserActivityHandler(c, st, scorer) })
+		profileGroup.GET("/activities", func(c *gin.Context) { handlers.GetMyActivitiesHandler(c, st) })
+		profileGroup.POST("/tree/water", func(c *gin.Context) { handlers.WaterTreeHandler(c, st, cfg.Trees) })
+		profileGroup.GET("/sessions", func(c *gin.Context) { handlers.GetMySessionsHandler(c, st) })
+		profileGroup.DELETE("/sessions/:id", func(c *gin.Context) { handlers.RevokeMySessionHandler(c, st) })
+		// เพื่อน (ใช้กับการตั้งค่าความเป็นส่วนตัวแบบ friends)
+		profileGroup.GET("/friends", func(c *gin.Context) { handlers.GetMyFriendsHandler(c, st) })
+		profileGroup.POST("/friends/:uid", func(c *gin.Context) { handlers.AddFriendHandler(c, st) })
+		profileGroup.DELETE("/friends/:uid", func(c *gin.Context) { handlers.RemoveFriendHandler(c, st) })
 	}
 
-	// --- Admin Routes (สำหรับ Admin เท่านั้น) ---
+	// --- Admin Routes (แต่ละ route ต้องการสิทธิ์ของตัวเอง ดู permissions.Policy) ---
 	adminGroup := r.Group("/admin")
-	adminGroup.Use(middleware.AuthMiddleware())
-	adminGroup.Use(middleware.RoleMiddleware(models.RoleAdmin))
+	adminGroup.Use(middleware.AuthMiddleware(keys, st, policy))
+	canReadUsers := middleware.RequirePermission(permissions.UsersRead)
+	canWriteUsers := middleware.RequirePermission(permissions.UsersWrite)
+	canWriteRoles := middleware.RequirePermission(permissions.RolesWrite)
+	canResetStats := middleware.RequirePermission(permissions.StatsReset)
+	canModerate := middleware.RequirePermission(permissions.LeaderboardModerate)
+	canManageSeasons := middleware.RequirePermission(permissions.SeasonsManage)
+	canReadAudit := middleware.RequirePermission(permissions.AuditRead)
 	{
-		// GET /admin/users -> ดึงรายชื่อผู้ใช้ทั้งหมด (แบบย่อ)
-		adminGroup.GET("/users", func(c *gin.Context) {
-			handlersadmin.GetAllUsersSummaryHandler(c, client)
+		// GET /admin/users -> ค้นหาผู้ใช้ (แบบย่อ) พร้อมตัวกรอง การเรียง และแบ่งหน้า
+		adminGroup.GET("/users", canReadUsers, func(c *gin.Context) {
+			handlersadmin.GetAllUsersSummaryHandler(c, st)
 		})
 
 		// GET /admin/users/:uid -> ดึงข้อมูลผู้ใช้ 1 คน (แบบเต็ม)
-		adminGroup.GET("/users/:uid", func(c *gin.Context) {
-			handlersadmin.GetFullUserProfileHandler(c, client)
+		adminGroup.GET("/users/:uid", canReadUsers, func(c *gin.Context) {
+			handlersadmin.GetFullUserProfileHandler(c, st)
+		})
+
+		// PATCH /admin/users/:uid -> แก้ไขโปรไฟล์และสถิติ (ต้องระบุ reason)
+		adminGroup.PATCH("/users/:uid", canWriteUsers, func(c *gin.Context) {
+			handlersadmin.UpdateUserHandler(c, st)
+		})
+		adminGroup.POST("/users/:uid/suspend", canWriteUsers, func(c *gin.Context) {
+			handlersadmin.SuspendUserHandler(c, st)
+		})
+		adminGroup.POST("/users/:uid/unsuspend", canWriteUsers, func(c *gin.Context) {
+			handlersadmin.UnsuspendUserHandler(c, st)
+		})
+		// DELETE /admin/users/:uid -> soft-delete และลบถาวรเมื่อครบกำหนด (กู้คืนได้ที่ /restore ก่อนหน้านั้น)
+		adminGroup.DELETE("/users/:uid", canWriteUsers, func(c *gin.Context) {
+			handlersadmin.DeleteUserHandler(c, st)
+		})
+		adminGroup.POST("/users/:uid/restore", canWriteUsers, func(c *gin.Context) {
+			handlersadmin.RestoreUserHandler(c, st)
+		})
+		// PUT /admin/users/:uid/role -> เปลี่ยน role (ลด role ของ admin คนสุดท้ายไม่ได้)
+		adminGroup.PUT("/users/:uid/role", canWriteRoles, func(c *gin.Context) {
+			handlersadmin.ChangeUserRoleHandler(c, st)
 		})
 
-		adminGroup.POST("/users/reset-stats", func(c *gin.Context) {
-			handlersadmin.ResetAllUsersStatsHandler(c, client)
+		// รีเซ็ตสถิติทำเป็นงานเบื้องหลัง ดูความคืบหน้า undo หรือ resume ได้ที่ /admin/jobs/:id
+		adminGroup.POST("/users/reset-stats", canResetStats, func(c *gin.Context) {
+			handlersadmin.ResetAllUsersStatsHandler(c, st, jobRunner)
+		})
+		adminGroup.GET("/jobs/:id", canResetStats, func(c *gin.Context) {
+			handlersadmin.GetJobHandler(c, st)
+		})
+		adminGroup.POST("/jobs/:id/undo", canResetStats, func(c *gin.Context) {
+			handlersadmin.UndoJobHandler(c, st, jobRunner)
+		})
+		adminGroup.POST("/jobs/:id/resume", canResetStats, func(c *gin.Context) {
+			handlersadmin.ResumeJobHandler(c, st, jobRunner)
+		})
+
+		// คิวตรวจสอบกิจกรรมที่ระบบคิดคะแนน flag ไว้
+		adminGroup.GET("/reviews", canModerate, func(c *gin.Context) {
+			handlersadmin.ListActivityReviewsHandler(c, st)
+		})
+		adminGroup.POST("/reviews/:id/resolve", canModerate, func(c *gin.Context) {
+			handlersadmin.ResolveActivityReviewHandler(c, st)
 		})
+
+		// ฤดูกาลแข่งขัน: สร้าง และปิดพร้อมบันทึกอันดับสุดท้าย
+		adminGroup.POST("/seasons", canManageSeasons, func(c *gin.Context) {
+			handlersadmin.CreateSeasonHandler(c, st)
+		})
+		adminGroup.POST("/seasons/:id/close", canManageSeasons, func(c *gin.Context) {
+			handlersadmin.CloseSeasonHandler(c, st)
+		})
+
+		// GET /admin/audit -> audit log ของการกระทำของ admin การล็อกอิน และการแก้โปรไฟล์
+		adminGroup.GET("/audit", canReadAudit, func(c *gin.Context) {
+			handlersadmin.ListAuditHandler(c, st)
+		})
+	}
+}
+
+// corsConfig เปิดให้ browser เรียก API ได้จาก origin ใน server.CORSAllowedOrigins ("*" = ทุก origin)
+// และให้ส่งหรืออ่าน header ของ Idempotency-Key และ request id ได้
+func corsConfig(server config.Server) cors.Config {
+	corsCfg := cors.DefaultConfig()
+	if server.AllowAllOrigins() {
+		corsCfg.AllowAllOrigins = true
+	} else {
+		corsCfg.AllowOrigins = server.CORSAllowedOrigins
 	}
+	corsCfg.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Accept-Language", middleware.IdempotencyKeyHeader, middleware.RequestIDHeader}
+	corsCfg.ExposeHeaders = []string{middleware.IdempotentReplayedHeader, middleware.RequestIDHeader}
+	return corsCfg
 }
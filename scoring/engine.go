@@ -0,0 +1,155 @@
+package scoring
+
+import (
+	"errors"
+	"time"
+
+	"meerank/models"
+)
+
+// ข้อผิดพลาดที่ทำให้กิจกรรมถูกปฏิเสธ
+var (
+	ErrUnknownType     = errors.New("scoring: unknown activity type")
+	ErrInvalidDuration = errors.New("scoring: activity duration must be positive")
+	ErrSessionTooLong  = errors.New("scoring: activity is longer than the allowed maximum")
+	ErrFutureActivity  = errors.New("scoring: activity ends in the future")
+	ErrOverlap         = errors.New("scoring: activity overlaps an existing activity")
+)
+
+// เหตุผลที่กิจกรรมถูกส่งเข้าคิวตรวจสอบ (เก็บไว้ใน Activity.Flags)
+const (
+	FlagClaimedScoreMismatch = "claimed_score_mismatch"
+	FlagSessionScoreCapped   = "session_score_capped"
+	FlagDailyScoreCapped     = "daily_score_capped"
+	FlagLongSession          = "long_session"
+	FlagDailyMinutes         = "daily_minutes_exceeded"
+	FlagBackdated            = "backdated"
+)
+
+// futureSkew คือเวลาที่ยอมให้นาฬิกาของเครื่อง client เดินเร็วกว่าเซิร์ฟเวอร์
+const futureSkew = 5 * time.Minute
+
+// Engine คิดคะแนนของกิจกรรมจากประเภทและระยะเวลาตาม Rules
+// และตัดสินว่ากิจกรรมไหนต้องถูกปฏิเสธหรือส่งเข้าคิวตรวจสอบ
+type Engine struct {
+	rules Rules
+	loc   *time.Location
+}
+
+// NewEngine สร้าง Engine หลังตรวจสอบกติกาแล้ว
+// loc ใช้ตัดรอบวันของเพดานรายวัน ต้องเป็น timezone เดียวกับ leaderboard (periods.Calendar)
+// เพื่อให้ "วัน" ของคะแนนตรงกับ leaderboard รายวัน
+func NewEngine(rules Rules, loc *time.Location) (*Engine, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &Engine{rules: rules, loc: loc}, nil
+}
+
+// NewEngineFromFile สร้าง Engine จากกติกาในไฟล์ path (ว่าง = ค่าเริ่มต้น)
+func NewEngineFromFile(path string, loc *time.Location) (*Engine, error) {
+	rules, err := LoadRules(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(rules, loc)
+}
+
+// Rules คืนกติกาที่ Engine ใช้อยู่
+func (e *Engine) Rules() Rules {
+	return e.rules
+}
+
+// Check ตรวจสิ่งที่ไม่ต้องอาศัยประวัติกิจกรรม (ประเภท ระยะเวลา และเวลาในอนาคต)
+func (e *Engine) Check(activity *models.Activity, now time.Time) error {
+	if _, ok := e.rules.Activities[activity.Type]; !ok {
+		return ErrUnknownType
+	}
+	if activity.DurationMinutes <= 0 || !activity.EndedAt.After(activity.StartedAt) {
+		return ErrInvalidDuration
+	}
+	if activity.DurationMinutes > e.rules.MaxSessionMinutes {
+		return ErrSessionTooLong
+	}
+	if activity.EndedAt.After(now.Add(futureSkew)) {
+		return ErrFutureActivity
+	}
+	return nil
+}
+
+// Window คือช่วง started_at ของกิจกรรมเดิมที่ Score ต้องใช้
+// ครอบคลุมทั้งวันของกิจกรรม (สำหรับเพดานรายวัน) และกิจกรรมที่อาจทับซ้อนกัน
+// (กิจกรรมเดิมยาวไม่เกิน MaxSessionMinutes จึงต้องย้อนไปเท่านั้นพอ)
+func (e *Engine) Window(activity *models.Activity) (from, to time.Time) {
+	dayStart, dayEnd := e.day(activity.StartedAt)
+
+	from = activity.StartedAt.Add(-time.Duration(e.rules.MaxSessionMinutes) * time.Minute)
+	if dayStart.Before(from) {
+		from = dayStart
+	}
+	to = activity.EndedAt
+	if dayEnd.After(to) {
+		to = dayEnd
+	}
+	return from, to
+}
+
+// Score คิดคะแนนให้ activity โดยดูจากกิจกรรมเดิมในช่วง Window
+// ใส่ผลลงใน ScoreAwarded, Flags และ ReviewStatus (pending ถ้ามี Flags ซึ่งต้องรอตรวจสอบ มิฉะนั้นว่าง)
+// claimedScore คือคะแนนที่ client อ้างมา (nil ถ้าไม่ได้ส่งมา)
+func (e *Engine) Score(activity *models.Activity, claimedScore *int, existing []models.Activity, now time.Time) error {
+	dayStart, dayEnd := e.day(activity.StartedAt)
+
+	dayScore, dayMinutes := 0, 0
+	for _, other := range existing {
+		if other.ReviewStatus == models.ReviewStatusRejected {
+			continue
+		}
+		if other.StartedAt.Before(activity.EndedAt) && activity.StartedAt.Before(other.EndedAt) {
+			return ErrOverlap
+		}
+		if !other.StartedAt.Before(dayStart) && other.StartedAt.Before(dayEnd) {
+			dayScore += other.ScoreAwarded
+			dayMinutes += other.DurationMinutes
+		}
+	}
+
+	var flags []string
+	score := activity.DurationMinutes * e.rules.Activities[activity.Type].PointsPerMinute
+	if claimedScore != nil && *claimedScore > score {
+		flags = append(flags, FlagClaimedScoreMismatch)
+	}
+	if score > e.rules.MaxSessionScore {
+		score = e.rules.MaxSessionScore
+		flags = append(flags, FlagSessionScoreCapped)
+	}
+	if remaining := max(e.rules.MaxDailyScore-dayScore, 0); score > remaining {
+		score = remaining
+		flags = append(flags, FlagDailyScoreCapped)
+	}
+	if activity.DurationMinutes >= e.rules.FlagSessionMinutes {
+		flags = append(flags, FlagLongSession)
+	}
+	if dayMinutes+activity.DurationMinutes > e.rules.FlagDailyMinutes {
+		flags = append(flags, FlagDailyMinutes)
+	}
+	if now.Sub(activity.StartedAt) > time.Duration(e.rules.FlagBackdatedHours)*time.Hour {
+		flags = append(flags, FlagBackdated)
+	}
+
+	// ตั้งทุก field ของผลลัพธ์ใหม่ เผื่อ activity เคยผ่าน Score มาแล้ว (เช่น retry ของ transaction)
+	activity.ScoreAwarded = score
+	activity.Flags = flags
+	activity.ReviewStatus = ""
+	if len(flags) > 0 {
+		activity.ReviewStatus = models.ReviewStatusPending
+	}
+	return nil
+}
+
+// day คืนช่วงเวลาของวันที่ t อยู่ ตาม timezone ที่ส่งให้ NewEngine
+func (e *Engine) day(t time.Time) (start, end time.Time) {
+	local := t.In(e.loc)
+	start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, e.loc)
+	return start, start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,233 @@
+package scoring_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"meerank/models"
+	"meerank/scoring"
+)
+
+// bangkok คือเวลาไทย (ไม่มี daylight saving จึงใช้ offset คงที่ได้)
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+// now คือเที่ยงวันที่ 17 ตามเวลาไทย (05:00 UTC)
+var now = time.Date(2026, 10, 17, 12, 0, 0, 0, bangkok)
+
+func newEngine(t *testing.T) *scoring.Engine {
+	t.Helper()
+	engine, err := scoring.NewEngine(scoring.DefaultRules(), bangkok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+// at คือเวลา hour:minute ของวันที่ day ตุลาคม 2026 ตามเวลาไทย
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 10, day, hour, minute, 0, 0, bangkok)
+}
+
+func activity(activityType string, start time.Time, minutes int) *models.Activity {
+	return &models.Activity{
+		Type:            activityType,
+		StartedAt:       start,
+		EndedAt:         start.Add(time.Duration(minutes) * time.Minute),
+		DurationMinutes: minutes,
+	}
+}
+
+func scored(start time.Time, minutes, score int) models.Activity {
+	a := activity("exercise", start, minutes)
+	a.ScoreAwarded = score
+	return *a
+}
+
+func TestCheck(t *testing.T) {
+	engine := newEngine(t)
+
+	backwards := activity("exercise", at(17, 11, 0), 30)
+	backwards.EndedAt = backwards.StartedAt.Add(-30 * time.Minute)
+
+	tests := []struct {
+		name     string
+		activity *models.Activity
+		want     error
+	}{
+		{"valid", activity("exercise", at(17, 11, 0), 30), nil},
+		{"longest allowed", activity("walk", at(17, 7, 0), 240), nil},
+		{"unknown type", activity("teleport", at(17, 11, 0), 30), scoring.ErrUnknownType},
+		{"zero minutes", activity("exercise", at(17, 11, 0), 0), scoring.ErrInvalidDuration},
+		{"negative minutes", activity("exercise", at(17, 11, 0), -30), scoring.ErrInvalidDuration},
+		{"ends before it starts", backwards, scoring.ErrInvalidDuration},
+		{"longer than a session", activity("walk", at(17, 7, 0), 241), scoring.ErrSessionTooLong},
+		{"ends within clock skew", activity("exercise", at(17, 11, 35), 30), nil},
+		{"ends in the future", activity("exercise", at(17, 11, 40), 30), scoring.ErrFutureActivity},
+		{"starts in the future", activity("exercise", at(17, 13, 0), 30), scoring.ErrFutureActivity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.Check(tt.activity, now); !errors.Is(err, tt.want) {
+				t.Errorf("Check = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	engine := newEngine(t)
+	claim := func(score int) *int { return &score }
+
+	rejected := scored(at(17, 10, 0), 60, 5000)
+	rejected.ReviewStatus = models.ReviewStatusRejected
+
+	tests := []struct {
+		name     string
+		activity *models.Activity
+		claimed  *int
+		existing []models.Activity
+		score    int
+		flags    []string
+	}{
+		{
+			name:     "clean",
+			activity: activity("exercise", at(17, 11, 0), 30),
+			score:    300,
+		},
+		{
+			name:     "claim matches",
+			activity: activity("exercise", at(17, 11, 0), 30),
+			claimed:  claim(300),
+			score:    300,
+		},
+		{
+			name:     "negative claim is ignored",
+			activity: activity("exercise", at(17, 11, 0), 30),
+			claimed:  claim(-50),
+			score:    300,
+		},
+		{
+			name:     "claim above computed score",
+			activity: activity("exercise", at(17, 11, 0), 30),
+			claimed:  claim(9999),
+			score:    300,
+			flags:    []string{scoring.FlagClaimedScoreMismatch},
+		},
+		{
+			name:     "per-session cap",
+			activity: activity("run", at(17, 9, 0), 110),
+			score:    1500,
+			flags:    []string{scoring.FlagSessionScoreCapped},
+		},
+		{
+			name:     "per-day cap",
+			activity: activity("exercise", at(17, 11, 0), 30),
+			existing: []models.Activity{scored(at(17, 8, 0), 60, 4800)},
+			score:    200,
+			flags:    []string{scoring.FlagDailyScoreCapped},
+		},
+		{
+			name:     "per-day cap reached",
+			activity: activity("exercise", at(17, 11, 0), 30),
+			existing: []models.Activity{scored(at(17, 8, 0), 60, 5000)},
+			score:    0,
+			flags:    []string{scoring.FlagDailyScoreCapped},
+		},
+		{
+			// 23:00 วันที่ 16 กับ 00:00 วันที่ 17 ตามเวลาไทยเป็นวันเดียวกันใน UTC
+			name:     "previous local day does not count",
+			activity: activity("exercise", at(17, 0, 0), 30),
+			existing: []models.Activity{scored(at(16, 23, 0), 60, 5000)},
+			score:    300,
+		},
+		{
+			name:     "rejected activities do not count",
+			activity: activity("exercise", at(17, 10, 30), 30),
+			existing: []models.Activity{rejected},
+			score:    300,
+		},
+		{
+			name:     "adjacent activity does not overlap",
+			activity: activity("exercise", at(17, 11, 0), 30),
+			existing: []models.Activity{scored(at(17, 10, 30), 30, 300)},
+			score:    300,
+		},
+		{
+			name:     "long session",
+			activity: activity("exercise", at(17, 9, 0), 120),
+			score:    1200,
+			flags:    []string{scoring.FlagLongSession},
+		},
+		{
+			name:     "daily minutes",
+			activity: activity("walk", at(17, 9, 30), 110),
+			existing: []models.Activity{scored(at(17, 6, 0), 200, 1000)},
+			score:    550,
+			flags:    []string{scoring.FlagDailyMinutes},
+		},
+		{
+			name:     "backdated",
+			activity: activity("exercise", now.Add(-49*time.Hour), 30),
+			score:    300,
+			flags:    []string{scoring.FlagBackdated},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.Score(tt.activity, tt.claimed, tt.existing, now); err != nil {
+				t.Fatal(err)
+			}
+			if tt.activity.ScoreAwarded != tt.score {
+				t.Errorf("score = %d, want %d", tt.activity.ScoreAwarded, tt.score)
+			}
+			if !reflect.DeepEqual(tt.activity.Flags, tt.flags) {
+				t.Errorf("flags = %v, want %v", tt.activity.Flags, tt.flags)
+			}
+			// กิจกรรมที่ถูก flag ต้องเข้าคิวตรวจสอบ
+			wantStatus := ""
+			if len(tt.flags) > 0 {
+				wantStatus = models.ReviewStatusPending
+			}
+			if tt.activity.ReviewStatus != wantStatus {
+				t.Errorf("review status = %q, want %q", tt.activity.ReviewStatus, wantStatus)
+			}
+		})
+	}
+}
+
+func TestScoreRejectsOverlap(t *testing.T) {
+	engine := newEngine(t)
+	existing := []models.Activity{scored(at(17, 10, 0), 60, 600)}
+
+	for _, a := range []*models.Activity{
+		activity("exercise", at(17, 10, 30), 60), // เริ่มระหว่างกิจกรรมเดิม
+		activity("exercise", at(17, 9, 30), 60),  // จบระหว่างกิจกรรมเดิม
+		activity("exercise", at(17, 10, 15), 15), // อยู่ในกิจกรรมเดิม
+		activity("exercise", at(17, 9, 0), 180),  // ครอบกิจกรรมเดิม
+	} {
+		if err := engine.Score(a, nil, existing, now); !errors.Is(err, scoring.ErrOverlap) {
+			t.Errorf("%v-%v: err = %v, want ErrOverlap", a.StartedAt.Format("15:04"), a.EndedAt.Format("15:04"), err)
+		}
+	}
+}
+
+func TestScoreResetsReusedActivity(t *testing.T) {
+	engine := newEngine(t)
+	a := activity("exercise", at(17, 11, 0), 30)
+
+	// ครั้งแรกติดเพดานรายวัน ครั้งที่สอง (เช่น transaction ถูก retry) ไม่ติดแล้ว
+	if err := engine.Score(a, nil, []models.Activity{scored(at(17, 8, 0), 60, 5000)}, now); err != nil {
+		t.Fatal(err)
+	}
+	if a.ReviewStatus != models.ReviewStatusPending {
+		t.Fatalf("review status = %q, want pending", a.ReviewStatus)
+	}
+	if err := engine.Score(a, nil, nil, now); err != nil {
+		t.Fatal(err)
+	}
+	if a.ScoreAwarded != 300 || a.Flags != nil || a.ReviewStatus != "" {
+		t.Errorf("score/flags/status = %d/%v/%q, want 300/[]/empty", a.ScoreAwarded, a.Flags, a.ReviewStatus)
+	}
+}
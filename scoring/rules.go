@@ -0,0 +1,104 @@
+package scoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ActivityRule คือกติกาการคิดคะแนนของกิจกรรมหนึ่งประเภท
+type ActivityRule struct {
+	PointsPerMinute int `json:"points_per_minute"`
+}
+
+// Rules คือกติกาทั้งหมดของระบบคิดคะแนน
+// โหลดจากไฟล์ JSON ได้ (field ที่ไม่ได้ระบุจะใช้ค่าจาก DefaultRules)
+type Rules struct {
+	// Activities คือประเภทกิจกรรมที่รับได้ ประเภทที่ไม่อยู่ในนี้จะถูกปฏิเสธ
+	Activities map[string]ActivityRule `json:"activities"`
+
+	// MaxSessionMinutes คือความยาวสูงสุดของกิจกรรมหนึ่งครั้ง (ยาวกว่านี้ถูกปฏิเสธ)
+	MaxSessionMinutes int `json:"max_session_minutes"`
+	// MaxSessionScore คือคะแนนสูงสุดที่ได้จากกิจกรรมหนึ่งครั้ง
+	MaxSessionScore int `json:"max_session_score"`
+	// MaxDailyScore คือคะแนนสูงสุดที่ได้ต่อวัน (ตัดรอบวันตาม timezone ของ leaderboard ดู NewEngine)
+	MaxDailyScore int `json:"max_daily_score"`
+
+	// FlagSessionMinutes: กิจกรรมที่ยาวตั้งแต่ค่านี้จะถูกส่งเข้าคิวตรวจสอบ
+	FlagSessionMinutes int `json:"flag_session_minutes"`
+	// FlagDailyMinutes: ถ้านาทีรวมของวันเกินค่านี้จะถูกส่งเข้าคิวตรวจสอบ
+	FlagDailyMinutes int `json:"flag_daily_minutes"`
+	// FlagBackdatedHours: กิจกรรมที่เริ่มก่อนเวลาปัจจุบันเกินค่านี้จะถูกส่งเข้าคิวตรวจสอบ
+	FlagBackdatedHours int `json:"flag_backdated_hours"`
+}
+
+// DefaultRules คือกติกาที่ใช้เมื่อไม่ได้ระบุไฟล์กติกา (config.Scoring.RulesFile)
+func DefaultRules() Rules {
+	return Rules{
+		Activities: map[string]ActivityRule{
+			"exercise": {PointsPerMinute: 10},
+			"walk":     {PointsPerMinute: 5},
+			"run":      {PointsPerMinute: 15},
+		},
+		MaxSessionMinutes:  240,
+		MaxSessionScore:    1500,
+		MaxDailyScore:      5000,
+		FlagSessionMinutes: 120,
+		FlagDailyMinutes:   300,
+		FlagBackdatedHours: 48,
+	}
+}
+
+// Validate ตรวจว่ากติกาใช้งานได้จริง
+func (r Rules) Validate() error {
+	if len(r.Activities) == 0 {
+		return errors.New("scoring rules must define at least one activity type")
+	}
+	for name, rule := range r.Activities {
+		if rule.PointsPerMinute < 0 {
+			return fmt.Errorf("activity %q: points_per_minute must not be negative", name)
+		}
+	}
+	positive := map[string]int{
+		"max_session_minutes":  r.MaxSessionMinutes,
+		"max_session_score":    r.MaxSessionScore,
+		"max_daily_score":      r.MaxDailyScore,
+		"flag_session_minutes": r.FlagSessionMinutes,
+		"flag_daily_minutes":   r.FlagDailyMinutes,
+		"flag_backdated_hours": r.FlagBackdatedHours,
+	}
+	for name, value := range positive {
+		if value <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	return nil
+}
+
+// LoadRules อ่านกติกาจากไฟล์ JSON ที่ path
+// ถ้า path ว่างจะใช้ DefaultRules
+func LoadRules(path string) (Rules, error) {
+	rules := DefaultRules()
+	if path == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, fmt.Errorf("read scoring rules file: %w", err)
+	}
+	// ถ้าไฟล์ระบุ activities มา ให้แทนที่รายการเริ่มต้นทั้งหมด ไม่ใช่รวมกัน
+	rules.Activities = nil
+	// field ที่ไม่รู้จักถือว่าผิด (เช่น timezone ซึ่งตอนนี้ใช้ค่าเดียวกับ leaderboard) จะได้ไม่ถูกมองข้ามเงียบๆ
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return Rules{}, fmt.Errorf("parse scoring rules file: %w", err)
+	}
+	if rules.Activities == nil {
+		rules.Activities = DefaultRules().Activities
+	}
+	return rules, nil
+}
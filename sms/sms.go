@@ -0,0 +1,80 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Sender คือ interface สำหรับผู้ให้บริการส่ง SMS
+// เปลี่ยน gateway จริงได้โดยไม่ต้องแก้ Handler
+type Sender interface {
+	Send(ctx context.Context, phone, message string) error
+}
+
+// NewSender เลือก Sender ตามชื่อผู้ให้บริการ (config.SMS.Provider ค่าเริ่มต้นคือ console)
+func NewSender(provider string) (Sender, error) {
+	switch provider {
+	case "", "console":
+		return ConsoleSender{}, nil
+	case "memory":
+		return NewMemorySender(), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q (expected console or memory)", provider)
+	}
+}
+
+// --- Console Sender ---
+
+// ConsoleSender พิมพ์ข้อความลง log แทนการส่งจริง ใช้สำหรับทดสอบบนเครื่อง
+type ConsoleSender struct{}
+
+func (ConsoleSender) Send(ctx context.Context, phone, message string) error {
+	log.Printf("[SMS] to %s: %s", phone, message)
+	return nil
+}
+
+// --- In-Memory Sender ---
+
+// Message คือ SMS หนึ่งข้อความที่ MemorySender เก็บไว้
+type Message struct {
+	Phone   string
+	Message string
+}
+
+// MemorySender เก็บข้อความที่ส่งไว้ในหน่วยความจำ เพื่อให้เทสต์อ่านรหัส OTP ได้
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(ctx context.Context, phone, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, Message{Phone: phone, Message: message})
+	return nil
+}
+
+// Messages คืนสำเนาของข้อความทั้งหมดที่ส่งไปแล้ว
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Last คืนข้อความล่าสุดที่ส่งถึงเบอร์ที่ระบุ
+func (s *MemorySender) Last(phone string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].Phone == phone {
+			return s.messages[i], true
+		}
+	}
+	return Message{}, false
+}
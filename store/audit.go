@@ -0,0 +1,23 @@
+package store
+
+import "meerank/models"
+
+// auditMatches ตรวจ entry กับตัวกรองของ query (ใช้กับ backend ที่กรองเองในหน่วยความจำ)
+func auditMatches(entry *models.AuditEntry, query AuditQuery) bool {
+	if query.ActorID != "" && entry.ActorID != query.ActorID {
+		return false
+	}
+	if query.TargetID != "" && entry.TargetID != query.TargetID {
+		return false
+	}
+	if query.Action != "" && entry.Action != query.Action {
+		return false
+	}
+	if query.From != nil && entry.CreatedAt.Before(*query.From) {
+		return false
+	}
+	if query.To != nil && !entry.CreatedAt.Before(*query.To) {
+		return false
+	}
+	return true
+}
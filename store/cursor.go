@@ -0,0 +1,93 @@
+package store
+
+import (
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"meerank/models"
+)
+
+// timeCursor คือตำแหน่งของรายการสุดท้ายในหน้าที่แล้ว (เวลา + ID สำหรับกรณีเวลาเท่ากัน)
+type timeCursor struct {
+	At time.Time
+	ID string
+}
+
+func encodeTimeCursor(at time.Time, id string) string {
+	raw := strconv.FormatInt(at.UnixNano(), 10) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTimeCursor(cursor string) (*timeCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &timeCursor{At: time.Unix(0, n).UTC(), ID: id}, nil
+}
+
+// before บอกว่า (at, id) อยู่หลัง cursor ในลำดับใหม่ไปเก่าหรือไม่
+func (c *timeCursor) before(at time.Time, id string) bool {
+	if c == nil {
+		return true
+	}
+	return at.Before(c.At) || (at.Equal(c.At) && id < c.ID)
+}
+
+// sortNewestFirst เรียงตามเวลาจากใหม่ไปเก่า (เวลาเท่ากันเรียงตาม ID จากมากไปน้อย)
+func sortNewestFirst[T any](items []T, key func(item T) (time.Time, string)) {
+	sort.Slice(items, func(i, j int) bool {
+		ti, idi := key(items[i])
+		tj, idj := key(items[j])
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return idi > idj
+	})
+}
+
+// cutPage ตัดผลลัพธ์ที่ดึงมา limit+1 รายการให้เหลือหนึ่งหน้า พร้อมสร้าง cursor หน้าถัดไป
+func cutPage[T any](items []T, limit int, key func(item T) (time.Time, string)) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	at, id := key(items[limit-1])
+	return items, encodeTimeCursor(at, id)
+}
+
+func activityKey(a models.Activity) (time.Time, string)     { return a.StartedAt, a.ID }
+func reviewKey(r models.ActivityReview) (time.Time, string) { return r.CreatedAt, r.ID }
+func auditKey(e models.AuditEntry) (time.Time, string)      { return e.CreatedAt, e.ID }
+
+func newActivityPage(activities []models.Activity, limit int) *ActivityPage {
+	page := &ActivityPage{}
+	page.Activities, page.NextCursor = cutPage(activities, limit, activityKey)
+	return page
+}
+
+func newReviewPage(reviews []models.ActivityReview, limit int) *ReviewPage {
+	page := &ReviewPage{}
+	page.Reviews, page.NextCursor = cutPage(reviews, limit, reviewKey)
+	return page
+}
+
+func newAuditPage(entries []models.AuditEntry, limit int) *AuditPage {
+	page := &AuditPage{}
+	page.Entries, page.NextCursor = cutPage(entries, limit, auditKey)
+	return page
+}
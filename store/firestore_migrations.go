@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"meerank/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// collectionSchemaMigrations เก็บ migration ของ Firestore ที่รันไปแล้ว (Document ID คือเลขเวอร์ชัน)
+const collectionSchemaMigrations = "schema_migrations"
+
+// firestoreMigration คือการแปลงข้อมูลที่บันทึกไว้แล้วหนึ่งขั้น (Firestore ไม่มี schema แต่ข้อมูลเดิมอาจต้องแปลง)
+// ห้ามแก้ไข migration ที่ปล่อยไปแล้ว ให้เพิ่มเวอร์ชันใหม่ต่อท้ายแทน
+// แต่ละขั้นไม่ได้อยู่ใน transaction เดียว จึงต้องรันซ้ำได้ถ้าล้มเหลวกลางทาง
+type firestoreMigration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, s *FirestoreStore) error
+}
+
+var firestoreMigrations = []firestoreMigration{
+	{
+		Version: 1,
+		Name:    "normalize phone numbers to E.164 and create phone_index",
+		Up: func(ctx context.Context, s *FirestoreStore) error {
+			users, err := s.ListUsers(ctx)
+			if err != nil {
+				return err
+			}
+			plan := planPhoneBackfill(users)
+
+			var writes []func(batch *firestore.WriteBatch)
+			for id, phone := range plan.Phones {
+				ref := s.users().Doc(id)
+				writes = append(writes, func(batch *firestore.WriteBatch) {
+					batch.Update(ref, []firestore.Update{{Path: "phone", Value: phone}})
+				})
+			}
+			now := time.Now().UTC()
+			for phone, id := range plan.Index {
+				ref := s.phoneIndex(phone)
+				writes = append(writes, func(batch *firestore.WriteBatch) {
+					batch.Set(ref, models.PhoneIndex{UserID: id, CreatedAt: now})
+				})
+			}
+
+			// เขียนทีละไม่เกิน 500 (ขีดจำกัดของ Batched Writes)
+			for start := 0; start < len(writes); start += 500 {
+				batch := s.client.Batch()
+				for _, write := range writes[start:min(start+500, len(writes))] {
+					write(batch)
+				}
+				if _, err := batch.Commit(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "key login_otps by purpose and owner",
+		Up: func(ctx context.Context, s *FirestoreStore) error {
+			// Document ID เปลี่ยนจาก hash ของเบอร์เป็น hash ของ models.LoginOTPID
+			// รหัสเดิมมีอายุไม่กี่นาทีและจะไม่ถูกอ่านอีก จึงลบทิ้งทั้งหมด
+			return s.deleteQuery(ctx, s.client.Collection(models.CollectionLoginOTPs).Query)
+		},
+	},
+}
+
+// Migrate รัน migration ของ Firestore ที่ยังไม่เคยรันตามลำดับเวอร์ชัน
+// ควรรันตอนที่ยังไม่มีคำขอเข้ามา เพราะแต่ละขั้นอ่านและเขียนข้อมูลนอก transaction
+func (s *FirestoreStore) Migrate(ctx context.Context) error {
+	for _, m := range firestoreMigrations {
+		ref := s.client.Collection(collectionSchemaMigrations).Doc(strconv.Itoa(m.Version))
+		_, err := ref.Get(ctx)
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("read schema_migrations: %w", err)
+		}
+
+		if err := m.Up(ctx, s); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if _, err := ref.Set(ctx, schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		log.Printf("Applied Firestore migration %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
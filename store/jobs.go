@@ -0,0 +1,48 @@
+package store
+
+import (
+	"time"
+
+	"meerank/models"
+)
+
+// applyResetBatch นับผู้ใช้หนึ่ง batch เข้าความคืบหน้าของ job และเลื่อน Cursor
+// คืน snapshot ของผู้ใช้ที่มีสถิติไม่เป็น 0 (คนที่ต้องรีเซ็ต) และบอกว่าเป็น batch สุดท้ายหรือไม่
+func applyResetBatch(job *models.Job, users []models.User, limit int) ([]models.StatsSnapshot, bool) {
+	snapshots := []models.StatsSnapshot{}
+	for _, user := range users {
+		job.Processed++
+		if user.Minute == 0 && user.Score == 0 && user.NumberTree == 0 && user.TreeProgress == 0 {
+			continue
+		}
+		job.Changed++
+		job.ClearedMinute += user.Minute
+		job.ClearedScore += user.Score
+		job.ClearedNumberTree += user.NumberTree
+		job.ClearedTreeProgress += user.TreeProgress
+		snapshots = append(snapshots, models.StatsSnapshot{
+			ID:           job.ID + "_" + user.ID,
+			JobID:        job.ID,
+			UserID:       user.ID,
+			Minute:       user.Minute,
+			Score:        user.Score,
+			NumberTree:   user.NumberTree,
+			TreeProgress: user.TreeProgress,
+		})
+	}
+	if len(users) > 0 {
+		job.Cursor = users[len(users)-1].ID
+	}
+	job.UpdatedAt = time.Now()
+	return snapshots, len(users) < limit
+}
+
+// applyRestoreBatch นับ snapshot หนึ่ง batch เข้าความคืบหน้าของการ undo และเลื่อน UndoCursor
+func applyRestoreBatch(job *models.Job, snapshots []models.StatsSnapshot, limit int) bool {
+	job.Restored += len(snapshots)
+	if len(snapshots) > 0 {
+		job.UndoCursor = snapshots[len(snapshots)-1].UserID
+	}
+	job.UpdatedAt = time.Now()
+	return len(snapshots) < limit
+}
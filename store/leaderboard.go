@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	"meerank/models"
+	"meerank/periods"
+)
+
+// leaderboardPos คือตำแหน่งบน leaderboard ตามลำดับ (number_tree desc, score desc, user ID asc)
+type leaderboardPos struct {
+	NumberTree int
+	Score      int
+	UserID     string
+}
+
+func (r LeaderboardRow) pos() leaderboardPos {
+	return leaderboardPos{NumberTree: r.NumberTree, Score: r.Score, UserID: r.UserID}
+}
+
+// ahead บอกว่า p อยู่ก่อน other ตามลำดับของ leaderboard หรือไม่
+func (p leaderboardPos) ahead(other leaderboardPos) bool {
+	if p.NumberTree != other.NumberTree {
+		return p.NumberTree > other.NumberTree
+	}
+	if p.Score != other.Score {
+		return p.Score > other.Score
+	}
+	return p.UserID < other.UserID
+}
+
+// tiedWith บอกว่าสองตำแหน่งได้อันดับร่วมกันหรือไม่
+func (p leaderboardPos) tiedWith(other leaderboardPos) bool {
+	return p.NumberTree == other.NumberTree && p.Score == other.Score
+}
+
+func userLeaderboardRow(user *models.User) LeaderboardRow {
+	name := user.Name
+	if user.DeletedAt != nil {
+		name = models.DeletedUserName
+	}
+	return LeaderboardRow{UserID: user.ID, Name: name, NumberTree: user.NumberTree, Score: user.Score}
+}
+
+func periodStatsLeaderboardRow(stats *models.PeriodStats) LeaderboardRow {
+	return LeaderboardRow{UserID: stats.UserID, Name: stats.Name, NumberTree: stats.NumberTree, Score: stats.Score}
+}
+
+func standingLeaderboardRow(standing *models.SeasonStanding) LeaderboardRow {
+	return LeaderboardRow{UserID: standing.UserID, Name: standing.Name, NumberTree: standing.NumberTree, Score: standing.Score}
+}
+
+func encodeLeaderboardCursor(p leaderboardPos) string {
+	raw := fmt.Sprintf("%d|%d|%s", p.NumberTree, p.Score, p.UserID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeLeaderboardCursor(cursor string) (*leaderboardPos, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var p leaderboardPos
+	if n, err := fmt.Sscanf(string(raw), "%d|%d|%s", &p.NumberTree, &p.Score, &p.UserID); err != nil || n != 3 {
+		return nil, ErrInvalidCursor
+	}
+	return &p, nil
+}
+
+// leaderboardBoard ระบุว่าจัดอันดับจากข้อมูลชุดไหน
+// Period ว่างหมายถึงยอดตลอดกาลจากเอกสาร users มิฉะนั้นใช้ PeriodStats ของ Period + Key
+type leaderboardBoard struct {
+	Period string
+	Key    string
+	// Final ใช้ standings ของฤดูกาลที่ปิดแล้ว (Key คือ ID ของฤดูกาล) แทน PeriodStats
+	Final bool
+}
+
+func (b leaderboardBoard) allTime() bool {
+	return b.Period == ""
+}
+
+// seasonBoard คือ board ของฤดูกาล ฤดูกาลที่ปิดแล้วอ่านจาก standings ที่บันทึกไว้
+func seasonBoard(season *models.Season) leaderboardBoard {
+	return leaderboardBoard{
+		Period: models.PeriodSeason,
+		Key:    season.ID,
+		Final:  season.Status == models.SeasonStatusClosed,
+	}
+}
+
+// resolveBoard แปลงชื่อช่วงเวลาเป็นช่วงเวลาปัจจุบันตาม Calendar
+func resolveBoard(cal *periods.Calendar, period string, now time.Time) leaderboardBoard {
+	if period == "" || period == models.PeriodAll {
+		return leaderboardBoard{}
+	}
+	return leaderboardBoard{Period: period, Key: cal.Key(period, now)}
+}
+
+// leaderboardSource คือสิ่งที่แต่ละ backend ต้องมีเพื่อใช้ตรรกะการจัดอันดับร่วมกันด้านล่าง
+type leaderboardSource interface {
+	// leaderboardAfter คืนแถวที่อยู่หลัง after (nil = เริ่มจากอันดับแรก) ตามลำดับ ไม่เกิน limit แถว
+	leaderboardAfter(ctx context.Context, board leaderboardBoard, after *leaderboardPos, limit int) ([]LeaderboardRow, error)
+	// leaderboardBefore คืนแถวที่อยู่ติดกันก่อน before ไม่เกิน limit แถว เรียงตามลำดับ
+	leaderboardBefore(ctx context.Context, board leaderboardBoard, before leaderboardPos, limit int) ([]LeaderboardRow, error)
+	// leaderboardRow คืนแถวของผู้ใช้ (ErrNotFound ถ้าไม่อยู่บน leaderboard)
+	leaderboardRow(ctx context.Context, board leaderboardBoard, userID string) (*LeaderboardRow, error)
+	// countAhead นับแถวที่ number_tree/score ดีกว่า p แบบไม่นับคนที่เสมอกัน
+	countAhead(ctx context.Context, board leaderboardBoard, p leaderboardPos) (int, error)
+}
+
+func leaderboardPage(ctx context.Context, src leaderboardSource, board leaderboardBoard, query LeaderboardQuery) (*LeaderboardPage, error) {
+	after, err := decodeLeaderboardCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := src.leaderboardAfter(ctx, board, after, query.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &LeaderboardPage{PeriodKey: board.Key, Rows: rows}
+	if len(rows) > query.Limit {
+		page.Rows = rows[:query.Limit]
+		page.NextCursor = encodeLeaderboardCursor(page.Rows[query.Limit-1].pos())
+	}
+	if err := rankRows(ctx, src, board, page.Rows); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func leaderboardAround(ctx context.Context, src leaderboardSource, board leaderboardBoard, userID string, n int) (*LeaderboardAround, error) {
+	me, err := src.leaderboardRow(ctx, board, userID)
+	if err != nil {
+		return nil, err
+	}
+	above, err := src.leaderboardBefore(ctx, board, me.pos(), n)
+	if err != nil {
+		return nil, err
+	}
+	pos := me.pos()
+	below, err := src.leaderboardAfter(ctx, board, &pos, n)
+	if err != nil {
+		return nil, err
+	}
+
+	// จัดอันดับทั้งช่วงต่อเนื่องกันในครั้งเดียว แล้วแยกกลับเป็นสามส่วน
+	rows := make([]LeaderboardRow, 0, len(above)+1+len(below))
+	rows = append(rows, above...)
+	rows = append(rows, *me)
+	rows = append(rows, below...)
+	if err := rankRows(ctx, src, board, rows); err != nil {
+		return nil, err
+	}
+	return &LeaderboardAround{
+		PeriodKey: board.Key,
+		Above:     rows[:len(above)],
+		Me:        rows[len(above)],
+		Below:     rows[len(above)+1:],
+	}, nil
+}
+
+// rankRows ใส่อันดับให้แถวที่ต่อเนื่องกันบน leaderboard โดยคนที่เสมอกันได้อันดับเดียวกัน
+// แถวแรกนับจากจำนวนคนที่อยู่ข้างหน้า แต่กลุ่มที่เสมอกับแถวแรกอาจเริ่มก่อนช่วงนี้ (เช่นหน้าถูกตัดกลางกลุ่ม)
+// จึงนับคนข้างหน้าใหม่อีกครั้งที่กลุ่มถัดไป แล้วไล่ต่อจากตรงนั้น
+func rankRows(ctx context.Context, src leaderboardSource, board leaderboardBoard, rows []LeaderboardRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	ahead, err := src.countAhead(ctx, board, rows[0].pos())
+	if err != nil {
+		return err
+	}
+	rows[0].Rank = ahead + 1
+
+	next := -1 // แถวแรกที่ไม่เสมอกับแถวแรก
+	for i := 1; i < len(rows); i++ {
+		switch {
+		case rows[i].pos().tiedWith(rows[i-1].pos()):
+			rows[i].Rank = rows[i-1].Rank
+		case next < 0:
+			next = i
+			ahead, err := src.countAhead(ctx, board, rows[i].pos())
+			if err != nil {
+				return err
+			}
+			rows[i].Rank = ahead + 1
+		default:
+			rows[i].Rank = rows[next].Rank + i - next
+		}
+	}
+	return nil
+}
+
+// --- PeriodStats ---
+
+// periodStatsDelta คือค่าที่จะบวกเข้า PeriodStats ของทุกช่วงเวลาที่เวลาหนึ่งอยู่
+type periodStatsDelta struct {
+	Minute     int
+	Score      int
+	NumberTree int
+}
+
+func (d periodStatsDelta) isZero() bool {
+	return d == periodStatsDelta{}
+}
+
+func periodStatsID(period, key, userID string) string {
+	return period + "_" + key + "_" + userID
+}
+
+// periodStatsDocs คืนเอกสาร PeriodStats (ยังไม่มียอด) ของทุกช่วงเวลาที่ at อยู่
+// รวมถึงฤดูกาลใน seasons ที่ยังเปิดอยู่และครอบคลุม at
+func periodStatsDocs(cal *periods.Calendar, seasons []models.Season, user *models.User, at time.Time) []models.PeriodStats {
+	docs := make([]models.PeriodStats, 0, len(models.AggregatedPeriods)+len(seasons))
+	add := func(period, key string) {
+		docs = append(docs, models.PeriodStats{
+			ID:        periodStatsID(period, key, user.ID),
+			Period:    period,
+			PeriodKey: key,
+			UserID:    user.ID,
+			Name:      user.Name,
+		})
+	}
+	for _, period := range models.AggregatedPeriods {
+		add(period, cal.Key(period, at))
+	}
+	for i := range seasons {
+		if seasons[i].Status == models.SeasonStatusOpen && seasons[i].Contains(at) {
+			add(models.PeriodSeason, seasons[i].ID)
+		}
+	}
+	return docs
+}
+
+// tracksPeriodStats บอกว่าการเปลี่ยนแปลงนี้ต้องอัปเดต PeriodStats หรือไม่ (เฉพาะสมาชิก)
+func tracksPeriodStats(user *models.User, delta periodStatsDelta) bool {
+	return user.Role == models.RoleMember && !delta.isZero()
+}
+
+// --- Seasons ---
+
+// newSeasonStandings จัดอันดับยอดสะสมของฤดูกาลเป็น standings (คนที่เสมอกันได้อันดับเดียวกัน)
+func newSeasonStandings(seasonID string, stats []models.PeriodStats) []models.SeasonStanding {
+	sort.Slice(stats, func(i, j int) bool {
+		return periodStatsLeaderboardRow(&stats[i]).pos().ahead(periodStatsLeaderboardRow(&stats[j]).pos())
+	})
+	standings := make([]models.SeasonStanding, 0, len(stats))
+	for i := range stats {
+		rank := i + 1
+		if i > 0 && periodStatsLeaderboardRow(&stats[i]).pos().tiedWith(periodStatsLeaderboardRow(&stats[i-1]).pos()) {
+			rank = standings[i-1].Rank
+		}
+		standings = append(standings, models.SeasonStanding{
+			ID:         seasonID + "_" + stats[i].UserID,
+			SeasonID:   seasonID,
+			Rank:       rank,
+			UserID:     stats[i].UserID,
+			Name:       stats[i].Name,
+			Minute:     stats[i].Minute,
+			Score:      stats[i].Score,
+			NumberTree: stats[i].NumberTree,
+		})
+	}
+	return standings
+}
+
+// sortSeasons เรียงฤดูกาลจากที่เริ่มล่าสุดไปเก่าสุด
+func sortSeasons(seasons []models.Season) {
+	sort.Slice(seasons, func(i, j int) bool {
+		if !seasons[i].StartsAt.Equal(seasons[j].StartsAt) {
+			return seasons[i].StartsAt.After(seasons[j].StartsAt)
+		}
+		return seasons[i].ID > seasons[j].ID
+	})
+}
@@ -0,0 +1,176 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"meerank/models"
+	"meerank/store"
+)
+
+// leaderboardUsers คือสมาชิกบน leaderboard ตลอดกาลเรียงตามลำดับที่คาดไว้
+// tieFirst กับ tieSecond มี number_tree และ score เท่ากัน จึงเรียงตาม user ID
+type leaderboardUsers struct {
+	trees, tieFirst, tieSecond, score, zero, admin *models.User
+}
+
+// seedLeaderboard สร้างสมาชิกที่จะได้อันดับ 1, 2, 2, 4, 5 และ admin ที่ไม่อยู่บน leaderboard
+func seedLeaderboard(t *testing.T, st store.Store) leaderboardUsers {
+	t.Helper()
+	withStats := func(user *models.User, numberTree, score int) *models.User {
+		t.Helper()
+		_, err := st.UpdateUserStats(context.Background(), user.ID, func(u *models.User) error {
+			u.NumberTree = numberTree
+			u.Score = score
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return getUser(t, st, user.ID)
+	}
+
+	var u leaderboardUsers
+	// ต้นไม้มากกว่ามาก่อนแม้คะแนนน้อยกว่า
+	u.trees = withStats(createUser(t, st, "Trees", ""), 2, 100)
+	u.tieFirst = withStats(createUser(t, st, "Tie", ""), 1, 900)
+	u.tieSecond = withStats(createUser(t, st, "Tie", ""), 1, 900)
+	if u.tieSecond.ID < u.tieFirst.ID {
+		u.tieFirst, u.tieSecond = u.tieSecond, u.tieFirst
+	}
+	u.score = withStats(createUser(t, st, "Score", ""), 1, 500)
+	u.zero = withStats(createUser(t, st, "Zero", ""), 0, 5000)
+
+	admin := &models.User{Name: "Admin", Role: models.RoleAdmin}
+	if _, err := st.CreateUser(context.Background(), admin); err != nil {
+		t.Fatal(err)
+	}
+	u.admin = withStats(admin, 9, 9000)
+	return u
+}
+
+// checkRows ตรวจลำดับผู้ใช้และอันดับของแถว
+func checkRows(t *testing.T, label string, rows []store.LeaderboardRow, users []*models.User, ranks []int) {
+	t.Helper()
+	if len(rows) != len(users) {
+		t.Fatalf("%s: got %d rows %+v, want %d", label, len(rows), rows, len(users))
+	}
+	for i, row := range rows {
+		if row.UserID != users[i].ID || row.Rank != ranks[i] {
+			t.Errorf("%s[%d] = %s (%s) rank %d, want %s (%s) rank %d",
+				label, i, row.UserID, row.Name, row.Rank, users[i].ID, users[i].Name, ranks[i])
+		}
+	}
+}
+
+func TestLeaderboardRanks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		u := seedLeaderboard(t, st)
+
+		page, err := st.Leaderboard(context.Background(), store.LeaderboardQuery{Period: models.PeriodAll, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRows(t, "rows", page.Rows,
+			[]*models.User{u.trees, u.tieFirst, u.tieSecond, u.score, u.zero},
+			[]int{1, 2, 2, 4, 5})
+		if page.NextCursor != "" {
+			t.Errorf("next cursor = %q on the last page", page.NextCursor)
+		}
+	})
+}
+
+func TestLeaderboardCursorAcrossTies(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		u := seedLeaderboard(t, st)
+
+		// หน้าแรกตัดระหว่างคนที่เสมอกัน หน้าถัดไปต้องได้อันดับร่วมเดิม
+		pages := []struct {
+			users []*models.User
+			ranks []int
+		}{
+			{[]*models.User{u.trees, u.tieFirst}, []int{1, 2}},
+			{[]*models.User{u.tieSecond, u.score}, []int{2, 4}},
+			{[]*models.User{u.zero}, []int{5}},
+		}
+		cursor := ""
+		for i, want := range pages {
+			page, err := st.Leaderboard(ctx, store.LeaderboardQuery{Period: models.PeriodAll, Cursor: cursor, Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRows(t, "page", page.Rows, want.users, want.ranks)
+			last := i == len(pages)-1
+			if (page.NextCursor == "") != last {
+				t.Fatalf("page %d: next cursor = %q", i+1, page.NextCursor)
+			}
+			cursor = page.NextCursor
+		}
+
+		_, err := st.Leaderboard(ctx, store.LeaderboardQuery{Period: models.PeriodAll, Cursor: "not a cursor", Limit: 2})
+		if !errors.Is(err, store.ErrInvalidCursor) {
+			t.Errorf("bad cursor: err = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func TestLeaderboardAround(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		u := seedLeaderboard(t, st)
+
+		tests := []struct {
+			name         string
+			me           *models.User
+			n            int
+			meRank       int
+			above, below []*models.User
+			aboveRanks   []int
+			belowRanks   []int
+		}{
+			{
+				name:       "top",
+				me:         u.trees,
+				n:          2,
+				meRank:     1,
+				below:      []*models.User{u.tieFirst, u.tieSecond},
+				belowRanks: []int{2, 2},
+			},
+			{
+				name:       "tied",
+				me:         u.tieSecond,
+				n:          1,
+				meRank:     2,
+				above:      []*models.User{u.tieFirst},
+				aboveRanks: []int{2},
+				below:      []*models.User{u.score},
+				belowRanks: []int{4},
+			},
+			{
+				name:       "bottom",
+				me:         u.zero,
+				n:          2,
+				meRank:     5,
+				above:      []*models.User{u.tieSecond, u.score},
+				aboveRanks: []int{2, 4},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				around, err := st.LeaderboardAround(ctx, models.PeriodAll, tt.me.ID, tt.n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkRows(t, "above", around.Above, tt.above, tt.aboveRanks)
+				checkRows(t, "me", []store.LeaderboardRow{around.Me}, []*models.User{tt.me}, []int{tt.meRank})
+				checkRows(t, "below", around.Below, tt.below, tt.belowRanks)
+			})
+		}
+
+		if _, err := st.LeaderboardAround(ctx, models.PeriodAll, u.admin.ID, 2); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("admin: err = %v, want ErrNotFound", err)
+		}
+	})
+}
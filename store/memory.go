@@ -0,0 +1,1093 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"meerank/models"
+	"meerank/periods"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore คือ Store ที่เก็บข้อมูลไว้ในหน่วยความจำ
+// ใช้สำหรับการทดสอบและการรันบนเครื่องโดยไม่ต้องเชื่อมต่อฐานข้อมูลจริง
+type MemoryStore struct {
+	cal *periods.Calendar
+
+	mu          sync.Mutex
+	users       map[string]models.User
+	otps        map[string]models.LoginOTP
+	sessions    map[string]models.Session
+	activities  map[string][]models.Activity // แยกตาม user ID
+	reviews     map[string]models.ActivityReview
+	idempotent  map[string]models.IdempotencyRecord
+	periodStats map[string]models.PeriodStats
+	seasons     map[string]models.Season
+	standings   map[string][]models.SeasonStanding // แยกตาม season ID
+	jobs        map[string]models.Job
+	snapshots   map[string][]models.StatsSnapshot // แยกตาม job ID เรียงตาม user ID
+	audit       []models.AuditEntry
+	friendships map[string]models.Friendship
+	phones      map[string]string // phone_index: เบอร์โทร -> user ID
+}
+
+func NewMemoryStore(cal *periods.Calendar) *MemoryStore {
+	return &MemoryStore{
+		cal:         cal,
+		users:       map[string]models.User{},
+		otps:        map[string]models.LoginOTP{},
+		sessions:    map[string]models.Session{},
+		activities:  map[string][]models.Activity{},
+		reviews:     map[string]models.ActivityReview{},
+		idempotent:  map[string]models.IdempotencyRecord{},
+		periodStats: map[string]models.PeriodStats{},
+		seasons:     map[string]models.Season{},
+		standings:   map[string][]models.SeasonStanding{},
+		jobs:        map[string]models.Job{},
+		snapshots:   map[string][]models.StatsSnapshot{},
+		friendships: map[string]models.Friendship{},
+		phones:      map[string]string{},
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// --- UserStore ---
+
+func (s *MemoryStore) GetUser(ctx context.Context, id string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) GetUsers(ctx context.Context, ids []string) (map[string]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make(map[string]models.User, len(ids))
+	for _, id := range ids {
+		if user, ok := s.users[id]; ok {
+			users[id] = user
+		}
+	}
+	return users, nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, user *models.User) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := newID()
+	if err := s.movePhone(id, "", phoneOf(user.Phone)); err != nil {
+		return "", err
+	}
+	user.ID = id
+	s.users[user.ID] = *user
+	return user.ID, nil
+}
+
+func (s *MemoryStore) UpdateUser(ctx context.Context, id string, update UserUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if update.Name != nil {
+		user.Name = *update.Name
+	}
+	if update.Phone != nil {
+		if err := s.movePhone(id, phoneOf(user.Phone), *update.Phone); err != nil {
+			return err
+		}
+		phone := *update.Phone
+		user.Phone = &phone
+	}
+	if update.Age != nil {
+		age := *update.Age
+		user.Age = &age
+	}
+	if update.Gender != nil {
+		gender := *update.Gender
+		user.Gender = &gender
+	}
+	if update.LastLoginAt != nil {
+		at := *update.LastLoginAt
+		user.LastLoginAt = &at
+	}
+	if update.FirebaseUID != nil {
+		firebaseUID := *update.FirebaseUID
+		user.FirebaseUID = &firebaseUID
+	}
+	if update.ProfileVisibility != nil {
+		user.ProfileVisibility = *update.ProfileVisibility
+	}
+	if update.LeaderboardAlias != nil {
+		user.LeaderboardAlias = *update.LeaderboardAlias
+	}
+	if update.Locale != nil {
+		user.Locale = *update.Locale
+	}
+	s.users[id] = user
+	return nil
+}
+
+// findUser คืนผู้ใช้คนแรกที่ match ตรงกับเงื่อนไข (ต้องถือ lock อยู่แล้ว)
+func (s *MemoryStore) findUser(match func(user *models.User) bool) (*models.User, error) {
+	for _, user := range s.users {
+		if match(&user) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) FindUserByPhone(ctx context.Context, phone string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.phones[phone]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// movePhone ย้ายการจองเบอร์ของผู้ใช้ id จาก oldPhone ไป newPhone (ค่าว่าง = ไม่มีเบอร์) ต้องถือ lock อยู่แล้ว
+func (s *MemoryStore) movePhone(id, oldPhone, newPhone string) error {
+	if oldPhone == newPhone {
+		return nil
+	}
+	if newPhone != "" {
+		if owner, ok := s.phones[newPhone]; ok && owner != id {
+			return ErrPhoneTaken
+		}
+		s.phones[newPhone] = id
+	}
+	if oldPhone != "" && s.phones[oldPhone] == id {
+		delete(s.phones, oldPhone)
+	}
+	return nil
+}
+
+func (s *MemoryStore) FindUserByFirebaseUID(ctx context.Context, firebaseUID string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.findUser(func(user *models.User) bool {
+		return user.FirebaseUID != nil && *user.FirebaseUID == firebaseUID
+	})
+}
+
+func (s *MemoryStore) ListUsers(ctx context.Context) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]models.User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *MemoryStore) IncrementStats(ctx context.Context, id string, delta StatsDelta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Minute += delta.Minute
+	user.Score += delta.Score
+	user.NumberTree += delta.NumberTree
+	s.users[id] = user
+	s.addPeriodStats(&user, time.Now(), periodStatsDelta(delta))
+	return nil
+}
+
+func (s *MemoryStore) UpdateUserStats(ctx context.Context, id string, fn func(user *models.User) error) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	updated := user
+	if err := fn(&updated); err != nil {
+		return nil, err
+	}
+
+	grown := updated.NumberTree - user.NumberTree
+	user.Minute = updated.Minute
+	user.Score = updated.Score
+	user.NumberTree = updated.NumberTree
+	user.TreeProgress = updated.TreeProgress
+	s.users[id] = user
+	if grown > 0 {
+		s.addPeriodStats(&user, time.Now(), periodStatsDelta{NumberTree: grown})
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) CountUsers(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.users), nil
+}
+
+func (s *MemoryStore) ModifyUser(ctx context.Context, id string, fn func(user *models.User) error) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.modifyUser(id, fn)
+}
+
+func (s *MemoryStore) ModifyUserWithAdmins(ctx context.Context, id string, fn func(user *models.User, admins []models.User) error) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admins := s.admins()
+	return s.modifyUser(id, func(user *models.User) error { return fn(user, admins) })
+}
+
+// modifyUser ต้องถือ s.mu อยู่แล้ว
+func (s *MemoryStore) modifyUser(id string, fn func(user *models.User) error) (*models.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	oldPhone := phoneOf(user.Phone)
+	if err := fn(&user); err != nil {
+		return nil, err
+	}
+	if err := s.movePhone(id, oldPhone, phoneOf(user.Phone)); err != nil {
+		return nil, err
+	}
+	user.ID = id
+	s.users[id] = user
+	return &user, nil
+}
+
+// admins ต้องถือ s.mu อยู่แล้ว
+func (s *MemoryStore) admins() []models.User {
+	admins := []models.User{}
+	for _, other := range s.users {
+		if other.Role == models.RoleAdmin {
+			admins = append(admins, other)
+		}
+	}
+	return admins
+}
+
+func (s *MemoryStore) ChangeUserRole(ctx context.Context, id, role string, check func(user *models.User, admins []models.User) error) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := check(&user, s.admins()); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	s.users[id] = user
+	return &user, nil
+}
+
+func (s *MemoryStore) ListUsersToPurge(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range s.users {
+		if purgeDue(&user, now) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (s *MemoryStore) PurgeUser(ctx context.Context, id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || !purgeDue(&user, now) {
+		return ErrNotFound
+	}
+	delete(s.users, id)
+	delete(s.activities, id)
+	if err := s.movePhone(id, phoneOf(user.Phone), ""); err != nil {
+		return err
+	}
+	for sid, session := range s.sessions {
+		if session.UserID == id {
+			delete(s.sessions, sid)
+		}
+	}
+	for rid, review := range s.reviews {
+		if review.UserID == id {
+			delete(s.reviews, rid)
+		}
+	}
+	for pid, stats := range s.periodStats {
+		if stats.UserID == id {
+			delete(s.periodStats, pid)
+		}
+	}
+	for fid, friendship := range s.friendships {
+		if friendship.UserA == id || friendship.UserB == id {
+			delete(s.friendships, fid)
+		}
+	}
+	for oid, otp := range s.otps {
+		if otp.UserID == id || oid == models.LoginOTPID(phoneOf(user.Phone)) {
+			delete(s.otps, oid)
+		}
+	}
+	for rid, record := range s.idempotent {
+		if record.UserID == id {
+			delete(s.idempotent, rid)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SearchUsers(ctx context.Context, query UserQuery) (*UserPage, error) {
+	after, err := decodeUserCursor(query)
+	if err != nil {
+		return nil, err
+	}
+	sortField := userSortField(query)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range s.users {
+		value, ok := userSortValue(&user, sortField)
+		if ok && userMatches(&user, query) && after.userAfter(value, user.ID) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		a, _ := userSortValue(&users[i], sortField)
+		b, _ := userSortValue(&users[j], sortField)
+		if a != b {
+			return (a < b) == query.Ascending
+		}
+		return users[i].ID < users[j].ID
+	})
+	if len(users) > query.Limit+1 {
+		users = users[:query.Limit+1]
+	}
+	return newUserPage(users, query), nil
+}
+
+// --- LeaderboardStore ---
+
+func (s *MemoryStore) Leaderboard(ctx context.Context, query LeaderboardQuery) (*LeaderboardPage, error) {
+	return leaderboardPage(ctx, s, resolveBoard(s.cal, query.Period, time.Now()), query)
+}
+
+func (s *MemoryStore) LeaderboardAround(ctx context.Context, period, userID string, n int) (*LeaderboardAround, error) {
+	return leaderboardAround(ctx, s, resolveBoard(s.cal, period, time.Now()), userID, n)
+}
+
+func (s *MemoryStore) RenameLeaderboardEntries(ctx context.Context, userID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stats := range s.periodStats {
+		if stats.UserID == userID {
+			stats.Name = name
+			s.periodStats[id] = stats
+		}
+	}
+	for _, standings := range s.standings {
+		for i := range standings {
+			if standings[i].UserID == userID {
+				standings[i].Name = name
+			}
+		}
+	}
+	return nil
+}
+
+// leaderboardRows คืนทุกแถวของ board เรียงตามลำดับของ leaderboard
+func (s *MemoryStore) leaderboardRows(board leaderboardBoard) []LeaderboardRow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := []LeaderboardRow{}
+	if board.allTime() {
+		for _, user := range s.users {
+			if user.Role == models.RoleMember {
+				rows = append(rows, userLeaderboardRow(&user))
+			}
+		}
+	} else if board.Final {
+		for _, standing := range s.standings[board.Key] {
+			rows = append(rows, standingLeaderboardRow(&standing))
+		}
+	} else {
+		for _, stats := range s.periodStats {
+			if stats.Period == board.Period && stats.PeriodKey == board.Key {
+				rows = append(rows, periodStatsLeaderboardRow(&stats))
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].pos().ahead(rows[j].pos()) })
+	return rows
+}
+
+func (s *MemoryStore) leaderboardAfter(ctx context.Context, board leaderboardBoard, after *leaderboardPos, limit int) ([]LeaderboardRow, error) {
+	result := []LeaderboardRow{}
+	for _, row := range s.leaderboardRows(board) {
+		if len(result) == limit {
+			break
+		}
+		if after == nil || after.ahead(row.pos()) {
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) leaderboardBefore(ctx context.Context, board leaderboardBoard, before leaderboardPos, limit int) ([]LeaderboardRow, error) {
+	result := []LeaderboardRow{}
+	for _, row := range s.leaderboardRows(board) {
+		if row.pos().ahead(before) {
+			result = append(result, row)
+		}
+	}
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) leaderboardRow(ctx context.Context, board leaderboardBoard, userID string) (*LeaderboardRow, error) {
+	for _, row := range s.leaderboardRows(board) {
+		if row.UserID == userID {
+			return &row, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) countAhead(ctx context.Context, board leaderboardBoard, p leaderboardPos) (int, error) {
+	count := 0
+	for _, row := range s.leaderboardRows(board) {
+		if row.pos().ahead(p) && !row.pos().tiedWith(p) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// addPeriodStats บวกยอดเข้า PeriodStats ทุกช่วงเวลาที่ at อยู่ (ต้องถือ lock อยู่แล้ว)
+func (s *MemoryStore) addPeriodStats(user *models.User, at time.Time, delta periodStatsDelta) {
+	if !tracksPeriodStats(user, delta) {
+		return
+	}
+	seasons := make([]models.Season, 0, len(s.seasons))
+	for _, season := range s.seasons {
+		seasons = append(seasons, season)
+	}
+	for _, doc := range periodStatsDocs(s.cal, seasons, user, at) {
+		if existing, ok := s.periodStats[doc.ID]; ok {
+			doc = existing
+			doc.Name = user.Name
+		}
+		doc.Minute += delta.Minute
+		doc.Score += delta.Score
+		doc.NumberTree += delta.NumberTree
+		doc.UpdatedAt = time.Now()
+		s.periodStats[doc.ID] = doc
+	}
+}
+
+// --- SeasonStore ---
+
+func (s *MemoryStore) CreateSeason(ctx context.Context, season *models.Season, check func(existing []models.Season) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := make([]models.Season, 0, len(s.seasons))
+	for _, other := range s.seasons {
+		existing = append(existing, other)
+	}
+	sortSeasons(existing)
+	if err := check(existing); err != nil {
+		return err
+	}
+
+	season.ID = newID()
+	s.seasons[season.ID] = *season
+	return nil
+}
+
+func (s *MemoryStore) GetSeason(ctx context.Context, id string) (*models.Season, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	season, ok := s.seasons[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &season, nil
+}
+
+func (s *MemoryStore) ListSeasons(ctx context.Context) ([]models.Season, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seasons := make([]models.Season, 0, len(s.seasons))
+	for _, season := range s.seasons {
+		seasons = append(seasons, season)
+	}
+	sortSeasons(seasons)
+	return seasons, nil
+}
+
+func (s *MemoryStore) CloseSeason(ctx context.Context, id string, fn func(season *models.Season) error) (*models.Season, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	season, ok := s.seasons[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := fn(&season); err != nil {
+		return nil, err
+	}
+
+	stats := []models.PeriodStats{}
+	for _, doc := range s.periodStats {
+		if doc.Period == models.PeriodSeason && doc.PeriodKey == id {
+			stats = append(stats, doc)
+		}
+	}
+	s.standings[id] = newSeasonStandings(id, stats)
+	season.Status = models.SeasonStatusClosed
+	season.Participants = len(stats)
+	s.seasons[id] = season
+	return &season, nil
+}
+
+func (s *MemoryStore) SeasonLeaderboard(ctx context.Context, season *models.Season, query LeaderboardQuery) (*LeaderboardPage, error) {
+	return leaderboardPage(ctx, s, seasonBoard(season), query)
+}
+
+func (s *MemoryStore) SeasonLeaderboardAround(ctx context.Context, season *models.Season, userID string, n int) (*LeaderboardAround, error) {
+	return leaderboardAround(ctx, s, seasonBoard(season), userID, n)
+}
+
+// --- JobStore ---
+
+func (s *MemoryStore) CreateJob(ctx context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.ID = newID()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *MemoryStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func (s *MemoryStore) UpdateJob(ctx context.Context, id string, fn func(job *models.Job) error) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := fn(&job); err != nil {
+		return nil, err
+	}
+	s.jobs[id] = job
+	return &job, nil
+}
+
+func (s *MemoryStore) ListJobsByStatus(ctx context.Context, statuses ...string) ([]models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := []models.Job{}
+	for _, job := range s.jobs {
+		if slices.Contains(statuses, job.Status) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *MemoryStore) ResetStatsBatch(ctx context.Context, jobID string, limit int) (*models.Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, false, ErrNotFound
+	}
+
+	users := []models.User{}
+	for _, user := range s.users {
+		if user.ID > job.Cursor {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+
+	snapshots, done := applyResetBatch(&job, users, limit)
+	if !job.DryRun {
+		for _, snapshot := range snapshots {
+			user := s.users[snapshot.UserID]
+			user.Minute = 0
+			user.Score = 0
+			user.NumberTree = 0
+			user.TreeProgress = 0
+			s.users[snapshot.UserID] = user
+		}
+		s.snapshots[jobID] = append(s.snapshots[jobID], snapshots...)
+	}
+	s.jobs[jobID] = job
+	return &job, done, nil
+}
+
+func (s *MemoryStore) RestoreStatsBatch(ctx context.Context, jobID string, limit int) (*models.Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, false, ErrNotFound
+	}
+
+	snapshots := []models.StatsSnapshot{}
+	for _, snapshot := range s.snapshots[jobID] {
+		if len(snapshots) == limit {
+			break
+		}
+		if snapshot.UserID > job.UndoCursor {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	for _, snapshot := range snapshots {
+		user, ok := s.users[snapshot.UserID]
+		if !ok {
+			continue
+		}
+		user.Minute += snapshot.Minute
+		user.Score += snapshot.Score
+		user.NumberTree += snapshot.NumberTree
+		user.TreeProgress += snapshot.TreeProgress
+		s.users[snapshot.UserID] = user
+	}
+
+	done := applyRestoreBatch(&job, snapshots, limit)
+	s.jobs[jobID] = job
+	return &job, done, nil
+}
+
+// --- OTPStore ---
+
+func (s *MemoryStore) GetLoginOTP(ctx context.Context, id string) (*models.LoginOTP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	otp, ok := s.otps[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &otp, nil
+}
+
+func (s *MemoryStore) SaveLoginOTP(ctx context.Context, otp *models.LoginOTP) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.otps[otp.ID] = *otp
+	return nil
+}
+
+func (s *MemoryStore) DeleteLoginOTP(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.otps, id)
+	return nil
+}
+
+func (s *MemoryStore) ConsumeLoginOTP(ctx context.Context, id string, check func(otp *models.LoginOTP) OTPOutcome) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	otp, ok := s.otps[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if check(&otp) == OTPRejected {
+		otp.Attempts++
+		s.otps[id] = otp
+		return nil
+	}
+	delete(s.otps, id)
+	return nil
+}
+
+// --- SessionStore ---
+
+func (s *MemoryStore) CreateSession(ctx context.Context, session *models.Session) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.ID = newID()
+	s.sessions[session.ID] = *session
+	return session.ID, nil
+}
+
+func (s *MemoryStore) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (s *MemoryStore) ListUserSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := []models.Session{}
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *MemoryStore) UpdateSession(ctx context.Context, id string, fn func(session *models.Session) error) (*models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := fn(&session); err != nil {
+		return nil, err
+	}
+	s.sessions[id] = session
+	return &session, nil
+}
+
+func (s *MemoryStore) RevokeSession(ctx context.Context, userID, id string) error {
+	_, err := s.UpdateSession(ctx, id, func(session *models.Session) error {
+		if session.UserID != userID {
+			return ErrNotFound
+		}
+		if session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+		}
+		return nil
+	})
+	return err
+}
+
+// --- ActivityStore ---
+
+func (s *MemoryStore) RecordActivity(ctx context.Context, activity *models.Activity, window ActivityWindow, check func(existing []models.Activity) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[activity.UserID]
+	if !ok {
+		return ErrNotFound
+	}
+	existing := []models.Activity{}
+	for _, other := range s.activities[activity.UserID] {
+		if !other.StartedAt.Before(window.From) && other.StartedAt.Before(window.To) {
+			existing = append(existing, other)
+		}
+	}
+	if err := check(existing); err != nil {
+		return err
+	}
+
+	activity.ID = newID()
+	s.activities[activity.UserID] = append(s.activities[activity.UserID], *activity)
+	if activity.ReviewStatus == models.ReviewStatusPending {
+		s.reviews[activity.ID] = newActivityReview(activity)
+	}
+
+	user.Minute += activity.DurationMinutes
+	user.Score += activity.ScoreAwarded
+	s.users[activity.UserID] = user
+	s.addPeriodStats(&user, activity.StartedAt, periodStatsDelta{
+		Minute: activity.DurationMinutes,
+		Score:  activity.ScoreAwarded,
+	})
+	return nil
+}
+
+func (s *MemoryStore) ListActivities(ctx context.Context, userID string, query ActivityQuery) (*ActivityPage, error) {
+	cursor, err := decodeTimeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activities := []models.Activity{}
+	for _, activity := range s.activities[userID] {
+		if query.From != nil && activity.StartedAt.Before(*query.From) {
+			continue
+		}
+		if query.To != nil && !activity.StartedAt.Before(*query.To) {
+			continue
+		}
+		if !cursor.before(activity.StartedAt, activity.ID) {
+			continue
+		}
+		activities = append(activities, activity)
+	}
+	sortNewestFirst(activities, activityKey)
+	if len(activities) > query.Limit+1 {
+		activities = activities[:query.Limit+1]
+	}
+	return newActivityPage(activities, query.Limit), nil
+}
+
+// --- ReviewStore ---
+
+func (s *MemoryStore) ListActivityReviews(ctx context.Context, query ReviewQuery) (*ReviewPage, error) {
+	cursor, err := decodeTimeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reviews := []models.ActivityReview{}
+	for _, review := range s.reviews {
+		if query.Status != "" && review.Status != query.Status {
+			continue
+		}
+		if !cursor.before(review.CreatedAt, review.ID) {
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+	sortNewestFirst(reviews, reviewKey)
+	if len(reviews) > query.Limit+1 {
+		reviews = reviews[:query.Limit+1]
+	}
+	return newReviewPage(reviews, query.Limit), nil
+}
+
+func (s *MemoryStore) ResolveActivityReview(ctx context.Context, id string, fn func(review *models.ActivityReview) error) (*models.ActivityReview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	review, ok := s.reviews[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	previous := review.Status
+	if err := fn(&review); err != nil {
+		return nil, err
+	}
+	s.reviews[id] = review
+
+	activities := s.activities[review.UserID]
+	for i := range activities {
+		if activities[i].ID == id {
+			activities[i].ReviewStatus = review.Status
+		}
+	}
+	if rejectedNow(previous, review.Status) {
+		if user, ok := s.users[review.UserID]; ok {
+			user.Minute -= review.DurationMinutes
+			user.Score -= review.ScoreAwarded
+			s.users[review.UserID] = user
+			s.addPeriodStats(&user, review.StartedAt, periodStatsDelta{
+				Minute: -review.DurationMinutes,
+				Score:  -review.ScoreAwarded,
+			})
+		}
+	}
+	return &review, nil
+}
+
+// --- IdempotencyStore ---
+
+func (s *MemoryStore) BeginIdempotentRequest(ctx context.Context, record *models.IdempotencyRecord, now time.Time) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.idempotent[record.ID]; ok && existing.ExpiresAt.After(now) {
+		return &existing, nil
+	}
+	s.idempotent[record.ID] = *record
+	return nil, nil
+}
+
+func (s *MemoryStore) CompleteIdempotentRequest(ctx context.Context, record *models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.idempotent[record.ID]; !ok {
+		return ErrNotFound
+	}
+	s.idempotent[record.ID] = *record
+	return nil
+}
+
+func (s *MemoryStore) GetIdempotentRequest(ctx context.Context, id string) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.idempotent[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+func (s *MemoryStore) DeleteIdempotentRequest(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotent, id)
+	return nil
+}
+
+// --- FriendStore ---
+
+func (s *MemoryStore) GetFriendship(ctx context.Context, userID, otherID string) (*models.Friendship, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	friendship, ok := s.friendships[models.FriendshipID(userID, otherID)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &friendship, nil
+}
+
+func (s *MemoryStore) UpdateFriendship(ctx context.Context, userID, otherID string, fn func(friendship *models.Friendship) (*models.Friendship, error)) (*models.Friendship, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := models.FriendshipID(userID, otherID)
+	var current *models.Friendship
+	if existing, ok := s.friendships[id]; ok {
+		current = &existing
+	}
+	updated, err := fn(current)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		delete(s.friendships, id)
+		return nil, nil
+	}
+	updated.ID = id
+	s.friendships[id] = *updated
+	return updated, nil
+}
+
+func (s *MemoryStore) ListFriendships(ctx context.Context, userID string) ([]models.Friendship, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	friendships := []models.Friendship{}
+	for _, friendship := range s.friendships {
+		if friendship.UserA == userID || friendship.UserB == userID {
+			friendships = append(friendships, friendship)
+		}
+	}
+	return friendships, nil
+}
+
+// --- AuditStore ---
+
+func (s *MemoryStore) AppendAudit(ctx context.Context, entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = newID()
+	s.audit = append(s.audit, *entry)
+	return nil
+}
+
+func (s *MemoryStore) ListAudit(ctx context.Context, query AuditQuery) (*AuditPage, error) {
+	cursor, err := decodeTimeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []models.AuditEntry{}
+	for _, entry := range s.audit {
+		if !auditMatches(&entry, query) || !cursor.before(entry.CreatedAt, entry.ID) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sortNewestFirst(entries, auditKey)
+	if len(entries) > query.Limit+1 {
+		entries = entries[:query.Limit+1]
+	}
+	return newAuditPage(entries, query.Limit), nil
+}
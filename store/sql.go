@@ -0,0 +1,1143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"meerank/models"
+	"meerank/periods"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ Store = (*SQLStore)(nil)
+
+// SQLStore คือ Store ที่เก็บข้อมูลในฐานข้อมูล SQL ผ่าน GORM (MySQL หรือ SQLite)
+// ต้องเรียก Migrate ก่อนใช้งานครั้งแรก
+type SQLStore struct {
+	db  *gorm.DB
+	cal *periods.Calendar
+}
+
+func NewSQLStore(db *gorm.DB, cal *periods.Calendar) *SQLStore {
+	return &SQLStore{db: db, cal: cal}
+}
+
+func (s *SQLStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// mapRecordNotFound แปลง gorm.ErrRecordNotFound เป็น ErrNotFound
+func mapRecordNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// forUpdate ล็อกแถวที่อ่านไว้จนจบ transaction (SQLite ไม่รองรับและจะข้ามไปเอง)
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// --- UserStore ---
+
+func (s *SQLStore) GetUser(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &user, nil
+}
+
+func (s *SQLStore) GetUsers(ctx context.Context, ids []string) (map[string]models.User, error) {
+	users := make(map[string]models.User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+	var found []models.User
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range found {
+		users[user.ID] = user
+	}
+	return users, nil
+}
+
+func (s *SQLStore) CreateUser(ctx context.Context, user *models.User) (string, error) {
+	id := newID()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := movePhone(tx, id, "", phoneOf(user.Phone)); err != nil {
+			return err
+		}
+		user.ID = id
+		return tx.Create(user).Error
+	})
+	if err != nil {
+		user.ID = ""
+		return "", err
+	}
+	return user.ID, nil
+}
+
+func (s *SQLStore) UpdateUser(ctx context.Context, id string, update UserUpdate) error {
+	columns := map[string]interface{}{}
+	if update.Name != nil {
+		columns["name"] = *update.Name
+	}
+	if update.Phone != nil {
+		columns["phone"] = *update.Phone
+	}
+	if update.Age != nil {
+		columns["age"] = *update.Age
+	}
+	if update.Gender != nil {
+		columns["gender"] = *update.Gender
+	}
+	if update.LastLoginAt != nil {
+		columns["last_login_at"] = *update.LastLoginAt
+	}
+	if update.FirebaseUID != nil {
+		columns["firebase_uid"] = *update.FirebaseUID
+	}
+	if update.ProfileVisibility != nil {
+		columns["profile_visibility"] = *update.ProfileVisibility
+	}
+	if update.LeaderboardAlias != nil {
+		columns["leaderboard_alias"] = *update.LeaderboardAlias
+	}
+	if update.Locale != nil {
+		columns["locale"] = *update.Locale
+	}
+
+	return s.updateUserColumns(ctx, id, columns)
+}
+
+// updateUserColumns อัปเดตคอลัมน์ของผู้ใช้ (คืน ErrNotFound ถ้าไม่มีผู้ใช้)
+// ตรวจการมีอยู่ก่อน เพราะ MySQL นับ RowsAffected เฉพาะแถวที่ค่าเปลี่ยนจริง
+// ถ้ามีคอลัมน์ phone จะย้ายการจองเบอร์ใน transaction เดียวกัน
+func (s *SQLStore) updateUserColumns(ctx context.Context, id string, columns map[string]interface{}) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := forUpdate(tx).Select("id", "phone").First(&user, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+		if phone, ok := columns["phone"].(string); ok {
+			if err := movePhone(tx, id, phoneOf(user.Phone), phone); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", id).Updates(columns).Error
+	})
+}
+
+// movePhone ย้ายการจองเบอร์ของผู้ใช้ id จาก oldPhone ไป newPhone (ค่าว่าง = ไม่มีเบอร์) ภายใน tx
+// การจองใช้ primary key ของ phone_index ถ้ามีคนจองเบอร์เดียวกันพร้อมกัน แถวที่สองจะไม่ถูกเพิ่มและได้ ErrPhoneTaken
+func movePhone(tx *gorm.DB, id, oldPhone, newPhone string) error {
+	if oldPhone == newPhone {
+		return nil
+	}
+	if newPhone != "" {
+		var index models.PhoneIndex
+		err := forUpdate(tx).First(&index, "phone = ?", newPhone).Error
+		switch {
+		case err == nil:
+			if index.UserID != id {
+				return ErrPhoneTaken
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.PhoneIndex{Phone: newPhone, UserID: id, CreatedAt: time.Now().UTC()})
+			if created.Error != nil {
+				return created.Error
+			}
+			if created.RowsAffected == 0 {
+				return ErrPhoneTaken
+			}
+		default:
+			return err
+		}
+	}
+	if oldPhone != "" {
+		return tx.Where("phone = ? AND user_id = ?", oldPhone, id).Delete(&models.PhoneIndex{}).Error
+	}
+	return nil
+}
+
+func (s *SQLStore) findUser(ctx context.Context, query string, args ...interface{}) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Where(query, args...).First(&user).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &user, nil
+}
+
+func (s *SQLStore) FindUserByPhone(ctx context.Context, phone string) (*models.User, error) {
+	var index models.PhoneIndex
+	if err := s.db.WithContext(ctx).First(&index, "phone = ?", phone).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return s.GetUser(ctx, index.UserID)
+}
+
+func (s *SQLStore) FindUserByFirebaseUID(ctx context.Context, firebaseUID string) (*models.User, error) {
+	return s.findUser(ctx, "firebase_uid = ?", firebaseUID)
+}
+
+func (s *SQLStore) ListUsers(ctx context.Context) ([]models.User, error) {
+	users := []models.User{}
+	if err := s.db.WithContext(ctx).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *SQLStore) IncrementStats(ctx context.Context, id string, delta StatsDelta) error {
+	columns := map[string]interface{}{}
+	if delta.Minute != 0 {
+		columns["minute"] = gorm.Expr("minute + ?", delta.Minute)
+	}
+	if delta.Score != 0 {
+		columns["score"] = gorm.Expr("score + ?", delta.Score)
+	}
+	if delta.NumberTree != 0 {
+		columns["number_tree"] = gorm.Expr("number_tree + ?", delta.NumberTree)
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := forUpdate(tx).First(&user, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", id).Updates(columns).Error; err != nil {
+			return err
+		}
+		return s.addPeriodStats(tx, &user, time.Now(), periodStatsDelta(delta))
+	})
+}
+
+func (s *SQLStore) UpdateUserStats(ctx context.Context, id string, fn func(user *models.User) error) (*models.User, error) {
+	var result models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(&result, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		treesBefore := result.NumberTree
+		if err := fn(&result); err != nil {
+			return err
+		}
+		err := tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"minute":        result.Minute,
+			"score":         result.Score,
+			"number_tree":   result.NumberTree,
+			"tree_progress": result.TreeProgress,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if grown := result.NumberTree - treesBefore; grown > 0 {
+			return s.addPeriodStats(tx, &result, time.Now(), periodStatsDelta{NumberTree: grown})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *SQLStore) CountUsers(ctx context.Context) (int, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (s *SQLStore) ModifyUser(ctx context.Context, id string, fn func(user *models.User) error) (*models.User, error) {
+	return s.modifyUser(ctx, id, func(tx *gorm.DB, user *models.User) error { return fn(user) })
+}
+
+// ModifyUserWithAdmins ล็อกแถวของ admin ทุกคนเหมือน ChangeUserRole
+func (s *SQLStore) ModifyUserWithAdmins(ctx context.Context, id string, fn func(user *models.User, admins []models.User) error) (*models.User, error) {
+	return s.modifyUser(ctx, id, func(tx *gorm.DB, user *models.User) error {
+		admins, err := lockAdmins(tx)
+		if err != nil {
+			return err
+		}
+		return fn(user, admins)
+	})
+}
+
+func (s *SQLStore) modifyUser(ctx context.Context, id string, fn func(tx *gorm.DB, user *models.User) error) (*models.User, error) {
+	var result models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(&result, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		oldPhone := phoneOf(result.Phone)
+		if err := fn(tx, &result); err != nil {
+			return err
+		}
+		if err := movePhone(tx, id, oldPhone, phoneOf(result.Phone)); err != nil {
+			return err
+		}
+		result.ID = id
+		return tx.Save(&result).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// lockAdmins อ่านและล็อกแถวของ admin ทุกคน (เรียงตาม id เพื่อไม่ให้ deadlock)
+func lockAdmins(tx *gorm.DB) ([]models.User, error) {
+	admins := []models.User{}
+	if err := forUpdate(tx).Where("role = ?", models.RoleAdmin).Order("id").Find(&admins).Error; err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+// ChangeUserRole ล็อกแถวของ admin ทุกคนด้วย เพื่อให้การลด role ของ admin สองคนพร้อมกันทำทีละคน
+func (s *SQLStore) ChangeUserRole(ctx context.Context, id, role string, check func(user *models.User, admins []models.User) error) (*models.User, error) {
+	var result models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(&result, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		admins, err := lockAdmins(tx)
+		if err != nil {
+			return err
+		}
+		if err := check(&result, admins); err != nil {
+			return err
+		}
+		result.Role = role
+		return tx.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *SQLStore) ListUsersToPurge(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	users := []models.User{}
+	err := s.db.WithContext(ctx).
+		Where("deleted_at IS NOT NULL AND purge_at <= ?", now).
+		Order("id").Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *SQLStore) PurgeUser(ctx context.Context, id string, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := forUpdate(tx).First(&user, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		if !purgeDue(&user, now) {
+			return ErrNotFound
+		}
+		for _, owned := range []interface{}{&models.Session{}, &models.Activity{}, &models.ActivityReview{}, &models.PeriodStats{}, &models.IdempotencyRecord{}} {
+			if err := tx.Where("user_id = ?", id).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_a = ? OR user_b = ?", id, id).Delete(&models.Friendship{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR id = ?", id, models.LoginOTPID(phoneOf(user.Phone))).Delete(&models.LoginOTP{}).Error; err != nil {
+			return err
+		}
+		if err := movePhone(tx, id, phoneOf(user.Phone), ""); err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, "id = ?", id).Error
+	})
+}
+
+// userSortColumns คือคอลัมน์ที่ใช้เรียงตาม UserQuery.Sort
+var userSortColumns = map[string]string{
+	UserSortScore:      "score",
+	UserSortMinute:     "minute",
+	UserSortNumberTree: "number_tree",
+	UserSortLastLogin:  "last_login_at",
+}
+
+func (s *SQLStore) SearchUsers(ctx context.Context, query UserQuery) (*UserPage, error) {
+	after, err := decodeUserCursor(query)
+	if err != nil {
+		return nil, err
+	}
+	column := userSortColumns[userSortField(query)]
+
+	q := s.db.WithContext(ctx).Model(&models.User{})
+	if query.Role != "" {
+		q = q.Where("role = ?", query.Role)
+	}
+	if query.Gender != "" {
+		q = q.Where("gender = ?", query.Gender)
+	}
+	if query.MinAge != nil {
+		q = q.Where("age >= ?", *query.MinAge)
+	}
+	if query.MaxAge != nil {
+		q = q.Where("age <= ?", *query.MaxAge)
+	}
+	if query.LastLoginFrom != nil {
+		q = q.Where("last_login_at >= ?", *query.LastLoginFrom)
+	}
+	if query.LastLoginTo != nil {
+		q = q.Where("last_login_at < ?", *query.LastLoginTo)
+	}
+	if query.NamePrefix != "" {
+		q = q.Where("name LIKE ? ESCAPE '!'", likePrefix(query.NamePrefix))
+	}
+	if query.PhonePrefix != "" {
+		q = q.Where("phone LIKE ? ESCAPE '!'", likePrefix(query.PhonePrefix))
+	}
+	if column == "last_login_at" {
+		q = q.Where("last_login_at IS NOT NULL")
+	}
+
+	direction, beyond := " DESC", "<"
+	if query.Ascending {
+		direction, beyond = " ASC", ">"
+	}
+	if after != nil {
+		v := after.sortArg()
+		q = q.Where(column+" "+beyond+" ? OR ("+column+" = ? AND id > ?)", v, v, after.ID)
+	}
+
+	users := []models.User{}
+	if err := q.Order(column + direction).Order("id ASC").Limit(query.Limit + 1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return newUserPage(users, query), nil
+}
+
+// likePrefix สร้าง pattern ของ LIKE สำหรับค้นหาแบบขึ้นต้นด้วย prefix ('!' เป็นตัว escape)
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
+
+// --- LeaderboardStore ---
+
+func (s *SQLStore) Leaderboard(ctx context.Context, query LeaderboardQuery) (*LeaderboardPage, error) {
+	return leaderboardPage(ctx, s, resolveBoard(s.cal, query.Period, time.Now()), query)
+}
+
+func (s *SQLStore) LeaderboardAround(ctx context.Context, period, userID string, n int) (*LeaderboardAround, error) {
+	return leaderboardAround(ctx, s, resolveBoard(s.cal, period, time.Now()), userID, n)
+}
+
+func (s *SQLStore) RenameLeaderboardEntries(ctx context.Context, userID, name string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.PeriodStats{}, &models.SeasonStanding{}} {
+			if err := tx.Model(model).Where("user_id = ?", userID).Update("name", name).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boardQuery คืน query ของแถวใน board และชื่อคอลัมน์ user ID ที่ใช้เรียงเมื่อเสมอกัน
+func (s *SQLStore) boardQuery(ctx context.Context, board leaderboardBoard) (*gorm.DB, string) {
+	if board.allTime() {
+		return s.db.WithContext(ctx).Model(&models.User{}).Where("role = ?", models.RoleMember), "id"
+	}
+	if board.Final {
+		return s.db.WithContext(ctx).Model(&models.SeasonStanding{}).Where("season_id = ?", board.Key), "user_id"
+	}
+	return s.db.WithContext(ctx).Model(&models.PeriodStats{}).
+		Where("period = ? AND period_key = ?", board.Period, board.Key), "user_id"
+}
+
+func (s *SQLStore) findLeaderboardRows(board leaderboardBoard, q *gorm.DB) ([]LeaderboardRow, error) {
+	rows := []LeaderboardRow{}
+	if board.allTime() {
+		users := []models.User{}
+		if err := q.Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for i := range users {
+			rows = append(rows, userLeaderboardRow(&users[i]))
+		}
+		return rows, nil
+	}
+	if board.Final {
+		standings := []models.SeasonStanding{}
+		if err := q.Find(&standings).Error; err != nil {
+			return nil, err
+		}
+		for i := range standings {
+			rows = append(rows, standingLeaderboardRow(&standings[i]))
+		}
+		return rows, nil
+	}
+
+	stats := []models.PeriodStats{}
+	if err := q.Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		rows = append(rows, periodStatsLeaderboardRow(&stats[i]))
+	}
+	return rows, nil
+}
+
+func (s *SQLStore) leaderboardAfter(ctx context.Context, board leaderboardBoard, after *leaderboardPos, limit int) ([]LeaderboardRow, error) {
+	q, idColumn := s.boardQuery(ctx, board)
+	if after != nil {
+		q = q.Where("number_tree < ? OR (number_tree = ? AND score < ?) OR (number_tree = ? AND score = ? AND "+idColumn+" > ?)",
+			after.NumberTree, after.NumberTree, after.Score, after.NumberTree, after.Score, after.UserID)
+	}
+	q = q.Order("number_tree DESC").Order("score DESC").Order(idColumn + " ASC").Limit(limit)
+	return s.findLeaderboardRows(board, q)
+}
+
+func (s *SQLStore) leaderboardBefore(ctx context.Context, board leaderboardBoard, before leaderboardPos, limit int) ([]LeaderboardRow, error) {
+	q, idColumn := s.boardQuery(ctx, board)
+	q = q.Where("number_tree > ? OR (number_tree = ? AND score > ?) OR (number_tree = ? AND score = ? AND "+idColumn+" < ?)",
+		before.NumberTree, before.NumberTree, before.Score, before.NumberTree, before.Score, before.UserID).
+		Order("number_tree ASC").Order("score ASC").Order(idColumn + " DESC").Limit(limit)
+	rows, err := s.findLeaderboardRows(board, q)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(rows)
+	return rows, nil
+}
+
+func (s *SQLStore) leaderboardRow(ctx context.Context, board leaderboardBoard, userID string) (*LeaderboardRow, error) {
+	q, idColumn := s.boardQuery(ctx, board)
+	rows, err := s.findLeaderboardRows(board, q.Where(idColumn+" = ?", userID).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return &rows[0], nil
+}
+
+func (s *SQLStore) countAhead(ctx context.Context, board leaderboardBoard, p leaderboardPos) (int, error) {
+	var count int64
+	q, _ := s.boardQuery(ctx, board)
+	err := q.Where("number_tree > ? OR (number_tree = ? AND score > ?)", p.NumberTree, p.NumberTree, p.Score).
+		Count(&count).Error
+	return int(count), err
+}
+
+// addPeriodStats บวกยอดเข้า PeriodStats ทุกช่วงเวลาที่ at อยู่ (ภายใน transaction tx)
+func (s *SQLStore) addPeriodStats(tx *gorm.DB, user *models.User, at time.Time, delta periodStatsDelta) error {
+	if !tracksPeriodStats(user, delta) {
+		return nil
+	}
+	// ฤดูกาลที่ยังเปิดอยู่และครอบคลุม at (ฤดูกาลห้ามทับกัน จึงมีได้ไม่เกินหนึ่ง)
+	seasons := []models.Season{}
+	err := tx.Where("status = ? AND starts_at <= ? AND ends_at > ?", models.SeasonStatusOpen, at.UTC(), at.UTC()).
+		Find(&seasons).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, doc := range periodStatsDocs(s.cal, seasons, user, at) {
+		doc.Minute = delta.Minute
+		doc.Score = delta.Score
+		doc.NumberTree = delta.NumberTree
+		doc.UpdatedAt = now
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"name":        user.Name,
+				"minute":      gorm.Expr("minute + ?", delta.Minute),
+				"score":       gorm.Expr("score + ?", delta.Score),
+				"number_tree": gorm.Expr("number_tree + ?", delta.NumberTree),
+				"updated_at":  now,
+			}),
+		}).Create(&doc).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- SeasonStore ---
+
+func (s *SQLStore) CreateSeason(ctx context.Context, season *models.Season, check func(existing []models.Season) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing := []models.Season{}
+		if err := forUpdate(tx).Order("starts_at DESC").Order("id DESC").Find(&existing).Error; err != nil {
+			return err
+		}
+		if err := check(existing); err != nil {
+			return err
+		}
+
+		season.ID = newID()
+		return tx.Create(season).Error
+	})
+}
+
+func (s *SQLStore) GetSeason(ctx context.Context, id string) (*models.Season, error) {
+	var season models.Season
+	if err := s.db.WithContext(ctx).First(&season, "id = ?", id).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &season, nil
+}
+
+func (s *SQLStore) ListSeasons(ctx context.Context) ([]models.Season, error) {
+	seasons := []models.Season{}
+	if err := s.db.WithContext(ctx).Order("starts_at DESC").Order("id DESC").Find(&seasons).Error; err != nil {
+		return nil, err
+	}
+	return seasons, nil
+}
+
+func (s *SQLStore) CloseSeason(ctx context.Context, id string, fn func(season *models.Season) error) (*models.Season, error) {
+	var season models.Season
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// ล็อกฤดูกาลไว้ การบันทึกยอดที่อ่านฤดูกาลนี้อยู่ต้องรอจนปิดเสร็จ
+		if err := forUpdate(tx).First(&season, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		if err := fn(&season); err != nil {
+			return err
+		}
+
+		stats := []models.PeriodStats{}
+		err := tx.Where("period = ? AND period_key = ?", models.PeriodSeason, id).Find(&stats).Error
+		if err != nil {
+			return err
+		}
+		// ลบ standings เดิม (ถ้ามี) เพื่อให้สั่งปิดซ้ำได้ผลเหมือนเดิม
+		if err := tx.Where("season_id = ?", id).Delete(&models.SeasonStanding{}).Error; err != nil {
+			return err
+		}
+		if standings := newSeasonStandings(id, stats); len(standings) > 0 {
+			if err := tx.CreateInBatches(standings, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		season.Status = models.SeasonStatusClosed
+		season.Participants = len(stats)
+		return tx.Save(&season).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (s *SQLStore) SeasonLeaderboard(ctx context.Context, season *models.Season, query LeaderboardQuery) (*LeaderboardPage, error) {
+	return leaderboardPage(ctx, s, seasonBoard(season), query)
+}
+
+func (s *SQLStore) SeasonLeaderboardAround(ctx context.Context, season *models.Season, userID string, n int) (*LeaderboardAround, error) {
+	return leaderboardAround(ctx, s, seasonBoard(season), userID, n)
+}
+
+// --- JobStore ---
+
+func (s *SQLStore) CreateJob(ctx context.Context, job *models.Job) error {
+	job.ID = newID()
+	return s.db.WithContext(ctx).Create(job).Error
+}
+
+func (s *SQLStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	var job models.Job
+	if err := s.db.WithContext(ctx).First(&job, "id = ?", id).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &job, nil
+}
+
+func (s *SQLStore) UpdateJob(ctx context.Context, id string, fn func(job *models.Job) error) (*models.Job, error) {
+	var job models.Job
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(&job, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		if err := fn(&job); err != nil {
+			return err
+		}
+		return tx.Save(&job).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *SQLStore) ListJobsByStatus(ctx context.Context, statuses ...string) ([]models.Job, error) {
+	jobs := []models.Job{}
+	if err := s.db.WithContext(ctx).Where("status IN ?", statuses).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (s *SQLStore) ResetStatsBatch(ctx context.Context, jobID string, limit int) (*models.Job, bool, error) {
+	var job models.Job
+	var done bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// ล็อกงานไว้ ถ้ามีตัวทำงานสองตัว batch จะต่อกันแทนที่จะทำซ้ำ
+		if err := forUpdate(tx).First(&job, "id = ?", jobID).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		users := []models.User{}
+		err := forUpdate(tx).Where("id > ?", job.Cursor).Order("id").Limit(limit).Find(&users).Error
+		if err != nil {
+			return err
+		}
+
+		var snapshots []models.StatsSnapshot
+		snapshots, done = applyResetBatch(&job, users, limit)
+		if !job.DryRun && len(snapshots) > 0 {
+			if err := tx.Create(&snapshots).Error; err != nil {
+				return err
+			}
+			ids := make([]string, 0, len(snapshots))
+			for _, snapshot := range snapshots {
+				ids = append(ids, snapshot.UserID)
+			}
+			err := tx.Model(&models.User{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+				"minute":        0,
+				"score":         0,
+				"number_tree":   0,
+				"tree_progress": 0,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(&job).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &job, done, nil
+}
+
+func (s *SQLStore) RestoreStatsBatch(ctx context.Context, jobID string, limit int) (*models.Job, bool, error) {
+	var job models.Job
+	var done bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(&job, "id = ?", jobID).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		snapshots := []models.StatsSnapshot{}
+		err := tx.Where("job_id = ? AND user_id > ?", jobID, job.UndoCursor).Order("user_id").Limit(limit).
+			Find(&snapshots).Error
+		if err != nil {
+			return err
+		}
+
+		for _, snapshot := range snapshots {
+			err := tx.Model(&models.User{}).Where("id = ?", snapshot.UserID).Updates(map[string]interface{}{
+				"minute":        gorm.Expr("minute + ?", snapshot.Minute),
+				"score":         gorm.Expr("score + ?", snapshot.Score),
+				"number_tree":   gorm.Expr("number_tree + ?", snapshot.NumberTree),
+				"tree_progress": gorm.Expr("tree_progress + ?", snapshot.TreeProgress),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		done = applyRestoreBatch(&job, snapshots, limit)
+		return tx.Save(&job).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &job, done, nil
+}
+
+// --- OTPStore ---
+
+func (s *SQLStore) GetLoginOTP(ctx context.Context, id string) (*models.LoginOTP, error) {
+	var otp models.LoginOTP
+	if err := s.db.WithContext(ctx).First(&otp, "id = ?", id).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &otp, nil
+}
+
+func (s *SQLStore) SaveLoginOTP(ctx context.Context, otp *models.LoginOTP) error {
+	return s.db.WithContext(ctx).Save(otp).Error
+}
+
+func (s *SQLStore) DeleteLoginOTP(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&models.LoginOTP{}, "id = ?", id).Error
+}
+
+func (s *SQLStore) ConsumeLoginOTP(ctx context.Context, id string, check func(otp *models.LoginOTP) OTPOutcome) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var otp models.LoginOTP
+		if err := forUpdate(tx).First(&otp, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+
+		if check(&otp) == OTPRejected {
+			return tx.Model(&models.LoginOTP{}).Where("id = ?", id).
+				Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+		return tx.Delete(&models.LoginOTP{}, "id = ?", id).Error
+	})
+}
+
+// --- SessionStore ---
+
+func (s *SQLStore) CreateSession(ctx context.Context, session *models.Session) (string, error) {
+	session.ID = newID()
+	if err := s.db.WithContext(ctx).Create(session).Error; err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+func (s *SQLStore) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.WithContext(ctx).First(&session, "id = ?", id).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &session, nil
+}
+
+func (s *SQLStore) ListUserSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	sessions := []models.Session{}
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *SQLStore) UpdateSession(ctx context.Context, id string, fn func(session *models.Session) error) (*models.Session, error) {
+	var session models.Session
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(&session, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		if err := fn(&session); err != nil {
+			return err
+		}
+		return tx.Save(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *SQLStore) RevokeSession(ctx context.Context, userID, id string) error {
+	_, err := s.UpdateSession(ctx, id, func(session *models.Session) error {
+		if session.UserID != userID {
+			return ErrNotFound
+		}
+		if session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+		}
+		return nil
+	})
+	return err
+}
+
+// --- ActivityStore ---
+
+func (s *SQLStore) RecordActivity(ctx context.Context, activity *models.Activity, window ActivityWindow, check func(existing []models.Activity) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// ล็อกแถวของผู้ใช้ไว้ เพื่อให้การส่งกิจกรรมพร้อมกันของคนเดียวกันต้องรอกัน
+		var user models.User
+		if err := forUpdate(tx).First(&user, "id = ?", activity.UserID).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+
+		existing := []models.Activity{}
+		err := tx.Where("user_id = ? AND started_at >= ? AND started_at < ?", activity.UserID, window.From, window.To).
+			Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if err := check(existing); err != nil {
+			return err
+		}
+
+		activity.ID = newID()
+		if err := tx.Create(activity).Error; err != nil {
+			return err
+		}
+		if activity.ReviewStatus == models.ReviewStatusPending {
+			review := newActivityReview(activity)
+			if err := tx.Create(&review).Error; err != nil {
+				return err
+			}
+		}
+		err = tx.Model(&models.User{}).Where("id = ?", activity.UserID).Updates(map[string]interface{}{
+			"minute": gorm.Expr("minute + ?", activity.DurationMinutes),
+			"score":  gorm.Expr("score + ?", activity.ScoreAwarded),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return s.addPeriodStats(tx, &user, activity.StartedAt, periodStatsDelta{
+			Minute: activity.DurationMinutes,
+			Score:  activity.ScoreAwarded,
+		})
+	})
+}
+
+func (s *SQLStore) ListActivities(ctx context.Context, userID string, query ActivityQuery) (*ActivityPage, error) {
+	cursor, err := decodeTimeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	q := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if query.From != nil {
+		q = q.Where("started_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		q = q.Where("started_at < ?", *query.To)
+	}
+	if cursor != nil {
+		q = q.Where("started_at < ? OR (started_at = ? AND id < ?)", cursor.At, cursor.At, cursor.ID)
+	}
+
+	activities := []models.Activity{}
+	err = q.Order("started_at DESC").Order("id DESC").Limit(query.Limit + 1).Find(&activities).Error
+	if err != nil {
+		return nil, err
+	}
+	return newActivityPage(activities, query.Limit), nil
+}
+
+// --- ReviewStore ---
+
+func (s *SQLStore) ListActivityReviews(ctx context.Context, query ReviewQuery) (*ReviewPage, error) {
+	cursor, err := decodeTimeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	q := s.db.WithContext(ctx)
+	if query.Status != "" {
+		q = q.Where("status = ?", query.Status)
+	}
+	if cursor != nil {
+		q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.At, cursor.At, cursor.ID)
+	}
+
+	reviews := []models.ActivityReview{}
+	err = q.Order("created_at DESC").Order("id DESC").Limit(query.Limit + 1).Find(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+	return newReviewPage(reviews, query.Limit), nil
+}
+
+func (s *SQLStore) ResolveActivityReview(ctx context.Context, id string, fn func(review *models.ActivityReview) error) (*models.ActivityReview, error) {
+	var review models.ActivityReview
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(&review, "id = ?", id).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		previous := review.Status
+		if err := fn(&review); err != nil {
+			return err
+		}
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Activity{}).Where("id = ?", id).Update("review_status", review.Status).Error
+		if err != nil {
+			return err
+		}
+		if !rejectedNow(previous, review.Status) {
+			return nil
+		}
+		var user models.User
+		if err := forUpdate(tx).First(&user, "id = ?", review.UserID).Error; err != nil {
+			return mapRecordNotFound(err)
+		}
+		err = tx.Model(&models.User{}).Where("id = ?", review.UserID).Updates(map[string]interface{}{
+			"minute": gorm.Expr("minute - ?", review.DurationMinutes),
+			"score":  gorm.Expr("score - ?", review.ScoreAwarded),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return s.addPeriodStats(tx, &user, review.StartedAt, periodStatsDelta{
+			Minute: -review.DurationMinutes,
+			Score:  -review.ScoreAwarded,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// --- IdempotencyStore ---
+
+func (s *SQLStore) BeginIdempotentRequest(ctx context.Context, record *models.IdempotencyRecord, now time.Time) (*models.IdempotencyRecord, error) {
+	var existing *models.IdempotencyRecord
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.IdempotencyRecord
+		err := forUpdate(tx).First(&current, "id = ?", record.ID).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(record).Error
+		case err != nil:
+			return err
+		case current.ExpiresAt.After(now):
+			existing = &current
+			return nil
+		default:
+			return tx.Save(record).Error
+		}
+	})
+	if err != nil {
+		// คำขอซ้ำที่เข้ามาพร้อมกันอาจ insert ชนกัน ให้คืน record ของอีกฝั่งแทน
+		if current, getErr := s.GetIdempotentRequest(ctx, record.ID); getErr == nil {
+			return current, nil
+		}
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (s *SQLStore) CompleteIdempotentRequest(ctx context.Context, record *models.IdempotencyRecord) error {
+	return s.db.WithContext(ctx).Save(record).Error
+}
+
+func (s *SQLStore) GetIdempotentRequest(ctx context.Context, id string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	if err := s.db.WithContext(ctx).First(&record, "id = ?", id).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &record, nil
+}
+
+func (s *SQLStore) DeleteIdempotentRequest(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&models.IdempotencyRecord{}, "id = ?", id).Error
+}
+
+// --- FriendStore ---
+
+func (s *SQLStore) GetFriendship(ctx context.Context, userID, otherID string) (*models.Friendship, error) {
+	var friendship models.Friendship
+	if err := s.db.WithContext(ctx).First(&friendship, "id = ?", models.FriendshipID(userID, otherID)).Error; err != nil {
+		return nil, mapRecordNotFound(err)
+	}
+	return &friendship, nil
+}
+
+func (s *SQLStore) UpdateFriendship(ctx context.Context, userID, otherID string, fn func(friendship *models.Friendship) (*models.Friendship, error)) (*models.Friendship, error) {
+	id := models.FriendshipID(userID, otherID)
+	var result *models.Friendship
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current *models.Friendship
+		var existing models.Friendship
+		err := forUpdate(tx).First(&existing, "id = ?", id).Error
+		switch {
+		case err == nil:
+			current = &existing
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		updated, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if updated == nil {
+			if current == nil {
+				return nil
+			}
+			return tx.Delete(&models.Friendship{}, "id = ?", id).Error
+		}
+		updated.ID = id
+		result = updated
+		if current == nil {
+			return tx.Create(updated).Error
+		}
+		return tx.Save(updated).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *SQLStore) ListFriendships(ctx context.Context, userID string) ([]models.Friendship, error) {
+	friendships := []models.Friendship{}
+	err := s.db.WithContext(ctx).Where("user_a = ? OR user_b = ?", userID, userID).Find(&friendships).Error
+	return friendships, err
+}
+
+// --- AuditStore ---
+
+func (s *SQLStore) AppendAudit(ctx context.Context, entry *models.AuditEntry) error {
+	entry.ID = newID()
+	return s.db.WithContext(ctx).Create(entry).Error
+}
+
+func (s *SQLStore) ListAudit(ctx context.Context, query AuditQuery) (*AuditPage, error) {
+	cursor, err := decodeTimeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	q := s.db.WithContext(ctx)
+	if query.ActorID != "" {
+		q = q.Where("actor_id = ?", query.ActorID)
+	}
+	if query.TargetID != "" {
+		q = q.Where("target_id = ?", query.TargetID)
+	}
+	if query.Action != "" {
+		q = q.Where("action = ?", query.Action)
+	}
+	if query.From != nil {
+		q = q.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		q = q.Where("created_at < ?", *query.To)
+	}
+	if cursor != nil {
+		q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.At, cursor.At, cursor.ID)
+	}
+
+	entries := []models.AuditEntry{}
+	err = q.Order("created_at DESC").Order("id DESC").Limit(query.Limit + 1).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return newAuditPage(entries, query.Limit), nil
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"meerank/models"
+
+	"gorm.io/gorm"
+)
+
+// schemaMigration บันทึกว่า migration เวอร์ชันไหนถูกรันไปแล้ว
+// ใช้ร่วมกับ FirestoreStore.Migrate (collection schema_migrations, Document ID คือเลขเวอร์ชัน)
+type schemaMigration struct {
+	Version   int       `firestore:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `firestore:"name" gorm:"size:255;not null"`
+	AppliedAt time.Time `firestore:"applied_at"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// sqlMigration คือการเปลี่ยนแปลง schema หนึ่งขั้น
+// ห้ามแก้ไข migration ที่ปล่อยไปแล้ว ให้เพิ่มเวอร์ชันใหม่ต่อท้ายแทน
+type sqlMigration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+var sqlMigrations = []sqlMigration{
+	{
+		Version: 1,
+		Name:    "create users, login_otps and sessions",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.User{}, &models.LoginOTP{}, &models.Session{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create activities",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Activity{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add activity review flags and activity_reviews",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Activity{}, &models.ActivityReview{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create idempotency_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.IdempotencyRecord{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create period_stats",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PeriodStats{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create seasons and season_standings, widen period_stats.period_key",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AlterColumn(&models.PeriodStats{}, "PeriodKey"); err != nil {
+				return err
+			}
+			return tx.AutoMigrate(&models.Season{}, &models.SeasonStanding{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create jobs and stats_snapshots",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Job{}, &models.StatsSnapshot{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add suspension and soft-delete columns to users",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.User{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create audit_log",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.AuditEntry{})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add privacy settings to users and create friendships",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.User{}, &models.Friendship{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "normalize phone numbers to E.164, create phone_index and add user_id to login_otps",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.PhoneIndex{}, &models.LoginOTP{}); err != nil {
+				return err
+			}
+			var users []models.User
+			if err := tx.Where("phone IS NOT NULL").Find(&users).Error; err != nil {
+				return err
+			}
+			plan := planPhoneBackfill(users)
+			for id, phone := range plan.Phones {
+				if err := tx.Model(&models.User{}).Where("id = ?", id).Update("phone", phone).Error; err != nil {
+					return err
+				}
+			}
+			now := time.Now().UTC()
+			for phone, id := range plan.Index {
+				if err := tx.Create(&models.PhoneIndex{Phone: phone, UserID: id, CreatedAt: now}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add locale to users",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.User{})
+		},
+	},
+	{
+		Version: 13,
+		Name:    "key login_otps by purpose and owner",
+		Up: func(tx *gorm.DB) error {
+			// primary key เปลี่ยนจาก phone เป็น id รหัสเดิมมีอายุไม่กี่นาที จึงสร้างตารางใหม่แทนการย้ายข้อมูล
+			if err := tx.Migrator().DropTable(&models.LoginOTP{}); err != nil {
+				return err
+			}
+			return tx.AutoMigrate(&models.LoginOTP{})
+		},
+	},
+}
+
+// Migrate รัน migration ที่ยังไม่เคยรันตามลำดับเวอร์ชัน (แต่ละเวอร์ชันอยู่ใน transaction ของตัวเอง)
+func (s *SQLStore) Migrate() error {
+	if err := s.db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var applied []schemaMigration
+	if err := s.db.Find(&applied).Error; err != nil {
+		return fmt.Errorf("read schema_migrations: %w", err)
+	}
+	done := make(map[int]bool, len(applied))
+	for _, m := range applied {
+		done[m.Version] = true
+	}
+
+	for _, m := range sqlMigrations {
+		if done[m.Version] {
+			continue
+		}
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		log.Printf("Applied migration %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,416 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"meerank/models"
+)
+
+var (
+	// ErrNotFound ถูกคืนเมื่อไม่พบข้อมูลที่ต้องการ
+	ErrNotFound = errors.New("store: not found")
+	// ErrInvalidCursor ถูกคืนเมื่อ cursor สำหรับแบ่งหน้าอ่านไม่ออก
+	ErrInvalidCursor = errors.New("store: invalid cursor")
+	// ErrPhoneTaken ถูกคืนเมื่อเบอร์โทรถูกผู้ใช้คนอื่นจองไว้แล้วใน phone_index
+	ErrPhoneTaken = errors.New("store: phone number already registered")
+)
+
+// phoneOf คืนเบอร์โทรของผู้ใช้ (ค่าว่างถ้าไม่มี)
+func phoneOf(phone *string) string {
+	if phone == nil {
+		return ""
+	}
+	return *phone
+}
+
+// newID สุ่ม ID ความยาว 20 ตัวอักษรแบบเดียวกับ Firestore
+func newID() string {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Store รวมทุก interface ของชั้นจัดเก็บข้อมูล
+// Handler ทุกตัวพึ่งพา interface นี้แทน *firestore.Client เพื่อให้สลับ backend หรือใช้ตัวในหน่วยความจำตอนทดสอบได้
+type Store interface {
+	UserStore
+	LeaderboardStore
+	OTPStore
+	SessionStore
+	ActivityStore
+	ReviewStore
+	IdempotencyStore
+	SeasonStore
+	JobStore
+	AuditStore
+	FriendStore
+	Close() error
+}
+
+// --- Users ---
+
+// UserUpdate ระบุ field ของผู้ใช้ที่ต้องการแก้ไข (nil = ไม่เปลี่ยน)
+type UserUpdate struct {
+	Name        *string
+	Phone       *string
+	Age         *int
+	Gender      *string
+	LastLoginAt *time.Time
+	FirebaseUID *string
+	// ProfileVisibility และ LeaderboardAlias (ค่าว่าง = ลบ alias)
+	ProfileVisibility *string
+	LeaderboardAlias  *string
+	// Locale คือภาษาของข้อความ (ค่าว่าง = ใช้ Accept-Language)
+	Locale *string
+}
+
+// StatsDelta คือค่าที่จะบวกเพิ่มให้สถิติของผู้ใช้แบบ atomic
+type StatsDelta struct {
+	Minute     int
+	Score      int
+	NumberTree int
+}
+
+// ลำดับที่ใช้ค้นหาผู้ใช้ได้ (UserQuery.Sort)
+const (
+	UserSortScore      = "score"
+	UserSortMinute     = "minute"
+	UserSortNumberTree = "number_tree"
+	UserSortLastLogin  = "last_login_at"
+)
+
+// UserQuery คือเงื่อนไขการค้นหาผู้ใช้ (ค่าว่างหรือ nil = ไม่กรอง)
+// ผู้ใช้ที่ไม่มี age หรือ last_login_at จะไม่ผ่านตัวกรองของ field นั้น
+// และเมื่อเรียงตาม last_login_at จะมีเฉพาะคนที่เคยล็อกอินแล้ว
+type UserQuery struct {
+	Role   string
+	Gender string
+	MinAge *int
+	MaxAge *int
+	// LastLoginFrom และ LastLoginTo กรองตาม last_login_at (From <= last_login_at < To)
+	LastLoginFrom *time.Time
+	LastLoginTo   *time.Time
+	// NamePrefix และ PhonePrefix ค้นหาแบบขึ้นต้นด้วย (ตรงตัวพิมพ์)
+	NamePrefix  string
+	PhonePrefix string
+	// Sort คือ UserSort* (ว่าง = score) เรียงจากมากไปน้อยเว้นแต่ Ascending
+	// ค่าเท่ากันเรียงตาม ID จากน้อยไปมาก
+	Sort      string
+	Ascending bool
+	Cursor    string
+	Limit     int
+}
+
+// UserPage คือผลลัพธ์หนึ่งหน้า NextCursor ว่างเมื่อไม่มีหน้าถัดไป
+type UserPage struct {
+	Users      []models.User
+	NextCursor string
+}
+
+// UserStore จัดการข้อมูลผู้ใช้
+// เบอร์โทร (User.Phone) ต้องแปลงเป็น E.164 ด้วย phones.Normalize ก่อนส่งเข้ามา
+// ทุกเมธอดที่บันทึกเบอร์จะจองเบอร์ใน phone_index ใน transaction เดียวกัน และคืน ErrPhoneTaken ถ้าเบอร์เป็นของคนอื่น
+// ผู้ใช้ที่ถูก soft-delete ยังถือเบอร์ไว้จนกว่าจะถูก purge เพื่อให้ยกเลิกการลบได้
+type UserStore interface {
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	// GetUsers อ่านผู้ใช้หลายคนพร้อมกัน คืน map ตาม ID (ID ที่ไม่พบจะไม่อยู่ใน map)
+	GetUsers(ctx context.Context, ids []string) (map[string]models.User, error)
+	// CreateUser บันทึกผู้ใช้ใหม่และคืน ID ที่สร้างให้ (ใส่ไว้ใน user.ID ด้วย)
+	CreateUser(ctx context.Context, user *models.User) (string, error)
+	UpdateUser(ctx context.Context, id string, update UserUpdate) error
+	// FindUserByPhone หาเจ้าของเบอร์จาก phone_index
+	FindUserByPhone(ctx context.Context, phone string) (*models.User, error)
+	FindUserByFirebaseUID(ctx context.Context, firebaseUID string) (*models.User, error)
+	ListUsers(ctx context.Context) ([]models.User, error)
+	// SearchUsers ค้นหาผู้ใช้ตามเงื่อนไขทีละหน้า
+	SearchUsers(ctx context.Context, query UserQuery) (*UserPage, error)
+	IncrementStats(ctx context.Context, id string, delta StatsDelta) error
+	// UpdateUserStats อ่านผู้ใช้แล้วเรียก fn ภายใน transaction
+	// fn แก้ได้เฉพาะ Minute, Score, NumberTree และ TreeProgress ถ้า fn คืน error จะไม่มีการบันทึก
+	UpdateUserStats(ctx context.Context, id string, fn func(user *models.User) error) (*models.User, error)
+	CountUsers(ctx context.Context) (int, error)
+	// ModifyUser อ่านผู้ใช้แล้วเรียก fn ภายใน transaction และบันทึกทุก field ที่ fn แก้ (ยกเว้น ID)
+	// ใช้กับการแก้ไขโดย admin จึงไม่บวกยอดเข้า PeriodStats ถ้า fn คืน error จะไม่มีการบันทึก
+	ModifyUser(ctx context.Context, id string, fn func(user *models.User) error) (*models.User, error)
+	// ModifyUserWithAdmins เหมือน ModifyUser แต่ fn ได้รับ admin ทุกคนด้วย (รวมผู้ใช้เองถ้าเป็น admin)
+	// ใช้ตรวจว่าการแก้ไขไม่ทำให้ระบบไม่มี admin ที่ใช้งานได้เหลืออยู่
+	ModifyUserWithAdmins(ctx context.Context, id string, fn func(user *models.User, admins []models.User) error) (*models.User, error)
+	// ChangeUserRole เปลี่ยน role ของผู้ใช้ใน transaction
+	// check ได้รับผู้ใช้และ admin ทุกคน (รวมผู้ใช้เองถ้าเป็น admin) ถ้า check คืน error จะไม่มีการบันทึก
+	ChangeUserRole(ctx context.Context, id, role string, check func(user *models.User, admins []models.User) error) (*models.User, error)
+	// ListUsersToPurge คืนผู้ใช้ที่ถูก soft-delete และครบกำหนด purge (purge_at <= now) ไม่เกิน limit คน
+	ListUsersToPurge(ctx context.Context, now time.Time, limit int) ([]models.User, error)
+	// PurgeUser ลบผู้ใช้ที่ครบกำหนด purge แล้วอย่างถาวร พร้อม session, activity, review, PeriodStats, Friendship,
+	// การจองเบอร์โทร, OTP (ของเบอร์และของผู้ใช้) และผลลัพธ์ที่เก็บไว้ตอบซ้ำ (Idempotency-Key) ของผู้ใช้
+	// คืน ErrNotFound ถ้าไม่พบผู้ใช้หรือยังไม่ครบกำหนด (เช่นถูกกู้คืนไปแล้ว)
+	PurgeUser(ctx context.Context, id string, now time.Time) error
+}
+
+// --- Leaderboard ---
+
+// LeaderboardRow คือสมาชิกหนึ่งคนบน leaderboard
+// Rank นับแบบมีอันดับร่วม (1, 2, 2, 4) เมื่อ number_tree และ score เท่ากัน
+type LeaderboardRow struct {
+	Rank       int
+	UserID     string
+	Name       string
+	NumberTree int
+	Score      int
+}
+
+// LeaderboardQuery คือเงื่อนไขการดึง leaderboard หนึ่งหน้า
+type LeaderboardQuery struct {
+	// Period คือ models.PeriodDay/Week/Month สำหรับช่วงเวลาปัจจุบัน หรือ PeriodAll (ว่าง = PeriodAll)
+	Period string
+	Cursor string // ค่า NextCursor จากหน้าก่อนหน้า (ว่าง = เริ่มจากอันดับ 1)
+	Limit  int
+}
+
+// LeaderboardPage คือผลลัพธ์หนึ่งหน้า NextCursor ว่างเมื่อไม่มีหน้าถัดไป
+type LeaderboardPage struct {
+	PeriodKey  string // รหัสช่วงเวลาที่ใช้จัดอันดับ (ว่างสำหรับ PeriodAll)
+	Rows       []LeaderboardRow
+	NextCursor string
+}
+
+// LeaderboardAround คืออันดับของผู้ใช้หนึ่งคนพร้อมคนที่อยู่ติดกันด้านบนและด้านล่าง
+type LeaderboardAround struct {
+	PeriodKey string
+	Me        LeaderboardRow
+	Above     []LeaderboardRow // เรียงตามอันดับ (คนที่อยู่ติดกับ Me อยู่ท้ายสุด)
+	Below     []LeaderboardRow
+}
+
+// LeaderboardStore จัดอันดับสมาชิก (role member) ตาม number_tree แล้ว score จากมากไปน้อย
+// ถ้าเท่ากันทั้งคู่ เรียงตาม user ID เพื่อให้ลำดับคงที่ระหว่างการแบ่งหน้า
+// leaderboard รายช่วงเวลาใช้ยอดจาก models.PeriodStats ซึ่ง store อัปเดตเองทุกครั้งที่
+// บันทึกกิจกรรม ต้นไม้โต หรือกิจกรรมถูกปฏิเสธ (ตัดรอบตาม periods.Calendar ของ store)
+type LeaderboardStore interface {
+	Leaderboard(ctx context.Context, query LeaderboardQuery) (*LeaderboardPage, error)
+	// LeaderboardAround คืน ErrNotFound ถ้าผู้ใช้ไม่ได้อยู่บน leaderboard
+	// (เช่นเป็น admin หรือยังไม่มียอดในช่วงเวลานั้น)
+	LeaderboardAround(ctx context.Context, period, userID string, n int) (*LeaderboardAround, error)
+	// RenameLeaderboardEntries เปลี่ยนชื่อของผู้ใช้ใน PeriodStats และ standings ของฤดูกาลที่ปิดแล้ว
+	// ใช้ปิดชื่อของบัญชีที่ถูกลบ (models.DeletedUserName) และคืนชื่อเดิมเมื่อกู้คืน
+	// leaderboard ตลอดกาลอ่านชื่อจากผู้ใช้โดยตรงและแสดง models.DeletedUserName ให้บัญชีที่ถูกลบแล้ว
+	RenameLeaderboardEntries(ctx context.Context, userID, name string) error
+}
+
+// --- Login OTPs ---
+
+// OTPOutcome คือผลการตรวจรหัส OTP ซึ่งกำหนดว่าจะทำอะไรกับรหัสต่อ
+type OTPOutcome int
+
+const (
+	// OTPAccepted รหัสถูกต้อง ลบทิ้งเพื่อไม่ให้ใช้ซ้ำ
+	OTPAccepted OTPOutcome = iota
+	// OTPRejected รหัสผิด เพิ่มจำนวนครั้งที่ลอง
+	OTPRejected
+	// OTPDiscarded รหัสใช้ไม่ได้แล้ว (หมดอายุหรือลองเกินกำหนด) ลบทิ้ง
+	OTPDiscarded
+)
+
+// OTPStore จัดการรหัส OTP (1 รหัสต่อ ID ดู models.LoginOTPID)
+type OTPStore interface {
+	GetLoginOTP(ctx context.Context, id string) (*models.LoginOTP, error)
+	// SaveLoginOTP บันทึกรหัสใหม่ทับรหัสเดิมที่มี ID เดียวกัน
+	SaveLoginOTP(ctx context.Context, otp *models.LoginOTP) error
+	DeleteLoginOTP(ctx context.Context, id string) error
+	// ConsumeLoginOTP อ่านรหัสภายใน transaction แล้วทำตามผลที่ check คืนมา
+	// คืน ErrNotFound ถ้าไม่มีรหัสนี้
+	ConsumeLoginOTP(ctx context.Context, id string, check func(otp *models.LoginOTP) OTPOutcome) error
+}
+
+// --- Sessions ---
+
+// SessionStore จัดการ session และ Refresh Token
+type SessionStore interface {
+	// CreateSession บันทึก session ใหม่และคืน ID ที่สร้างให้ (ใส่ไว้ใน session.ID ด้วย)
+	CreateSession(ctx context.Context, session *models.Session) (string, error)
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	ListUserSessions(ctx context.Context, userID string) ([]models.Session, error)
+	// UpdateSession อ่าน session แล้วเรียก fn ภายใน transaction ถ้า fn คืน error จะไม่มีการบันทึก
+	UpdateSession(ctx context.Context, id string, fn func(session *models.Session) error) (*models.Session, error)
+	// RevokeSession เพิกถอน session ของผู้ใช้ (คืน ErrNotFound ถ้าไม่พบหรือไม่ใช่ของผู้ใช้คนนี้)
+	RevokeSession(ctx context.Context, userID, id string) error
+}
+
+// --- Activities ---
+
+// ActivityQuery คือเงื่อนไขการดึงประวัติกิจกรรม เรียงจากใหม่ไปเก่าตาม started_at
+type ActivityQuery struct {
+	// From และ To กรองตาม started_at (From <= started_at < To) ถ้าเป็น nil จะไม่กรอง
+	From *time.Time
+	To   *time.Time
+	// Cursor คือค่า NextCursor จากหน้าก่อนหน้า (ว่าง = หน้าแรก)
+	Cursor string
+	Limit  int
+}
+
+// ActivityPage คือผลลัพธ์หนึ่งหน้า NextCursor ว่างเมื่อไม่มีหน้าถัดไป
+type ActivityPage struct {
+	Activities []models.Activity `json:"activities"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// ActivityWindow คือช่วง started_at (From <= started_at < To) ของกิจกรรมเดิม
+// ที่ต้องใช้ตัดสินกิจกรรมใหม่ เช่นหาการทับซ้อนและยอดรวมรายวัน
+type ActivityWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// ActivityStore จัดการประวัติการออกกำลังกาย
+type ActivityStore interface {
+	// RecordActivity บันทึกกิจกรรมและบวก minute/score ของผู้ใช้ใน transaction เดียวกัน
+	// check ถูกเรียกใน transaction พร้อมกิจกรรมเดิมของผู้ใช้ในช่วง window
+	// และแก้ activity ได้ (เช่นใส่คะแนน) ถ้าคืน error จะไม่บันทึกอะไรเลย
+	// ถ้า activity.ReviewStatus เป็น pending จะสร้าง ActivityReview ไปพร้อมกัน
+	// (ใส่ ID ที่สร้างให้ไว้ใน activity.ID)
+	RecordActivity(ctx context.Context, activity *models.Activity, window ActivityWindow, check func(existing []models.Activity) error) error
+	ListActivities(ctx context.Context, userID string, query ActivityQuery) (*ActivityPage, error)
+}
+
+// --- Activity reviews ---
+
+// ReviewQuery คือเงื่อนไขการดึงคิวตรวจสอบ เรียงจากใหม่ไปเก่าตาม created_at
+type ReviewQuery struct {
+	Status string // ว่าง = ทุกสถานะ
+	Cursor string
+	Limit  int
+}
+
+// ReviewPage คือผลลัพธ์หนึ่งหน้า NextCursor ว่างเมื่อไม่มีหน้าถัดไป
+type ReviewPage struct {
+	Reviews    []models.ActivityReview `json:"reviews"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+// ReviewStore จัดการคิวตรวจสอบกิจกรรมที่น่าสงสัย
+type ReviewStore interface {
+	ListActivityReviews(ctx context.Context, query ReviewQuery) (*ReviewPage, error)
+	// ResolveActivityReview แก้ไขรายการตรวจสอบใน transaction ผ่าน fn
+	// ถ้า fn เปลี่ยนสถานะเป็น rejected จะหักนาทีและคะแนนของกิจกรรมออกจากผู้ใช้ด้วย
+	ResolveActivityReview(ctx context.Context, id string, fn func(review *models.ActivityReview) error) (*models.ActivityReview, error)
+}
+
+// --- Idempotency keys ---
+
+// IdempotencyStore เก็บผลลัพธ์ของคำขอที่มี Idempotency-Key
+type IdempotencyStore interface {
+	// BeginIdempotentRequest จอง record.ID ถ้ายังไม่มี หรือ record เดิมหมดอายุแล้ว (ExpiresAt <= now) และคืน nil
+	// ถ้ามี record ที่ยังไม่หมดอายุอยู่แล้ว จะไม่แก้อะไรและคืน record เดิมกลับมา
+	BeginIdempotentRequest(ctx context.Context, record *models.IdempotencyRecord, now time.Time) (*models.IdempotencyRecord, error)
+	// CompleteIdempotentRequest บันทึกผลลัพธ์และเปลี่ยนสถานะเป็น completed
+	CompleteIdempotentRequest(ctx context.Context, record *models.IdempotencyRecord) error
+	GetIdempotentRequest(ctx context.Context, id string) (*models.IdempotencyRecord, error)
+	// DeleteIdempotentRequest ปล่อยการจอง เพื่อให้ retry ครั้งถัดไปทำงานใหม่ได้
+	DeleteIdempotentRequest(ctx context.Context, id string) error
+}
+
+// --- Seasons ---
+
+// SeasonStore จัดการฤดูกาลแข่งขันและอันดับสุดท้ายของฤดูกาลที่ปิดแล้ว
+// ยอดของฤดูกาลที่เปิดอยู่สะสมใน PeriodStats (period = season) แบบเดียวกับ leaderboard รายช่วงเวลา
+type SeasonStore interface {
+	// CreateSeason บันทึกฤดูกาลใหม่และใส่ ID ที่สร้างให้ไว้ใน season.ID
+	// check ถูกเรียกใน transaction พร้อมฤดูกาลทั้งหมดที่มีอยู่ ถ้าคืน error จะไม่บันทึก
+	CreateSeason(ctx context.Context, season *models.Season, check func(existing []models.Season) error) error
+	GetSeason(ctx context.Context, id string) (*models.Season, error)
+	// ListSeasons คืนทุกฤดูกาล เรียงจากที่เริ่มล่าสุดก่อน
+	ListSeasons(ctx context.Context) ([]models.Season, error)
+	// CloseSeason เรียก fn ใน transaction (เช่นตรวจสถานะและใส่ ClosedBy) แล้วหยุดรับยอดของฤดูกาล
+	// และบันทึก leaderboard สุดท้ายลง standings จากนั้นเปลี่ยนสถานะเป็น closed
+	CloseSeason(ctx context.Context, id string, fn func(season *models.Season) error) (*models.Season, error)
+	// SeasonLeaderboard และ SeasonLeaderboardAround จัดอันดับของฤดูกาลแบบเดียวกับ LeaderboardStore
+	// ฤดูกาลที่ยังไม่ปิดใช้ยอดสะสมปัจจุบัน ฤดูกาลที่ปิดแล้วใช้ standings
+	SeasonLeaderboard(ctx context.Context, season *models.Season, query LeaderboardQuery) (*LeaderboardPage, error)
+	SeasonLeaderboardAround(ctx context.Context, season *models.Season, userID string, n int) (*LeaderboardAround, error)
+}
+
+// --- Admin jobs ---
+
+// JobStore จัดการงานเบื้องหลังของ admin และข้อมูลที่แต่ละ batch ของงานเขียน
+// แต่ละ batch ถูก commit พร้อมกับความคืบหน้าของงาน (Cursor) ใน transaction เดียวกัน
+type JobStore interface {
+	// CreateJob บันทึกงานใหม่และใส่ ID ที่สร้างให้ไว้ใน job.ID
+	CreateJob(ctx context.Context, job *models.Job) error
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+	// UpdateJob อ่านงานแล้วเรียก fn ภายใน transaction ถ้า fn คืน error จะไม่มีการบันทึก
+	UpdateJob(ctx context.Context, id string, fn func(job *models.Job) error) (*models.Job, error)
+	// ListJobsByStatus คืนงานที่อยู่ในสถานะใดสถานะหนึ่งใน statuses
+	ListJobsByStatus(ctx context.Context, statuses ...string) ([]models.Job, error)
+	// ResetStatsBatch รีเซ็ตผู้ใช้ถัดจาก job.Cursor (เรียงตาม ID) ไม่เกิน limit คน
+	// บันทึก StatsSnapshot ของคนที่มีสถิติไว้ก่อน (ถ้า job.DryRun จะนับอย่างเดียวไม่เขียนอะไร)
+	// done เป็น true เมื่อไม่มีผู้ใช้เหลือแล้ว
+	ResetStatsBatch(ctx context.Context, jobID string, limit int) (job *models.Job, done bool, err error)
+	// RestoreStatsBatch บวกสถิติจาก StatsSnapshot ถัดจาก job.UndoCursor คืนให้ผู้ใช้ ไม่เกิน limit คน
+	// (บวกคืนแทนการเขียนทับ เพื่อไม่ให้ยอดที่ได้หลังรีเซ็ตหายไป)
+	RestoreStatsBatch(ctx context.Context, jobID string, limit int) (job *models.Job, done bool, err error)
+}
+
+// newActivityReview สร้างรายการตรวจสอบสำหรับกิจกรรมที่ถูก flag (ใช้ ID เดียวกับกิจกรรม)
+func newActivityReview(activity *models.Activity) models.ActivityReview {
+	return models.ActivityReview{
+		ID:              activity.ID,
+		UserID:          activity.UserID,
+		ActivityType:    activity.Type,
+		StartedAt:       activity.StartedAt,
+		DurationMinutes: activity.DurationMinutes,
+		ScoreAwarded:    activity.ScoreAwarded,
+		Flags:           activity.Flags,
+		Status:          models.ReviewStatusPending,
+		CreatedAt:       activity.CreatedAt,
+	}
+}
+
+// rejectedNow บอกว่าการเปลี่ยนสถานะนี้ต้องหักคะแนนคืนหรือไม่ (หักครั้งเดียวตอนเปลี่ยนเป็น rejected)
+func rejectedNow(previous, current string) bool {
+	return previous != models.ReviewStatusRejected && current == models.ReviewStatusRejected
+}
+
+// --- Friends ---
+
+// FriendStore เก็บความเป็นเพื่อนของผู้ใช้ (หนึ่ง models.Friendship ต่อคู่ ID คือ models.FriendshipID)
+type FriendStore interface {
+	// GetFriendship คืน ErrNotFound ถ้าทั้งสองคนยังไม่มีความสัมพันธ์กัน
+	GetFriendship(ctx context.Context, userID, otherID string) (*models.Friendship, error)
+	// UpdateFriendship อ่านความสัมพันธ์ของคู่ผู้ใช้ (nil = ยังไม่มี) แล้วเรียก fn ภายใน transaction
+	// fn คืนค่าที่จะบันทึก หรือ nil เพื่อลบความสัมพันธ์ ถ้า fn คืน error จะไม่มีการบันทึก
+	UpdateFriendship(ctx context.Context, userID, otherID string, fn func(friendship *models.Friendship) (*models.Friendship, error)) (*models.Friendship, error)
+	// ListFriendships คืนทุกความสัมพันธ์ (ทั้ง pending และ accepted) ที่ผู้ใช้เป็นฝ่ายใดฝ่ายหนึ่ง
+	ListFriendships(ctx context.Context, userID string) ([]models.Friendship, error)
+}
+
+// --- Audit log ---
+
+// AuditQuery คือเงื่อนไขการดึง audit log เรียงจากใหม่ไปเก่าตาม created_at (ค่าว่างหรือ nil = ไม่กรอง)
+type AuditQuery struct {
+	ActorID  string
+	TargetID string
+	Action   string
+	// From และ To กรองตาม created_at (From <= created_at < To)
+	From   *time.Time
+	To     *time.Time
+	Cursor string
+	Limit  int
+}
+
+// AuditPage คือผลลัพธ์หนึ่งหน้า NextCursor ว่างเมื่อไม่มีหน้าถัดไป
+type AuditPage struct {
+	Entries    []models.AuditEntry `json:"entries"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// AuditStore เก็บ audit log แบบเพิ่มได้อย่างเดียว (ไม่มีเมธอดแก้ไขหรือลบโดยตั้งใจ)
+type AuditStore interface {
+	// AppendAudit บันทึก entry ใหม่และใส่ ID ที่สร้างให้ไว้ใน entry.ID
+	AppendAudit(ctx context.Context, entry *models.AuditEntry) error
+	ListAudit(ctx context.Context, query AuditQuery) (*AuditPage, error)
+}
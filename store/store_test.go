@@ -0,0 +1,361 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"meerank/database"
+	"meerank/models"
+	"meerank/periods"
+	"meerank/store"
+)
+
+// ทุก backend ต้องทำงานเหมือนกันตามที่ store.Store กำหนด
+// Firestore ต้องต่อ emulator จึงไม่อยู่ในชุดนี้
+var backends = []struct {
+	name string
+	open func(t *testing.T, cal *periods.Calendar) store.Store
+}{
+	{"memory", func(t *testing.T, cal *periods.Calendar) store.Store {
+		return store.NewMemoryStore(cal)
+	}},
+	{"sqlite", func(t *testing.T, cal *periods.Calendar) store.Store {
+		// ใช้ไฟล์แยกต่อ test เพื่อไม่ให้ข้อมูลปนกัน
+		db, err := database.SetupSQLDatabase("sqlite", filepath.Join(t.TempDir(), "meerank.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+
+		st := store.NewSQLStore(db, cal)
+		if err := st.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}},
+}
+
+// forEachBackend รัน test กับทุก backend โดยเริ่มจาก store ว่าง
+func forEachBackend(t *testing.T, test func(t *testing.T, st store.Store)) {
+	cal, err := periods.NewCalendar("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t, cal))
+		})
+	}
+}
+
+func createUser(t *testing.T, st store.Store, name, phone string) *models.User {
+	t.Helper()
+	user := &models.User{Name: name, Role: models.RoleMember}
+	if phone != "" {
+		user.Phone = &phone
+	}
+	if _, err := st.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func getUser(t *testing.T, st store.Store, id string) *models.User {
+	t.Helper()
+	user, err := st.GetUser(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestUpdateUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		user := createUser(t, st, "Somchai", "+66812345678")
+
+		// แก้เฉพาะ field ที่ส่งมา field อื่นคงเดิม
+		name, age := "Somsak", 30
+		if err := st.UpdateUser(ctx, user.ID, store.UserUpdate{Name: &name, Age: &age}); err != nil {
+			t.Fatal(err)
+		}
+		got := getUser(t, st, user.ID)
+		if got.Name != name || got.Age == nil || *got.Age != age {
+			t.Errorf("name/age = %q/%v, want %q/%d", got.Name, got.Age, name, age)
+		}
+		if got.Phone == nil || *got.Phone != "+66812345678" {
+			t.Errorf("phone = %v, want it unchanged", got.Phone)
+		}
+
+		if owner, err := st.FindUserByPhone(ctx, "+66812345678"); err != nil || owner.ID != user.ID {
+			t.Errorf("FindUserByPhone = %v (%v), want %s", owner, err, user.ID)
+		}
+		if _, err := st.FindUserByPhone(ctx, "+66899999999"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("unknown phone: err = %v, want ErrNotFound", err)
+		}
+		if err := st.UpdateUser(ctx, "missing", store.UserUpdate{Name: &name}); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("missing user: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestUpdateUserStats(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		user := createUser(t, st, "Somchai", "+66812345678")
+
+		// บันทึกเฉพาะสถิติ field อื่นที่ fn แก้ไม่ถูกบันทึก
+		_, err := st.UpdateUserStats(ctx, user.ID, func(u *models.User) error {
+			u.Minute += 30
+			u.Score += 300
+			u.NumberTree++
+			u.TreeProgress = 50
+			u.Name = "Changed"
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := getUser(t, st, user.ID)
+		if got.Minute != 30 || got.Score != 300 || got.NumberTree != 1 || got.TreeProgress != 50 {
+			t.Errorf("stats = %d/%d/%d/%d, want 30/300/1/50", got.Minute, got.Score, got.NumberTree, got.TreeProgress)
+		}
+		if got.Name != "Somchai" {
+			t.Errorf("name = %q, want it unchanged", got.Name)
+		}
+
+		// fn คืน error แล้วต้องไม่มีการบันทึก
+		errStop := errors.New("stop")
+		_, err = st.UpdateUserStats(ctx, user.ID, func(u *models.User) error {
+			u.Score = 0
+			return errStop
+		})
+		if !errors.Is(err, errStop) {
+			t.Fatalf("err = %v, want %v", err, errStop)
+		}
+		if got := getUser(t, st, user.ID); got.Score != 300 {
+			t.Errorf("score = %d after failed update, want 300", got.Score)
+		}
+
+		if _, err := st.UpdateUserStats(ctx, "missing", func(*models.User) error { return nil }); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("missing user: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestUpdateSession(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		user := createUser(t, st, "Somchai", "")
+		now := time.Now().UTC().Truncate(time.Second)
+		session := &models.Session{
+			UserID:           user.ID,
+			RefreshTokenHash: "hash",
+			CreatedAt:        now,
+			LastUsedAt:       now,
+			ExpiresAt:        now.Add(time.Hour),
+		}
+		id, err := st.CreateSession(ctx, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		revokedAt := now.Add(time.Minute)
+		_, err = st.UpdateSession(ctx, id, func(s *models.Session) error {
+			s.RefreshTokenHash = "rotated"
+			s.RevokedAt = &revokedAt
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := st.GetSession(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.RefreshTokenHash != "rotated" || got.RevokedAt == nil || !got.RevokedAt.Equal(revokedAt) {
+			t.Errorf("session = %q revoked at %v, want rotated revoked at %v", got.RefreshTokenHash, got.RevokedAt, revokedAt)
+		}
+		if got.UserID != user.ID {
+			t.Errorf("user_id = %q, want %q", got.UserID, user.ID)
+		}
+
+		// fn คืน error แล้วต้องไม่มีการบันทึก
+		errStop := errors.New("stop")
+		_, err = st.UpdateSession(ctx, id, func(s *models.Session) error {
+			s.RefreshTokenHash = "lost"
+			return errStop
+		})
+		if !errors.Is(err, errStop) {
+			t.Fatalf("err = %v, want %v", err, errStop)
+		}
+		if got, _ := st.GetSession(ctx, id); got.RefreshTokenHash != "rotated" {
+			t.Errorf("hash = %q after failed update, want rotated", got.RefreshTokenHash)
+		}
+
+		if _, err := st.UpdateSession(ctx, "missing", func(*models.Session) error { return nil }); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("missing session: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestPhoneUniqueness(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		alice := createUser(t, st, "Alice", "+66811111111")
+		bob := createUser(t, st, "Bob", "+66822222222")
+
+		taken := "+66811111111"
+		if _, err := st.CreateUser(ctx, &models.User{Name: "Carol", Role: models.RoleMember, Phone: &taken}); !errors.Is(err, store.ErrPhoneTaken) {
+			t.Errorf("CreateUser with taken phone: err = %v, want ErrPhoneTaken", err)
+		}
+		if err := st.UpdateUser(ctx, bob.ID, store.UserUpdate{Phone: &taken}); !errors.Is(err, store.ErrPhoneTaken) {
+			t.Errorf("UpdateUser to taken phone: err = %v, want ErrPhoneTaken", err)
+		}
+		if _, err := st.ModifyUser(ctx, bob.ID, func(u *models.User) error { u.Phone = &taken; return nil }); !errors.Is(err, store.ErrPhoneTaken) {
+			t.Errorf("ModifyUser to taken phone: err = %v, want ErrPhoneTaken", err)
+		}
+		if got := getUser(t, st, bob.ID); got.Phone == nil || *got.Phone != "+66822222222" {
+			t.Errorf("bob's phone = %v after rejected changes, want +66822222222", got.Phone)
+		}
+
+		// เปลี่ยนเบอร์แล้วเบอร์เดิมว่างให้คนอื่นใช้ได้
+		fresh := "+66833333333"
+		if err := st.UpdateUser(ctx, alice.ID, store.UserUpdate{Phone: &fresh}); err != nil {
+			t.Fatal(err)
+		}
+		if owner, err := st.FindUserByPhone(ctx, fresh); err != nil || owner.ID != alice.ID {
+			t.Errorf("owner of new phone = %v (%v), want alice", owner, err)
+		}
+		if _, err := st.FindUserByPhone(ctx, taken); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("old phone: err = %v, want ErrNotFound", err)
+		}
+		if err := st.UpdateUser(ctx, bob.ID, store.UserUpdate{Phone: &taken}); err != nil {
+			t.Errorf("UpdateUser to released phone: %v", err)
+		}
+	})
+}
+
+func TestPurgeUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		phone := "+66812345678"
+		user := createUser(t, st, "Somchai", phone)
+		other := createUser(t, st, "Other", "+66899999999")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		for _, owner := range []*models.User{user, other} {
+			session := &models.Session{UserID: owner.ID, RefreshTokenHash: "hash", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+			if _, err := st.CreateSession(ctx, session); err != nil {
+				t.Fatal(err)
+			}
+			record := &models.IdempotencyRecord{ID: "key-" + owner.ID, UserID: owner.ID, Key: "k", RequestHash: "h", Status: models.IdempotencyCompleted, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+			if _, err := st.BeginIdempotentRequest(ctx, record, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := st.SaveLoginOTP(ctx, &models.LoginOTP{ID: models.LoginOTPID(phone), Phone: phone, CodeHash: "hash", ExpiresAt: now.Add(time.Minute), CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+		pending := "+66877777777"
+		if err := st.SaveLoginOTP(ctx, &models.LoginOTP{ID: models.PhoneChangeOTPID(user.ID, pending), Phone: pending, UserID: user.ID, CodeHash: "hash", ExpiresAt: now.Add(time.Minute), CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+
+		// ยังไม่ถูกลบ จึง purge ไม่ได้
+		if err := st.PurgeUser(ctx, user.ID, now); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("purge of active user: err = %v, want ErrNotFound", err)
+		}
+
+		purgeAt := now.Add(time.Hour)
+		if _, err := st.ModifyUser(ctx, user.ID, func(u *models.User) error {
+			u.DeletedAt, u.PurgeAt = &now, &purgeAt
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.PurgeUser(ctx, user.ID, now); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("purge before purge_at: err = %v, want ErrNotFound", err)
+		}
+		if err := st.PurgeUser(ctx, user.ID, purgeAt); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := st.GetUser(ctx, user.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("GetUser after purge: err = %v, want ErrNotFound", err)
+		}
+		if sessions, err := st.ListUserSessions(ctx, user.ID); err != nil || len(sessions) != 0 {
+			t.Errorf("sessions after purge = %d (%v), want 0", len(sessions), err)
+		}
+		if _, err := st.GetIdempotentRequest(ctx, "key-"+user.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("idempotency record after purge: err = %v, want ErrNotFound", err)
+		}
+		for _, otpID := range []string{models.LoginOTPID(phone), models.PhoneChangeOTPID(user.ID, pending)} {
+			if _, err := st.GetLoginOTP(ctx, otpID); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("OTP %s after purge: err = %v, want ErrNotFound", otpID, err)
+			}
+		}
+
+		// ข้อมูลของผู้ใช้คนอื่นยังอยู่
+		if sessions, err := st.ListUserSessions(ctx, other.ID); err != nil || len(sessions) != 1 {
+			t.Errorf("other user's sessions = %d (%v), want 1", len(sessions), err)
+		}
+		if _, err := st.GetIdempotentRequest(ctx, "key-"+other.ID); err != nil {
+			t.Errorf("other user's idempotency record: %v", err)
+		}
+
+		// เบอร์ว่างให้สมัครใหม่ได้
+		createUser(t, st, "Newcomer", phone)
+	})
+}
+
+func TestRecordActivityQueuesFlagged(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st store.Store) {
+		ctx := context.Background()
+		user := createUser(t, st, "Somchai", "")
+		start := time.Now().UTC().Truncate(time.Second).Add(-3 * time.Hour)
+
+		record := func(start time.Time, score int, status string) *models.Activity {
+			t.Helper()
+			activity := &models.Activity{
+				UserID:          user.ID,
+				Type:            models.ActivityTypeExercise,
+				StartedAt:       start,
+				EndedAt:         start.Add(30 * time.Minute),
+				DurationMinutes: 30,
+				CreatedAt:       start,
+			}
+			window := store.ActivityWindow{From: start.Add(-time.Hour), To: start.Add(time.Hour)}
+			err := st.RecordActivity(ctx, activity, window, func([]models.Activity) error {
+				activity.ScoreAwarded = score
+				activity.ReviewStatus = status
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return activity
+		}
+		record(start, 300, "")
+		flagged := record(start.Add(time.Hour), 200, models.ReviewStatusPending)
+
+		// เฉพาะกิจกรรมที่ถูก flag เข้าคิวตรวจสอบ แต่คะแนนของทั้งสองถูกบวกแล้ว
+		page, err := st.ListActivityReviews(ctx, store.ReviewQuery{Status: models.ReviewStatusPending, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Reviews) != 1 || page.Reviews[0].ID != flagged.ID || page.Reviews[0].UserID != user.ID {
+			t.Fatalf("pending reviews = %+v, want only activity %s", page.Reviews, flagged.ID)
+		}
+		if got := getUser(t, st, user.ID); got.Score != 500 || got.Minute != 60 {
+			t.Errorf("score/minute = %d/%d, want 500/60", got.Score, got.Minute)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"meerank/models"
+	"meerank/phones"
+)
+
+// userCursor คือตำแหน่งของผู้ใช้คนสุดท้ายในหน้าที่แล้ว (ค่าที่ใช้เรียง + ID)
+// Sort และ Ascending ถูกเก็บไว้ด้วย เพื่อไม่ให้ใช้ cursor ข้ามลำดับการเรียง
+type userCursor struct {
+	Sort      string
+	Ascending bool
+	Value     int64
+	ID        string
+}
+
+// userSortField คืนลำดับการเรียงของ query (ค่าที่ไม่รู้จักถือเป็น score)
+func userSortField(query UserQuery) string {
+	switch query.Sort {
+	case UserSortMinute, UserSortNumberTree, UserSortLastLogin:
+		return query.Sort
+	default:
+		return UserSortScore
+	}
+}
+
+// userSortValue คืนค่าที่ใช้เรียงของผู้ใช้ (เวลาเป็น UnixNano)
+// ok เป็น false ถ้าผู้ใช้ไม่มีค่านั้น (ยังไม่เคยล็อกอิน)
+func userSortValue(user *models.User, sort string) (value int64, ok bool) {
+	switch sort {
+	case UserSortMinute:
+		return int64(user.Minute), true
+	case UserSortNumberTree:
+		return int64(user.NumberTree), true
+	case UserSortLastLogin:
+		if user.LastLoginAt == nil {
+			return 0, false
+		}
+		return user.LastLoginAt.UnixNano(), true
+	default:
+		return int64(user.Score), true
+	}
+}
+
+// sortArg แปลงค่าใน cursor กลับเป็นชนิดของคอลัมน์ เพื่อใช้เทียบใน query ของฐานข้อมูล
+func (c *userCursor) sortArg() interface{} {
+	if c.Sort == UserSortLastLogin {
+		return time.Unix(0, c.Value).UTC()
+	}
+	return c.Value
+}
+
+func encodeUserCursor(query UserQuery, user *models.User) string {
+	sort := userSortField(query)
+	value, _ := userSortValue(user, sort)
+	raw := fmt.Sprintf("%s|%t|%d|%s", sort, query.Ascending, value, user.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeUserCursor(query UserQuery) (*userCursor, error) {
+	if query.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 4)
+	if len(parts) != 4 || parts[3] == "" {
+		return nil, ErrInvalidCursor
+	}
+	c := &userCursor{Sort: parts[0], ID: parts[3]}
+	if _, err := fmt.Sscanf(parts[1]+" "+parts[2], "%t %d", &c.Ascending, &c.Value); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != userSortField(query) || c.Ascending != query.Ascending {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// userAfter บอกว่าผู้ใช้ที่มีค่า (value, id) อยู่หลัง cursor ตามลำดับของ query หรือไม่
+func (c *userCursor) userAfter(value int64, id string) bool {
+	if c == nil {
+		return true
+	}
+	if value != c.Value {
+		return (value > c.Value) == c.Ascending
+	}
+	return id > c.ID
+}
+
+// userMatches ตรวจผู้ใช้กับตัวกรองของ query (ใช้กับ backend ที่กรองเองในหน่วยความจำ)
+func userMatches(user *models.User, query UserQuery) bool {
+	if query.Role != "" && user.Role != query.Role {
+		return false
+	}
+	if query.Gender != "" && (user.Gender == nil || *user.Gender != query.Gender) {
+		return false
+	}
+	if query.MinAge != nil && (user.Age == nil || *user.Age < *query.MinAge) {
+		return false
+	}
+	if query.MaxAge != nil && (user.Age == nil || *user.Age > *query.MaxAge) {
+		return false
+	}
+	if query.LastLoginFrom != nil && (user.LastLoginAt == nil || user.LastLoginAt.Before(*query.LastLoginFrom)) {
+		return false
+	}
+	if query.LastLoginTo != nil && (user.LastLoginAt == nil || !user.LastLoginAt.Before(*query.LastLoginTo)) {
+		return false
+	}
+	if query.NamePrefix != "" && !strings.HasPrefix(user.Name, query.NamePrefix) {
+		return false
+	}
+	if query.PhonePrefix != "" && (user.Phone == nil || !strings.HasPrefix(*user.Phone, query.PhonePrefix)) {
+		return false
+	}
+	return true
+}
+
+// newUserPage ตัดผลลัพธ์ที่ดึงมา limit+1 คนให้เหลือหนึ่งหน้า พร้อมสร้าง cursor หน้าถัดไป
+func newUserPage(users []models.User, query UserQuery) *UserPage {
+	page := &UserPage{Users: users}
+	if len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		page.NextCursor = encodeUserCursor(query, &page.Users[query.Limit-1])
+	}
+	return page
+}
+
+// purgeDue บอกว่าผู้ใช้ถูก soft-delete และครบกำหนด purge ณ เวลา now แล้วหรือไม่
+func purgeDue(user *models.User, now time.Time) bool {
+	return user.DeletedAt != nil && user.PurgeAt != nil && !user.PurgeAt.After(now)
+}
+
+// phoneBackfill คือผลการแปลงเบอร์ของผู้ใช้ที่บันทึกไว้ก่อนมี phone_index
+type phoneBackfill struct {
+	Phones map[string]string // user ID -> เบอร์ E.164 (เฉพาะคนที่เบอร์เปลี่ยน)
+	Index  map[string]string // เบอร์ E.164 -> เจ้าของเบอร์
+}
+
+// planPhoneBackfill แปลงเบอร์ของ users เป็น E.164 และเลือกเจ้าของเบอร์ที่ซ้ำกัน
+// เบอร์ซ้ำเป็นของบัญชีที่ยังไม่ถูกลบและล็อกอินล่าสุด คนที่เหลือและเบอร์ที่แปลงไม่ได้จะไม่ถูกจอง
+// (ล็อกอินด้วย OTP ไม่ได้จนกว่า admin จะแก้เบอร์) ทุกกรณีถูกบันทึกใน log
+func planPhoneBackfill(users []models.User) phoneBackfill {
+	ranked := slices.Clone(users)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := &ranked[i], &ranked[j]
+		if (a.DeletedAt == nil) != (b.DeletedAt == nil) {
+			return a.DeletedAt == nil
+		}
+		var lastA, lastB time.Time
+		if a.LastLoginAt != nil {
+			lastA = *a.LastLoginAt
+		}
+		if b.LastLoginAt != nil {
+			lastB = *b.LastLoginAt
+		}
+		if !lastA.Equal(lastB) {
+			return lastA.After(lastB)
+		}
+		return a.ID < b.ID
+	})
+
+	plan := phoneBackfill{Phones: map[string]string{}, Index: map[string]string{}}
+	for _, user := range ranked {
+		if user.Phone == nil || *user.Phone == "" {
+			continue
+		}
+		phone, err := phones.Normalize(*user.Phone)
+		if err != nil {
+			log.Printf("Phone backfill: user %s has an invalid phone number %q, left unindexed", user.ID, *user.Phone)
+			continue
+		}
+		if phone != *user.Phone {
+			plan.Phones[user.ID] = phone
+		}
+		if owner, ok := plan.Index[phone]; ok {
+			log.Printf("Phone backfill: user %s shares phone %s with user %s, left unindexed", user.ID, phone, owner)
+			continue
+		}
+		plan.Index[phone] = user.ID
+	}
+	return plan
+}
@@ -0,0 +1,210 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"meerank/apperror"
+	"meerank/i18n"
+	"meerank/models"
+	"meerank/phones"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// รหัสของข้อผิดพลาดราย field (FieldError.Code) ให้ client ใช้ตัดสินใจแทนการอ่านข้อความ
+const (
+	CodeRequired      = "required"
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeTooSmall      = "too_small"
+	CodeTooLarge      = "too_large"
+	CodeInvalidChoice = "invalid_choice"
+	CodeInvalidPhone  = "invalid_phone"
+	CodeInvalidType   = "invalid_type"
+	CodeReserved      = "reserved"
+	CodeMustBeAfter   = "must_be_after"
+	CodeInvalid       = "invalid"
+)
+
+// Codes คือรหัสทั้งหมดข้างบน ใช้ตรวจตอนเริ่มโปรแกรมว่าทุกรหัสมีข้อความครบทุกภาษา (ดู i18n.Validate)
+var Codes = []string{
+	CodeRequired,
+	CodeTooShort,
+	CodeTooLong,
+	CodeTooSmall,
+	CodeTooLarge,
+	CodeInvalidChoice,
+	CodeInvalidPhone,
+	CodeInvalidType,
+	CodeReserved,
+	CodeMustBeAfter,
+	CodeInvalid,
+}
+
+// FieldError คือ field หนึ่งที่ไม่ผ่านการตรวจ
+// Param คือค่าที่ใช้ประกอบข้อความ เช่นความยาวสูงสุด หรือรายการค่าที่อนุญาต
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"-"`
+}
+
+// Errors คือรายการ field ที่ไม่ผ่านการตรวจของคำขอหนึ่ง
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	fields := make([]string, len(e))
+	for i, fe := range e {
+		fields[i] = fe.Field + ": " + fe.Code
+	}
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+// Field สร้าง FieldError สำหรับกฎที่เขียนเป็น tag ไม่ได้ (เช่นกฎที่ขึ้นกับหลาย field)
+func Field(field, code, param string) FieldError {
+	return FieldError{Field: field, Code: code, Param: param}
+}
+
+var registerOnce sync.Once
+
+// register เพิ่มกฎที่ใช้ใน tag binding ของ payload ให้ validator ของ gin
+//   - notblank: string ต้องมีตัวอักษรที่ไม่ใช่ช่องว่าง
+//   - phone: แปลงเป็น E.164 ได้ (ดู phones.Normalize)
+//   - gender: เป็นค่าใน models.Genders
+//   - locale: เป็นภาษาที่รองรับ (i18n.Locales) หรือค่าว่าง (ลบค่าที่ตั้งไว้)
+//
+// และให้ชื่อ field ในข้อผิดพลาดเป็นชื่อใน JSON
+func register() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	_ = v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	_ = v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		_, err := phones.Normalize(fl.Field().String())
+		return err == nil
+	})
+	_ = v.RegisterValidation("gender", func(fl validator.FieldLevel) bool {
+		return models.ValidGender(fl.Field().String())
+	})
+	_ = v.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		locale := fl.Field().String()
+		return locale == "" || i18n.Supported(locale)
+	})
+}
+
+// BindJSON อ่าน JSON payload ใส่ obj แล้วตรวจตามกฎใน tag binding
+// ถ้าไม่ผ่านจะส่ง error 422 พร้อมรายการ field ที่ผิด (หรือ 400 ถ้า JSON อ่านไม่ได้) ให้ ErrorMiddleware แล้วคืน false
+func BindJSON(c *gin.Context, obj any) bool {
+	registerOnce.Do(register)
+
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+	if errs, ok := fieldErrors(err); ok {
+		Respond(c, errs)
+		return false
+	}
+	invalid := apperror.BadRequest(apperror.CodeInvalidInput, "Invalid input")
+	// บอกตำแหน่งที่ JSON ผิดรูปแบบ ส่วน error อื่นอาจมีรายละเอียดภายในของเซิร์ฟเวอร์ จึงไม่ส่งกลับไป
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		invalid = invalid.WithDetails(gin.H{"reason": err.Error()})
+	}
+	apperror.Abort(c, invalid)
+	return false
+}
+
+// Respond ตอบ 422 พร้อมรายการ field ที่ไม่ผ่านการตรวจใน details.fields (ข้อความตามภาษาของผู้ใช้)
+func Respond(c *gin.Context, errs Errors) {
+	locale := i18n.Locale(c)
+	for i := range errs {
+		errs[i].Message = i18n.Field(locale, errs[i].Code, errs[i].Param)
+	}
+	apperror.Abort(c, apperror.Validation(errs))
+}
+
+// fieldErrors แปลงข้อผิดพลาดจากการ bind เป็นรายการ field ถ้าบอกได้ว่าเป็น field ไหน
+func fieldErrors(err error) (Errors, bool) {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		errs := make(Errors, 0, len(verrs))
+		for _, fe := range verrs {
+			errs = append(errs, fromValidator(fe))
+		}
+		return errs, true
+	}
+
+	// ส่งชนิดข้อมูลผิด เช่น "age": "abc"
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return Errors{Field(typeErr.Field, CodeInvalidType, typeName(typeErr.Type))}, true
+	}
+	return nil, false
+}
+
+// fromValidator แปลงกฎที่ไม่ผ่านของ validator เป็นรหัสของเรา
+func fromValidator(fe validator.FieldError) FieldError {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required", "notblank":
+		return Field(fe.Field(), CodeRequired, "")
+	case "min", "gte":
+		if isString {
+			return Field(fe.Field(), CodeTooShort, fe.Param())
+		}
+		return Field(fe.Field(), CodeTooSmall, fe.Param())
+	case "max", "lte":
+		if isString {
+			return Field(fe.Field(), CodeTooLong, fe.Param())
+		}
+		return Field(fe.Field(), CodeTooLarge, fe.Param())
+	case "oneof":
+		return Field(fe.Field(), CodeInvalidChoice, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "gender":
+		return Field(fe.Field(), CodeInvalidChoice, strings.Join(models.Genders, ", "))
+	case "locale":
+		return Field(fe.Field(), CodeInvalidChoice, strings.Join(i18n.Locales, ", "))
+	case "phone":
+		return Field(fe.Field(), CodeInvalidPhone, "")
+	default:
+		return Field(fe.Field(), CodeInvalid, "")
+	}
+}
+
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "string"
+	}
+}